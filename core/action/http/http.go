@@ -44,6 +44,7 @@ func NewRouter(node node.Node, cluster cluster.Cluster, reporter, distributer ac
 	mux["/pausespider"] = router.PauseSpider
 	mux["/recoverspider"] = router.RecoverSpider
 	mux["/startspider"] = router.Crawl
+	mux["/checkpoint"] = router.Checkpoint
 	return router
 }
 
@@ -127,6 +128,19 @@ func (this *Router) SpiderStatus(w http.ResponseWriter, r *http.Request){
 	w.Write(encoder)
 }
 
+func (this *Router) Checkpoint(w http.ResponseWriter, r *http.Request) {
+	yierr := this.node.SaveCheckpoint()
+	result := &Result{
+		Yierr: yierr,
+		Content: this.node.GetNodeInfo().Settings.CheckpointFile,
+	}
+	encoder, err := json.Marshal(result)
+	if err != nil {
+		log.Error(err)
+	}
+	w.Write(encoder)
+}
+
 func (this *Router) StopSpider(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	spiderName := r.Form["spider"][0]
@@ -2,6 +2,7 @@ package action
 
 import (
 	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/module/remote"
 	"github.com/l-dandelion/yi-ants-go/core/node"
 	"github.com/l-dandelion/yi-ants-go/core/spider"
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
@@ -60,10 +61,20 @@ type RpcServerCluster interface {
 	GetNodeInfo(req *RpcBase, resp *RpcNodeInfoList) error
 }
 
+type RpcServerModule interface {
+	//download by a hosted downloader
+	Download(req *remote.DownloadArgs, resp *remote.DownloadReply) error
+	//send an item to a hosted pipeline
+	SendItem(req *remote.SendItemArgs, resp *remote.SendItemReply) error
+	//get the summary of a hosted module
+	ModuleSummary(req *remote.SummaryArgs, resp *remote.SummaryReply) error
+}
+
 type RpcServerAnts interface {
 	RpcServer
 	RpcServerCrawl
 	RpcServerCluster
+	RpcServerModule
 }
 
 type RpcClient interface {
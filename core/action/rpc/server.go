@@ -3,6 +3,7 @@ package rpc
 import (
 	"github.com/l-dandelion/yi-ants-go/core/action"
 	"github.com/l-dandelion/yi-ants-go/core/cluster"
+	"github.com/l-dandelion/yi-ants-go/core/module/remote"
 	"github.com/l-dandelion/yi-ants-go/core/node"
 	log "github.com/sirupsen/logrus"
 	"net"
@@ -20,11 +21,12 @@ type RpcServer struct {
 	port        int
 	rpcClient   action.RpcClientAnts
 	distributer action.Watcher
+	modules     *remote.Handler
 }
 
 func NewRpcServer(node node.Node, cluster cluster.Cluster, port int, rpcClient action.RpcClientAnts, distributer action.Watcher) *RpcServer {
 	rpcServer := &RpcServer{
-		node, cluster, port, rpcClient, distributer,
+		node, cluster, port, rpcClient, distributer, remote.NewHandler(node.ModuleRegistrar()),
 	}
 	rpcServer.start()
 	return rpcServer
@@ -251,3 +253,18 @@ func (this *RpcServer) CrawlerSumamry(req *action.RpcBase, resp *action.RpcCrawl
 	resp.Result = true
 	return nil
 }
+
+//download by a hosted downloader for a remote downloader
+func (this *RpcServer) Download(req *remote.DownloadArgs, resp *remote.DownloadReply) error {
+	return this.modules.Download(req, resp)
+}
+
+//send an item to a hosted pipeline for a remote pipeline
+func (this *RpcServer) SendItem(req *remote.SendItemArgs, resp *remote.SendItemReply) error {
+	return this.modules.SendItem(req, resp)
+}
+
+//get the summary of a hosted module
+func (this *RpcServer) ModuleSummary(req *remote.SummaryArgs, resp *remote.SummaryReply) error {
+	return this.modules.ModuleSummary(req, resp)
+}
@@ -1,6 +1,7 @@
 package crawler
 
 import (
+	"io"
 	"sync"
 
 	"github.com/l-dandelion/yi-ants-go/core/module/data"
@@ -38,6 +39,8 @@ type Crawler interface {
 	FilterRequests(reqs []*data.Request) []*data.Request
 	SignRequests(reqs []*data.Request)
 	CrawlerSummary() *Summary
+	Checkpoint(w io.Writer) *constant.YiError
+	RestoreCheckpoint(r io.Reader) *constant.YiError
 }
 
 type myCrawler struct {
@@ -463,3 +466,52 @@ func (crawler *myCrawler) CrawlerSummary() *Summary {
 	summary.DistributeQueueSize = int(crawler.distributeQueue.Total())
 	return summary
 }
+
+/*
+ * write the requests waiting for distribution as a checkpoint (see data.WriteRequests)
+ * the requests stay in the distribute queue.
+ */
+func (crawler *myCrawler) Checkpoint(w io.Writer) *constant.YiError {
+	crawler.distributeQueueLock.Lock()
+	defer crawler.distributeQueueLock.Unlock()
+	total := crawler.distributeQueue.Total()
+	reqs := []*data.Request{}
+	for i := uint64(0); i < total; i++ {
+		req, err := crawler.distributeQueue.Get()
+		if err != nil {
+			crawler.putRequests(reqs)
+			return constant.NewYiErrore(constant.ERR_CHECKPOINT_WRITE, err)
+		}
+		reqs = append(reqs, req.(*data.Request))
+	}
+	defer crawler.putRequests(reqs)
+	if err := data.WriteRequests(w, reqs); err != nil {
+		return constant.NewYiErrore(constant.ERR_CHECKPOINT_WRITE, err)
+	}
+	return nil
+}
+
+/*
+ * put the requests of a checkpoint into the distribute queue
+ */
+func (crawler *myCrawler) RestoreCheckpoint(r io.Reader) *constant.YiError {
+	reqs, err := data.ReadRequests(r)
+	if err != nil {
+		return constant.NewYiErrore(constant.ERR_CHECKPOINT_READ, err)
+	}
+	crawler.distributeQueueLock.Lock()
+	defer crawler.distributeQueueLock.Unlock()
+	crawler.putRequests(reqs)
+	return nil
+}
+
+/*
+ * put the requests back into the distribute queue
+ */
+func (crawler *myCrawler) putRequests(reqs []*data.Request) {
+	for _, req := range reqs {
+		if err := crawler.distributeQueue.Put(req); err != nil {
+			log.Errorf("Put Request: %s, Req: %v", err, req)
+		}
+	}
+}
@@ -68,6 +68,12 @@ type Analyzer interface {
 	Module                                              // inherit from module
 	RespParsers() []ParseResponse                       // get responser parsers
 	Analyze(resp *data.Response) ([]data.Data, []*constant.YiError) // analyze according to the rule and return the data
+
+	/*
+	 * The provenance of the response is attached to every item in data.PROVENANCE_KEY if the source is set
+	 */
+	Provenance() *data.ProvenanceSource
+	SetProvenance(source *data.ProvenanceSource) // Set the source of the provenance, nil to disable
 }
 
 /*
@@ -77,6 +83,17 @@ type Analyzer interface {
  */
 type ParseResponse func(resp *data.Response) ([]data.Data, []*constant.YiError)
 
+/*
+ * parsers of a parser model
+ * Name: name of the model, a request carrying the name is only dispatched to its parsers
+ * Accept: check whether a response of the request without name is accepted, all are accepted if nil
+ */
+type ParserRoute struct {
+	Name    string
+	Accept  func(resp *data.Response) bool
+	Parsers []ParseResponse
+}
+
 /*
  * interface for the pipeline
  * inherit from module
@@ -93,6 +110,13 @@ type Pipeline interface {
 	 */
 	FailFast() bool
 	SetFailFast(failFast bool) // Set fail fast
+
+	/*
+	 * The validator runs before the item processors
+	 * the item it returns is processed, and the item is dropped with the error if it fails
+	 */
+	ItemValidator() ProcessItem
+	SetItemValidator(validator ProcessItem) // Set item validator, nil to disable
 }
 
 /*
@@ -1,5 +1,10 @@
 package data
 
+import (
+	"encoding/gob"
+	"encoding/json"
+)
+
 /*
  * define the item type
  */
@@ -11,3 +16,30 @@ type Item map[string]interface{}
 func (item Item) Valid() bool {
 	return item != nil
 }
+
+/*
+ * merge the item into a copy of the parent item
+ * the fields of the item override the fields of the parent, except nil and empty strings.
+ */
+func MergeItem(parent, item Item) Item {
+	result := make(Item, len(parent)+len(item))
+	for key, value := range parent {
+		result[key] = value
+	}
+	for key, value := range item {
+		if _, ok := result[key]; ok && (value == nil || value == "") {
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
+
+func init() {
+	// nested values of items sent by rpc, and of the parent items carried by requests
+	gob.Register(map[string]interface{}{})
+	gob.Register([]map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register([]string{})
+	gob.Register(json.Number(""))
+}
@@ -0,0 +1,45 @@
+package data
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMergeItem(t *testing.T) {
+	parent := Item{"name": "Lamp", "price": 20, "link": "http://a.com/1"}
+	item := Item{"price": "", "stock": 12, "name": "Lamp 2", "color": nil}
+	result := MergeItem(parent, item)
+	expected := Item{"name": "Lamp 2", "price": 20, "link": "http://a.com/1", "stock": 12, "color": nil}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("Wrong merged item: %v", result)
+	}
+	if parent["name"] != "Lamp" || len(parent) != 3 {
+		t.Fatalf("The parent item was modified: %v", parent)
+	}
+	if result = MergeItem(nil, item); !reflect.DeepEqual(result, item) {
+		t.Fatalf("Wrong merged item without parent: %v", result)
+	}
+}
+
+func TestParentItem(t *testing.T) {
+	httpReq, _ := http.NewRequest("GET", "http://a.com/1", nil)
+	req := NewRequest(httpReq)
+	if req.ParentItem() != nil {
+		t.Fatalf("Non-nil parent item of a new request: %v", req.ParentItem())
+	}
+	key := req.UniqueKey()
+	req.SetParentItem(Item{"name": "Lamp"})
+	if req.ParentItem()["name"] != "Lamp" {
+		t.Fatalf("Wrong parent item: %v", req.ParentItem())
+	}
+
+	// the requests of the same URL carrying different parent items are different requests
+	other := NewRequest(httpReq)
+	other.SetParentItem(Item{"name": "Desk"})
+	same := NewRequest(httpReq)
+	same.SetParentItem(Item{"name": "Lamp"})
+	if req.UniqueKey() == key || req.UniqueKey() == other.UniqueKey() || req.UniqueKey() != same.UniqueKey() {
+		t.Fatalf("Wrong unique keys: %s %s %s", req.UniqueKey(), other.UniqueKey(), same.UniqueKey())
+	}
+}
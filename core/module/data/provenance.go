@@ -0,0 +1,95 @@
+package data
+
+import (
+	"time"
+)
+
+/*
+ * the reserved field of the provenance of an item
+ * the parsers shouldn't emit items with the field.
+ */
+const PROVENANCE_KEY = "_provenance"
+
+/*
+ * keys of the provenance
+ * url: url of the response the item was parsed from
+ * referer: referer of the request of the response, empty for the initial requests
+ * depth: depth of the response
+ * spider: name of the spider
+ * node: name of the node which parsed the item
+ * crawled_at: time of the parsing, in RFC3339
+ * status: status code of the response
+ */
+const (
+	PROVENANCE_URL        = "url"
+	PROVENANCE_REFERER    = "referer"
+	PROVENANCE_DEPTH      = "depth"
+	PROVENANCE_SPIDER     = "spider"
+	PROVENANCE_NODE       = "node"
+	PROVENANCE_CRAWLED_AT = "crawled_at"
+	PROVENANCE_STATUS     = "status"
+)
+
+/*
+ * the spider and the node recorded in the provenance
+ * the spider name of the request is used if it is set.
+ */
+type ProvenanceSource struct {
+	Spider string
+	Node   string
+}
+
+/*
+ * create the provenance of the items parsed from the response
+ */
+func NewProvenance(resp *Response, source *ProvenanceSource) map[string]interface{} {
+	provenance := map[string]interface{}{
+		PROVENANCE_URL:        "",
+		PROVENANCE_REFERER:    "",
+		PROVENANCE_DEPTH:      int(resp.Depth()),
+		PROVENANCE_SPIDER:     source.Spider,
+		PROVENANCE_NODE:       source.Node,
+		PROVENANCE_CRAWLED_AT: time.Now().Format(time.RFC3339),
+		PROVENANCE_STATUS:     0,
+	}
+	if req := resp.Request(); req != nil {
+		if req.SpiderName() != "" {
+			provenance[PROVENANCE_SPIDER] = req.SpiderName()
+		}
+		if httpReq := req.HTTPReq(); httpReq != nil {
+			if httpReq.URL != nil {
+				provenance[PROVENANCE_URL] = httpReq.URL.String()
+			}
+			provenance[PROVENANCE_REFERER] = httpReq.Referer()
+		}
+	}
+	if httpResp := resp.HTTPResp(); httpResp != nil {
+		provenance[PROVENANCE_STATUS] = httpResp.StatusCode
+	}
+	return provenance
+}
+
+/*
+ * get the provenance of the item, nil if it has none
+ */
+func (item Item) Provenance() map[string]interface{} {
+	provenance, _ := item[PROVENANCE_KEY].(map[string]interface{})
+	return provenance
+}
+
+/*
+ * get a copy of the item without the provenance
+ * the item itself is returned if it has no provenance.
+ */
+func (item Item) WithoutProvenance() Item {
+	if _, ok := item[PROVENANCE_KEY]; !ok {
+		return item
+	}
+	result := make(Item, len(item))
+	for key, value := range item {
+		if key != PROVENANCE_KEY {
+			result[key] = value
+		}
+	}
+	return result
+}
@@ -1,9 +1,15 @@
 package data
 
 import (
-	"net/http"
+	"crypto/sha1"
 	"encoding/gob"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
 )
 
 /*
@@ -12,6 +18,7 @@ import (
  * depth: crawl depth
  * proxy: use proxy if not empty
  * extra: additional information(used for context)
+ * wire: the wire format of a decoded request, whose http request is built when it is used
  */
 type Request struct {
 	RNodeName   string
@@ -20,6 +27,8 @@ type Request struct {
 	RDepth      uint32                 // crawl depth
 	RProxy      string                 // use proxy if not empty
 	Extra      map[string]interface{} // additional information(used for context)
+	wire        *WireRequest
+	build       *sync.Once // builds RHttpReq from wire once
 }
 
 /*
@@ -45,11 +54,43 @@ func (req *Request) SetNodeName(nodeName string) {
 
 /*
  * get http request
+ * the http request of a decoded request is built from its wire format at the first call.
  */
 func (req *Request) HTTPReq() *http.Request {
+	if req.wire != nil {
+		req.build.Do(func() {
+			// the url was checked when the request was decoded
+			req.RHttpReq, _ = req.wire.HTTPRequest()
+		})
+	}
 	return req.RHttpReq
 }
 
+/*
+ * get a copy of the http request for a download attempt
+ * the body is read again by GetBody, so every attempt sends the whole body.
+ */
+func (req *Request) DownloadReq() (*http.Request, error) {
+	if req.RHttpReq == nil && req.wire != nil {
+		return req.wire.HTTPRequest()
+	}
+	httpReq := *req.RHttpReq
+	if httpReq.GetBody != nil && httpReq.ContentLength != 0 {
+		body, err := httpReq.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Body = body
+	}
+	return &httpReq, nil
+}
+
+// the key of the extra holding the name of the parser model handling the request
+const EXTRA_PARSER = "parser"
+
+// the key of the extra holding the parent item carried by the request
+const EXTRA_PARENT_ITEM = "parent_item"
+
 /*
  * get crawl depth
  */
@@ -64,6 +105,42 @@ func (req *Request) SetExtra(key string, val interface{}) {
 	req.Extra[key] = val
 }
 
+/*
+ * get the name of the parser model handling the request, empty for any model
+ */
+func (req *Request) Parser() string {
+	name, _ := req.Extra[EXTRA_PARSER].(string)
+	return name
+}
+
+/*
+ * set the name of the parser model handling the request
+ */
+func (req *Request) SetParser(name string) {
+	if req.Extra == nil {
+		req.Extra = map[string]interface{}{}
+	}
+	req.Extra[EXTRA_PARSER] = name
+}
+
+/*
+ * get the parent item carried by the request, nil if there is none
+ */
+func (req *Request) ParentItem() Item {
+	parent, _ := req.Extra[EXTRA_PARENT_ITEM].(map[string]interface{})
+	return Item(parent)
+}
+
+/*
+ * carry the parent item, whose fields are merged into the items of the response
+ */
+func (req *Request) SetParentItem(item Item) {
+	if req.Extra == nil {
+		req.Extra = map[string]interface{}{}
+	}
+	req.Extra[EXTRA_PARENT_ITEM] = map[string]interface{}(item)
+}
+
 /*
  * set crawl depth
  */
@@ -71,11 +148,83 @@ func (req *Request) SetDepth(depth uint32) {
 	req.RDepth = depth
 }
 
+/*
+ * get the fingerprint of request, used as the key of stored responses
+ */
+func (req *Request) Fingerprint() string {
+	if req.RHttpReq == nil && req.wire != nil {
+		return req.wire.Fingerprint
+	}
+	return Fingerprint(req.RHttpReq)
+}
+
+/*
+ * get the key of the request checking repeated requests
+ * it is the URL for requests without body, and the fingerprint for others,
+ * so the submissions of a form to the same URL are different requests.
+ * the hash of the parent item is appended for the requests carrying one,
+ * so the same page reached from different parent items is crawled for every parent.
+ */
+func (req *Request) UniqueKey() string {
+	var key string
+	if req.RHttpReq == nil && req.wire != nil {
+		key = req.wire.URL
+		if len(req.wire.Body) != 0 {
+			key = req.wire.Fingerprint
+		}
+	} else if httpReq := req.RHttpReq; httpReq.GetBody == nil || httpReq.ContentLength == 0 {
+		key = httpReq.URL.String()
+	} else {
+		key = req.Fingerprint()
+	}
+	if parent := req.ParentItem(); parent != nil {
+		// the keys of maps are sorted by fmt
+		sum := sha1.Sum([]byte(fmt.Sprintf("%v", map[string]interface{}(parent))))
+		key += " parent:" + hex.EncodeToString(sum[:])
+	}
+	return key
+}
+
+/*
+ * calculate the fingerprint of a http request by its method, URL and body
+ * the body is read by GetBody, requests without body keep the fingerprint of method and URL.
+ */
+func Fingerprint(httpReq *http.Request) string {
+	var body io.Reader
+	if httpReq.GetBody != nil && httpReq.ContentLength != 0 {
+		if rc, err := httpReq.GetBody(); err == nil {
+			defer rc.Close()
+			body = rc
+		}
+	}
+	return fingerprint(httpReq.Method, httpReq.URL.String(), body)
+}
+
+/*
+ * calculate the fingerprint of the method, the URL and the body, which may be nil
+ */
+func fingerprint(method string, u string, body io.Reader) string {
+	method = strings.ToUpper(method)
+	if method == "" {
+		method = "GET"
+	}
+	h := sha1.New()
+	h.Write([]byte(method))
+	h.Write([]byte(" "))
+	h.Write([]byte(u))
+	if body != nil {
+		h.Write([]byte("\n"))
+		io.Copy(h, body)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 /*
  * check the request
  */
 func (req *Request) Valid() bool {
-	return req.RHttpReq != nil && req.RHttpReq.URL != nil
+	httpReq := req.HTTPReq()
+	return httpReq != nil && httpReq.URL != nil
 }
 
 /*
@@ -102,14 +251,14 @@ func (req *Request) AddCookie(key, value string) {
 		Name:  key,
 		Value: value,
 	}
-	req.RHttpReq.AddCookie(c)
+	req.HTTPReq().AddCookie(c)
 }
 
 /*
  * set header
  */
 func (req *Request) SetHeader(key, value string) {
-	req.RHttpReq.Header.Set(key, value)
+	req.HTTPReq().Header.Set(key, value)
 }
 
 /*
@@ -21,6 +21,7 @@ import (
 type Response struct {
 	req      *Request          // the http response for thr request
 	httpResp *http.Response    // the request for the response
+	body     []byte            // raw body
 	text     []byte            // body's []byte type
 	dom      *goquery.Document // body's Dom type if body is html
 }
@@ -67,6 +68,26 @@ func (resp *Response) Valid() bool {
 	return resp.httpResp != nil && resp.httpResp.Body != nil
 }
 
+/*
+ * get the raw body without charset conversion
+ */
+func (resp *Response) GetBody() ([]byte, error) {
+	if resp.body != nil {
+		return resp.body, nil
+	}
+	multiReader, err := reader.NewMultipleReader(resp.httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.httpResp.Body = multiReader.Reader()
+	defer func() {
+		resp.httpResp.Body.Close()
+		resp.httpResp.Body = multiReader.Reader()
+	}()
+	resp.body, err = ioutil.ReadAll(resp.httpResp.Body)
+	return resp.body, err
+}
+
 /*
  * get body's []byte
  */
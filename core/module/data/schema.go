@@ -0,0 +1,368 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+ * types of the fields of an item schema
+ */
+const (
+	FIELD_TYPE_STRING = "string"
+	FIELD_TYPE_INT    = "int"
+	FIELD_TYPE_FLOAT  = "float"
+	FIELD_TYPE_BOOL   = "bool"
+	FIELD_TYPE_TIME   = "time"
+	FIELD_TYPE_LIST   = "list"
+	FIELD_TYPE_MAP    = "map"
+	FIELD_TYPE_ANY    = "any"
+)
+
+// the layouts tried when a string is coerced into a time
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.ANSIC,
+}
+
+/*
+ * schema of the items of a spider
+ * Strict: the fields not declared are invalid, except the provenance
+ */
+type ItemSchema struct {
+	Fields []*FieldSchema `json:"fields"`
+	Strict bool           `json:"strict,omitempty"`
+}
+
+/*
+ * schema of a field
+ * Type: one of FIELD_TYPE_*, FIELD_TYPE_ANY if empty
+ * Required: the field must be present, not nil and not a blank string
+ * Pattern: the regex the whole value of a string field must match, it is anchored at both ends
+ * Format: the layout of the time fields after coercion, "2006-01-02 15:04:05" if empty
+ *
+ * the values are coerced into the types:
+ *   string  numbers and bools are formatted
+ *   int     int64, from numbers without fraction and numeric strings
+ *   float   float64, from numbers and numeric strings
+ *   bool    from "true", "false", "1", "0", "yes", "no" and the numbers 0 and 1
+ *   time    a string of Format, from strings of the common layouts, time.Time and unix seconds
+ *   list    []interface{}, from slices, a single value becomes a list of it
+ *   map     map[string]interface{}
+ */
+type FieldSchema struct {
+	Name     string `json:"name"`
+	Type     string `json:"type,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	Pattern  string `json:"pattern,omitempty"`
+	Format   string `json:"format,omitempty"`
+}
+
+/*
+ * error of a field of an invalid item
+ */
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %s: %s", e.Field, e.Reason)
+}
+
+/*
+ * compiled item schema
+ */
+type ItemValidator struct {
+	fields []*fieldValidator
+	names  map[string]bool
+	strict bool
+}
+
+type fieldValidator struct {
+	*FieldSchema
+	pattern *regexp.Regexp
+}
+
+/*
+ * check and compile the schema
+ */
+func (schema *ItemSchema) Compile() (*ItemValidator, error) {
+	validator := &ItemValidator{names: map[string]bool{}, strict: schema.Strict}
+	for i, field := range schema.Fields {
+		if field == nil || field.Name == "" {
+			return nil, fmt.Errorf("field[%d]: name is required", i)
+		}
+		if validator.names[field.Name] {
+			return nil, fmt.Errorf("field[%d]: duplicate name %s", i, field.Name)
+		}
+		validator.names[field.Name] = true
+		switch field.Type {
+		case FIELD_TYPE_STRING, FIELD_TYPE_INT, FIELD_TYPE_FLOAT, FIELD_TYPE_BOOL,
+			FIELD_TYPE_TIME, FIELD_TYPE_LIST, FIELD_TYPE_MAP, FIELD_TYPE_ANY, "":
+		default:
+			return nil, fmt.Errorf("field %s: unknown type %s", field.Name, field.Type)
+		}
+		fv := &fieldValidator{FieldSchema: field}
+		if field.Pattern != "" {
+			if field.Type != FIELD_TYPE_STRING {
+				return nil, fmt.Errorf("field %s: pattern is only for string fields", field.Name)
+			}
+			var err error
+			if fv.pattern, err = regexp.Compile(`^(?:` + field.Pattern + `)$`); err != nil {
+				return nil, fmt.Errorf("field %s: %s", field.Name, err)
+			}
+		}
+		validator.fields = append(validator.fields, fv)
+	}
+	return validator, nil
+}
+
+/*
+ * validate the item and coerce its fields into the types of the schema
+ * the item is not modified, a copy with the coerced values is returned.
+ * the error is a *FieldError naming the first invalid field.
+ */
+func (validator *ItemValidator) Validate(item Item) (Item, error) {
+	result := make(Item, len(item))
+	for key, value := range item {
+		result[key] = value
+	}
+	if validator.strict {
+		extra := []string{}
+		for key := range item {
+			if !validator.names[key] && key != PROVENANCE_KEY {
+				extra = append(extra, key)
+			}
+		}
+		if len(extra) > 0 {
+			sort.Strings(extra)
+			return nil, &FieldError{Field: extra[0], Reason: "not declared in the schema"}
+		}
+	}
+	for _, field := range validator.fields {
+		value, ok := item[field.Name]
+		if !ok || value == nil || isBlank(value) {
+			if field.Required {
+				return nil, &FieldError{Field: field.Name, Reason: "required"}
+			}
+			continue
+		}
+		coerced, err := field.coerce(value)
+		if err != nil {
+			return nil, &FieldError{Field: field.Name, Reason: err.Error()}
+		}
+		if field.pattern != nil && !field.pattern.MatchString(coerced.(string)) {
+			return nil, &FieldError{Field: field.Name, Reason: fmt.Sprintf("%q does not match %s", coerced, field.Pattern)}
+		}
+		result[field.Name] = coerced
+	}
+	return result, nil
+}
+
+/*
+ * check if the value is a string of only whitespace
+ */
+func isBlank(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.TrimSpace(s) == ""
+}
+
+/*
+ * coerce the value into the type of the field
+ */
+func (field *fieldValidator) coerce(value interface{}) (interface{}, error) {
+	var (
+		result interface{}
+		ok     bool
+	)
+	switch field.Type {
+	case FIELD_TYPE_STRING:
+		result, ok = toString(value)
+	case FIELD_TYPE_INT:
+		result, ok = toInt(value)
+	case FIELD_TYPE_FLOAT:
+		result, ok = toFloat(value)
+	case FIELD_TYPE_BOOL:
+		result, ok = toBool(value)
+	case FIELD_TYPE_TIME:
+		var t time.Time
+		if t, ok = toTime(value); ok {
+			format := field.Format
+			if format == "" {
+				format = "2006-01-02 15:04:05"
+			}
+			result = t.Format(format)
+		}
+	case FIELD_TYPE_LIST:
+		result, ok = toList(value), true
+	case FIELD_TYPE_MAP:
+		result, ok = toMap(value)
+	default:
+		result, ok = value, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %v (%T) to %s", value, value, field.Type)
+	}
+	return result, nil
+}
+
+func toString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return string(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), true
+	}
+	return "", false
+}
+
+/*
+ * get the float of a number or a numeric string
+ */
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+/*
+ * get the int64 of a number without fraction or a numeric string
+ * the strings are parsed exactly first, floats lose the precision of big ints.
+ */
+func toInt(value interface{}) (int64, bool) {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = strings.TrimSpace(v)
+	case json.Number:
+		s = string(v)
+	}
+	if s != "" {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, true
+		}
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() <= math.MaxInt64 {
+			return int64(rv.Uint()), true
+		}
+		return 0, false
+	}
+	f, ok := toFloat(value)
+	if !ok || f != math.Trunc(f) || math.Abs(f) >= 1<<63 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+func toBool(value interface{}) (bool, bool) {
+	if s, ok := value.(string); ok {
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "true", "1", "yes":
+			return true, true
+		case "false", "0", "no":
+			return false, true
+		}
+		return false, false
+	}
+	if b, ok := value.(bool); ok {
+		return b, true
+	}
+	if f, ok := toFloat(value); ok && (f == 0 || f == 1) {
+		return f == 1, true
+	}
+	return false, false
+}
+
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		s := strings.TrimSpace(v)
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	}
+	if f, ok := toFloat(value); ok {
+		return time.Unix(int64(f), 0).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+func toList(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	rv := reflect.ValueOf(value)
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = rv.Index(i).Interface()
+		}
+		return list
+	}
+	return []interface{}{value}
+}
+
+func toMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case Item:
+		return map[string]interface{}(v), true
+	}
+	return nil, false
+}
@@ -0,0 +1,158 @@
+package data
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	schema := &ItemSchema{Fields: []*FieldSchema{
+		{Name: "title", Type: FIELD_TYPE_STRING, Required: true},
+		{Name: "sku", Type: FIELD_TYPE_STRING, Pattern: `^[A-Z]+-\d+$`},
+		{Name: "count", Type: FIELD_TYPE_INT},
+		{Name: "price", Type: FIELD_TYPE_FLOAT},
+		{Name: "stock", Type: FIELD_TYPE_BOOL},
+		{Name: "date", Type: FIELD_TYPE_TIME, Format: "2006-01-02"},
+		{Name: "tags", Type: FIELD_TYPE_LIST},
+		{Name: "extra", Type: FIELD_TYPE_MAP},
+		{Name: "raw"},
+	}}
+	validator, err := schema.Compile()
+	if err != nil {
+		t.Fatalf("Compile fail: %s", err)
+	}
+	item := Item{
+		"title": 12,
+		"sku":   "AB-12",
+		"count": json.Number("9007199254740993"),
+		"price": " 1.5 ",
+		"stock": "yes",
+		"date":  "2018-03-04T05:06:07Z",
+		"tags":  []string{"a", "b"},
+		"extra": Item{"a": 1},
+		"raw":   []int{1},
+		"other": "kept",
+	}
+	result, err := validator.Validate(item)
+	if err != nil {
+		t.Fatalf("Validate fail: %s", err)
+	}
+	expected := Item{
+		"title": "12",
+		"sku":   "AB-12",
+		"count": int64(9007199254740993),
+		"price": 1.5,
+		"stock": true,
+		"date":  "2018-03-04",
+		"tags":  []interface{}{"a", "b"},
+		"extra": map[string]interface{}{"a": 1},
+		"raw":   []int{1},
+		"other": "kept",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("Wrong result: %#v", result)
+	}
+	if item["title"] != 12 {
+		t.Fatalf("The item was modified: %v", item)
+	}
+	// optional fields may be absent
+	if _, err = validator.Validate(Item{"title": "t", "count": nil}); err != nil {
+		t.Fatalf("Validate fail: %s", err)
+	}
+}
+
+func TestValidateError(t *testing.T) {
+	schema := &ItemSchema{Strict: true, Fields: []*FieldSchema{
+		{Name: "title", Type: FIELD_TYPE_STRING, Required: true},
+		{Name: "sku", Type: FIELD_TYPE_STRING, Pattern: `^[A-Z]+-\d+$`},
+		{Name: "code", Type: FIELD_TYPE_STRING, Pattern: `[A-Z]+|\d+`},
+		{Name: "count", Type: FIELD_TYPE_INT},
+		{Name: "stock", Type: FIELD_TYPE_BOOL},
+		{Name: "date", Type: FIELD_TYPE_TIME},
+		{Name: "extra", Type: FIELD_TYPE_MAP},
+	}}
+	validator, err := schema.Compile()
+	if err != nil {
+		t.Fatalf("Compile fail: %s", err)
+	}
+	cases := []struct {
+		item  Item
+		field string
+	}{
+		{Item{}, "title"},
+		{Item{"title": ""}, "title"},
+		{Item{"title": " \n\t"}, "title"},
+		{Item{"title": []string{"t"}}, "title"},
+		{Item{"title": "t", "sku": "ab-12"}, "sku"},
+		{Item{"title": "t", "code": "AB1"}, "code"},
+		{Item{"title": "t", "count": 1.5}, "count"},
+		{Item{"title": "t", "count": "1x"}, "count"},
+		{Item{"title": "t", "stock": 2}, "stock"},
+		{Item{"title": "t", "date": "yesterday"}, "date"},
+		{Item{"title": "t", "extra": "x"}, "extra"},
+		{Item{"title": "t", "b": 1, "a": 1}, "a"},
+	}
+	for _, c := range cases {
+		_, err := validator.Validate(c.item)
+		fieldErr, ok := err.(*FieldError)
+		if !ok || fieldErr.Field != c.field {
+			t.Fatalf("Wrong error of %v: %v", c.item, err)
+		}
+	}
+	// the pattern matches the whole value
+	for _, code := range []string{"AB", "12"} {
+		if _, err := validator.Validate(Item{"title": "t", "code": code}); err != nil {
+			t.Fatalf("Validate fail: %s", err)
+		}
+	}
+}
+
+func TestCompileSchema(t *testing.T) {
+	schemas := []*ItemSchema{
+		{Fields: []*FieldSchema{{Type: FIELD_TYPE_STRING}}},
+		{Fields: []*FieldSchema{{Name: "a"}, {Name: "a"}}},
+		{Fields: []*FieldSchema{{Name: "a", Type: "date"}}},
+		{Fields: []*FieldSchema{{Name: "a", Type: FIELD_TYPE_INT, Pattern: `\d`}}},
+		{Fields: []*FieldSchema{{Name: "a", Type: FIELD_TYPE_STRING, Pattern: `(`}}},
+	}
+	for _, schema := range schemas {
+		if _, err := schema.Compile(); err == nil {
+			t.Fatalf("No error when compiling the illegal schema %+v", schema.Fields[0])
+		}
+	}
+}
+
+func TestSchemaJSON(t *testing.T) {
+	schema := &ItemSchema{}
+	err := json.Unmarshal([]byte(`{"strict": true, "fields": [
+		{"name": "title", "type": "string", "required": true, "pattern": "[a-z]+"},
+		{"name": "date", "type": "time", "format": "2006-01-02"}]}`), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &ItemSchema{Strict: true, Fields: []*FieldSchema{
+		{Name: "title", Type: FIELD_TYPE_STRING, Required: true, Pattern: "[a-z]+"},
+		{Name: "date", Type: FIELD_TYPE_TIME, Format: "2006-01-02"},
+	}}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Fatalf("Wrong schema: %+v", schema)
+	}
+}
+
+func TestCoerceTime(t *testing.T) {
+	field := &fieldValidator{FieldSchema: &FieldSchema{Name: "t", Type: FIELD_TYPE_TIME}}
+	values := []interface{}{
+		"2018-03-04 05:06:07",
+		"Sun, 04 Mar 2018 05:06:07 GMT",
+		time.Date(2018, 3, 4, 5, 6, 7, 0, time.UTC),
+		int64(1520139967),
+	}
+	for _, value := range values {
+		result, err := field.coerce(value)
+		if err != nil || result != "2018-03-04 05:06:07" {
+			t.Fatalf("Wrong time of %v: %v %v", value, result, err)
+		}
+	}
+}
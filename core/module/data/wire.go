@@ -0,0 +1,453 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/*
+ * declared types of the extra values in wire format
+ * the values of WIRE_TYPE_LIST, WIRE_TYPE_MAP, WIRE_TYPE_ITEM and WIRE_TYPE_MAPS
+ * are declared one by one in the items of the value, so nested values keep their types.
+ */
+const (
+	WIRE_TYPE_NULL    = "null"
+	WIRE_TYPE_STRING  = "string"
+	WIRE_TYPE_INT     = "int"
+	WIRE_TYPE_INT64   = "int64"
+	WIRE_TYPE_UINT32  = "uint32"
+	WIRE_TYPE_UINT64  = "uint64"
+	WIRE_TYPE_FLOAT64 = "float64"
+	WIRE_TYPE_BOOL    = "bool"
+	WIRE_TYPE_NUMBER  = "number"  // json.Number
+	WIRE_TYPE_TIME    = "time"    // time.Time in RFC3339 with nanoseconds
+	WIRE_TYPE_STRINGS = "strings" // []string
+	WIRE_TYPE_LIST    = "list"    // []interface{}
+	WIRE_TYPE_MAP     = "map"     // map[string]interface{}
+	WIRE_TYPE_ITEM    = "item"    // Item
+	WIRE_TYPE_MAPS    = "maps"    // []map[string]interface{}
+	WIRE_TYPE_JSON    = "json"
+)
+
+/*
+ * request in wire format, used by rpc and checkpoints
+ * the body is kept as bytes, since a http request can not be encoded.
+ * a decoded request keeps its wire format, and the http request is built from it
+ * when it is downloaded (see Request.DownloadReq).
+ * Fingerprint: the fingerprint of the request, checked when it is decoded
+ * Extra: the extra values with their declared types, sorted by key
+ */
+type WireRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	Host        string       `json:"host,omitempty"`
+	Header      http.Header  `json:"header,omitempty"`
+	Body        []byte       `json:"body,omitempty"`
+	Depth       uint32       `json:"depth"`
+	Proxy       string       `json:"proxy,omitempty"`
+	NodeName    string       `json:"node_name,omitempty"`
+	SpiderName  string       `json:"spider_name,omitempty"`
+	Fingerprint string       `json:"fingerprint"`
+	Extra       []*WireValue `json:"extra,omitempty"`
+}
+
+/*
+ * extra value in wire format
+ * Key: the key of the extra or of the map holding the value, empty for the elements of lists
+ * Type: one of WIRE_TYPE_*, the values of other types are WIRE_TYPE_JSON,
+ *       whose numbers are decoded as json.Number like the items of the json parsers
+ * Value: the value formatted as a string, or the json of the value
+ * Items: the elements of lists and the values of maps, sorted by key
+ */
+type WireValue struct {
+	Key   string       `json:"key,omitempty"`
+	Type  string       `json:"type"`
+	Value string       `json:"value,omitempty"`
+	Items []*WireValue `json:"items,omitempty"`
+}
+
+/*
+ * convert the request into wire format
+ * the body is read by GetBody, or read and replaced if the request has no GetBody.
+ */
+func (req *Request) Wire() (*WireRequest, error) {
+	var wire *WireRequest
+	if req.RHttpReq == nil && req.wire != nil {
+		copied := *req.wire
+		wire = &copied
+	} else {
+		httpReq := req.RHttpReq
+		if httpReq == nil || httpReq.URL == nil {
+			return nil, fmt.Errorf("invalid request")
+		}
+		body, err := readBody(httpReq)
+		if err != nil {
+			return nil, err
+		}
+		wire = &WireRequest{
+			Method:      httpReq.Method,
+			URL:         httpReq.URL.String(),
+			Header:      httpReq.Header,
+			Body:        body,
+			Fingerprint: req.Fingerprint(),
+		}
+		if httpReq.Host != httpReq.URL.Host {
+			wire.Host = httpReq.Host
+		}
+	}
+	wire.Depth = req.RDepth
+	wire.Proxy = req.RProxy
+	wire.NodeName = req.RNodeName
+	wire.SpiderName = req.RSpiderName
+	wire.Extra = nil
+	keys := make([]string, 0, len(req.Extra))
+	for key := range req.Extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, err := newWireValue(key, req.Extra[key])
+		if err != nil {
+			return nil, fmt.Errorf("extra %s: %s", key, err)
+		}
+		wire.Extra = append(wire.Extra, value)
+	}
+	return wire, nil
+}
+
+/*
+ * decode the request from wire format
+ * the request keeps the wire format, and its http request is built when it is used.
+ */
+func (wire *WireRequest) Request() (*Request, error) {
+	u, err := url.Parse(wire.URL)
+	if err != nil {
+		return nil, err
+	}
+	copied := *wire
+	copied.URL = u.String()
+	copied.Extra = nil
+	var body io.Reader
+	if len(copied.Body) > 0 {
+		body = bytes.NewReader(copied.Body)
+	}
+	fingerprint := fingerprint(copied.Method, copied.URL, body)
+	if wire.Fingerprint != "" && fingerprint != wire.Fingerprint {
+		return nil, fmt.Errorf("fingerprint mismatch of %s", wire.URL)
+	}
+	copied.Fingerprint = fingerprint
+	req := &Request{
+		RDepth:      wire.Depth,
+		RProxy:      wire.Proxy,
+		RNodeName:   wire.NodeName,
+		RSpiderName: wire.SpiderName,
+		Extra:       map[string]interface{}{},
+		wire:        &copied,
+		build:       &sync.Once{},
+	}
+	for _, value := range wire.Extra {
+		v, err := value.Interface()
+		if err != nil {
+			return nil, fmt.Errorf("extra %s: %s", value.Key, err)
+		}
+		req.Extra[value.Key] = v
+	}
+	return req, nil
+}
+
+/*
+ * create the http request, whose body can be read again by GetBody
+ */
+func (wire *WireRequest) HTTPRequest() (*http.Request, error) {
+	var body io.Reader
+	if len(wire.Body) > 0 {
+		body = bytes.NewReader(wire.Body)
+	}
+	httpReq, err := http.NewRequest(wire.Method, wire.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range wire.Header {
+		httpReq.Header[key] = append([]string{}, values...)
+	}
+	if wire.Host != "" {
+		httpReq.Host = wire.Host
+	}
+	return httpReq, nil
+}
+
+/*
+ * create the extra value in wire format
+ */
+func newWireValue(key string, value interface{}) (*WireValue, error) {
+	wv := &WireValue{Key: key}
+	switch v := value.(type) {
+	case nil:
+		wv.Type = WIRE_TYPE_NULL
+	case string:
+		wv.Type, wv.Value = WIRE_TYPE_STRING, v
+	case int:
+		wv.Type, wv.Value = WIRE_TYPE_INT, strconv.Itoa(v)
+	case int64:
+		wv.Type, wv.Value = WIRE_TYPE_INT64, strconv.FormatInt(v, 10)
+	case uint32:
+		wv.Type, wv.Value = WIRE_TYPE_UINT32, strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		wv.Type, wv.Value = WIRE_TYPE_UINT64, strconv.FormatUint(v, 10)
+	case float64:
+		wv.Type, wv.Value = WIRE_TYPE_FLOAT64, strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		wv.Type, wv.Value = WIRE_TYPE_BOOL, strconv.FormatBool(v)
+	case json.Number:
+		wv.Type, wv.Value = WIRE_TYPE_NUMBER, string(v)
+	case time.Time:
+		wv.Type, wv.Value = WIRE_TYPE_TIME, v.Format(time.RFC3339Nano)
+	case []string:
+		b, _ := json.Marshal(v)
+		wv.Type, wv.Value = WIRE_TYPE_STRINGS, string(b)
+	case []interface{}:
+		wv.Type = WIRE_TYPE_LIST
+		for i, elem := range v {
+			item, err := newWireValue("", elem)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %s", i, err)
+			}
+			wv.Items = append(wv.Items, item)
+		}
+	case map[string]interface{}:
+		wv.Type = WIRE_TYPE_MAP
+		return wv, wv.setMap(v)
+	case Item:
+		wv.Type = WIRE_TYPE_ITEM
+		return wv, wv.setMap(v)
+	case []map[string]interface{}:
+		wv.Type = WIRE_TYPE_MAPS
+		for i, elem := range v {
+			item := &WireValue{Type: WIRE_TYPE_MAP}
+			if err := item.setMap(elem); err != nil {
+				return nil, fmt.Errorf("[%d]: %s", i, err)
+			}
+			wv.Items = append(wv.Items, item)
+		}
+	default:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		wv.Type, wv.Value = WIRE_TYPE_JSON, string(b)
+	}
+	return wv, nil
+}
+
+/*
+ * set the values of the map as the items, sorted by key
+ */
+func (wv *WireValue) setMap(m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		item, err := newWireValue(key, m[key])
+		if err != nil {
+			return fmt.Errorf("%s: %s", key, err)
+		}
+		wv.Items = append(wv.Items, item)
+	}
+	return nil
+}
+
+/*
+ * get the value of its declared type
+ */
+func (wv *WireValue) Interface() (interface{}, error) {
+	var (
+		value interface{}
+		err   error
+	)
+	switch wv.Type {
+	case WIRE_TYPE_NULL:
+	case WIRE_TYPE_STRING:
+		value = wv.Value
+	case WIRE_TYPE_INT:
+		value, err = strconv.Atoi(wv.Value)
+	case WIRE_TYPE_INT64:
+		value, err = strconv.ParseInt(wv.Value, 10, 64)
+	case WIRE_TYPE_UINT32:
+		var n uint64
+		n, err = strconv.ParseUint(wv.Value, 10, 32)
+		value = uint32(n)
+	case WIRE_TYPE_UINT64:
+		value, err = strconv.ParseUint(wv.Value, 10, 64)
+	case WIRE_TYPE_FLOAT64:
+		value, err = strconv.ParseFloat(wv.Value, 64)
+	case WIRE_TYPE_BOOL:
+		value, err = strconv.ParseBool(wv.Value)
+	case WIRE_TYPE_NUMBER:
+		if _, err = strconv.ParseFloat(wv.Value, 64); err == nil {
+			value = json.Number(wv.Value)
+		}
+	case WIRE_TYPE_TIME:
+		value, err = time.Parse(time.RFC3339Nano, wv.Value)
+	case WIRE_TYPE_STRINGS:
+		list := []string{}
+		err = json.Unmarshal([]byte(wv.Value), &list)
+		value = list
+	case WIRE_TYPE_LIST:
+		list := make([]interface{}, len(wv.Items))
+		for i, item := range wv.Items {
+			if list[i], err = item.Interface(); err != nil {
+				return nil, fmt.Errorf("[%d]: %s", i, err)
+			}
+		}
+		value = list
+	case WIRE_TYPE_MAP:
+		value, err = wv.getMap()
+	case WIRE_TYPE_ITEM:
+		var m map[string]interface{}
+		m, err = wv.getMap()
+		value = Item(m)
+	case WIRE_TYPE_MAPS:
+		list := make([]map[string]interface{}, len(wv.Items))
+		for i, item := range wv.Items {
+			if list[i], err = item.getMap(); err != nil {
+				return nil, fmt.Errorf("[%d]: %s", i, err)
+			}
+		}
+		value = list
+	case WIRE_TYPE_JSON:
+		decoder := json.NewDecoder(bytes.NewReader([]byte(wv.Value)))
+		decoder.UseNumber()
+		err = decoder.Decode(&value)
+	default:
+		return nil, fmt.Errorf("unknown type %s", wv.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+/*
+ * get the map of the items
+ */
+func (wv *WireValue) getMap() (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(wv.Items))
+	for _, item := range wv.Items {
+		value, err := item.Interface()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", item.Key, err)
+		}
+		m[item.Key] = value
+	}
+	return m, nil
+}
+
+/*
+ * encode the request in wire format for gob, so rpc keeps the body
+ * the wire format is encoded as json, like the checkpoints written by WriteRequests.
+ */
+func (req *Request) GobEncode() ([]byte, error) {
+	wire, err := req.Wire()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(wire)
+}
+
+/*
+ * decode the request encoded by GobEncode
+ * the http request is not built here, but when the request is used.
+ */
+func (req *Request) GobDecode(b []byte) error {
+	wire := &WireRequest{}
+	if err := json.Unmarshal(b, wire); err != nil {
+		return err
+	}
+	decoded, err := wire.Request()
+	if err != nil {
+		return err
+	}
+	*req = *decoded
+	return nil
+}
+
+/*
+ * write the requests in wire format as json lines, such as a checkpoint of the queued requests
+ */
+func WriteRequests(w io.Writer, reqs []*Request) error {
+	encoder := json.NewEncoder(w)
+	for _, req := range reqs {
+		wire, err := req.Wire()
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode(wire); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+ * read the requests written by WriteRequests
+ */
+func ReadRequests(r io.Reader) ([]*Request, error) {
+	reqs := []*Request{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		wire := &WireRequest{}
+		if err := json.Unmarshal(scanner.Bytes(), wire); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		req, err := wire.Request()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, scanner.Err()
+}
+
+/*
+ * read the body of the http request without consuming it
+ */
+func readBody(httpReq *http.Request) ([]byte, error) {
+	if httpReq.GetBody != nil {
+		body, err := httpReq.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return ioutil.ReadAll(body)
+	}
+	if httpReq.Body == nil || httpReq.Body == http.NoBody {
+		return nil, nil
+	}
+	b, err := ioutil.ReadAll(httpReq.Body)
+	httpReq.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Body = ioutil.NopCloser(bytes.NewReader(b))
+	httpReq.ContentLength = int64(len(b))
+	httpReq.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+	return b, nil
+}
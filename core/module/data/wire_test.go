@@ -0,0 +1,241 @@
+package data
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newPostRequest(t *testing.T) *Request {
+	httpReq, err := http.NewRequest("POST", "http://a.com/search", strings.NewReader("q=lamp&page=2"))
+	if err != nil {
+		t.Fatalf("An error occurs when creating the request: %s", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req := NewRequest(httpReq)
+	req.SetDepth(3)
+	req.SetProxy("http://proxy:8080")
+	req.SetSpiderName("shop")
+	req.SetNodeName("node1")
+	req.SetParser("search")
+	req.SetParentItem(Item{"name": "Lamp", "price": 20})
+	req.SetExtra("page", 2)
+	req.SetExtra("score", 0.5)
+	req.SetExtra("seen", true)
+	return req
+}
+
+func checkRequest(t *testing.T, req, result *Request) {
+	httpReq := result.HTTPReq()
+	if httpReq.Method != "POST" || httpReq.URL.String() != "http://a.com/search" {
+		t.Fatalf("Wrong method or url: %s %s", httpReq.Method, httpReq.URL)
+	}
+	if httpReq.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Fatalf("Wrong header: %v", httpReq.Header)
+	}
+	body, _ := ioutil.ReadAll(httpReq.Body)
+	if string(body) != "q=lamp&page=2" {
+		t.Fatalf("Wrong body: %q", body)
+	}
+	if result.Fingerprint() != req.Fingerprint() {
+		t.Fatalf("Wrong fingerprint: %s, expected: %s", result.Fingerprint(), req.Fingerprint())
+	}
+	if result.Depth() != 3 || result.RProxy != "http://proxy:8080" ||
+		result.SpiderName() != "shop" || result.NodeName() != "node1" {
+		t.Fatalf("Wrong request: %+v", result)
+	}
+	if result.Parser() != "search" || result.Extra["page"] != 2 ||
+		result.Extra["score"] != 0.5 || result.Extra["seen"] != true {
+		t.Fatalf("Wrong extra: %v", result.Extra)
+	}
+	parent := Item{"name": "Lamp", "price": 20}
+	if !reflect.DeepEqual(result.ParentItem(), parent) {
+		t.Fatalf("Wrong parent item: %v", result.ParentItem())
+	}
+}
+
+func TestWireRequest(t *testing.T) {
+	req := newPostRequest(t)
+	wire, err := req.Wire()
+	if err != nil {
+		t.Fatalf("An error occurs when converting the request: %s", err)
+	}
+	result, err := wire.Request()
+	if err != nil {
+		t.Fatalf("An error occurs when rebuilding the request: %s", err)
+	}
+	if result.RHttpReq != nil || result.UniqueKey() != req.UniqueKey() || result.Fingerprint() != req.Fingerprint() {
+		t.Fatalf("Wrong request before building the http request: %+v", result)
+	}
+	checkRequest(t, req, result)
+
+	// a request without body
+	httpReq, _ := http.NewRequest("GET", "http://a.com/item?id=1", nil)
+	get := NewRequest(httpReq)
+	if wire, err := get.Wire(); err != nil {
+		t.Fatalf("An error occurs when converting the request: %s", err)
+	} else if result, err := wire.Request(); err != nil || result.Fingerprint() != get.Fingerprint() || result.UniqueKey() != get.UniqueKey() {
+		t.Fatalf("Wrong request without body: %v %v", result, err)
+	}
+
+	wire.Body = []byte("q=pear")
+	if _, err := wire.Request(); err == nil {
+		t.Fatalf("No error for the request of a wrong fingerprint")
+	}
+	wire.Fingerprint = ""
+	wire.Extra = []*WireValue{{Key: "page", Type: "complex", Value: "1"}}
+	if _, err := wire.Request(); err == nil {
+		t.Fatalf("No error for the extra of an unknown type")
+	}
+	wire.Extra = []*WireValue{{Key: "parent", Type: WIRE_TYPE_MAP, Items: []*WireValue{{Key: "price", Type: WIRE_TYPE_INT, Value: "1.5"}}}}
+	if _, err := wire.Request(); err == nil || !strings.Contains(err.Error(), "extra parent: price: ") {
+		t.Fatalf("Wrong error for the nested extra of a wrong value: %v", err)
+	}
+}
+
+func TestWireValue(t *testing.T) {
+	values := []interface{}{
+		nil,
+		"a",
+		1,
+		int64(-2),
+		uint32(3),
+		uint64(4),
+		0.5,
+		false,
+		json.Number("1.25"),
+		time.Date(2018, 3, 4, 5, 6, 7, 8, time.UTC),
+		[]string{"a", "b"},
+		[]interface{}{1, "a", []interface{}{}, nil},
+		map[string]interface{}{"n": int64(1), "list": []string{}, "map": map[string]interface{}{"f": 1.5}},
+		Item{"price": 20, "tags": []interface{}{"a"}},
+		[]map[string]interface{}{{"sku": "A-1", "stock": 3}, {}},
+		struct{ A int }{1},
+	}
+	for _, value := range values {
+		wv, err := newWireValue("key", value)
+		if err != nil {
+			t.Fatalf("An error occurs when converting %#v: %s", value, err)
+		}
+		b, err := json.Marshal(wv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &WireValue{}
+		if err = json.Unmarshal(b, decoded); err != nil {
+			t.Fatal(err)
+		}
+		result, err := decoded.Interface()
+		if err != nil {
+			t.Fatalf("An error occurs when decoding %s: %s", b, err)
+		}
+		expected := value
+		if wv.Type == WIRE_TYPE_JSON {
+			expected = map[string]interface{}{"A": json.Number("1")}
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Fatalf("Wrong value of %s: %#v, expected: %#v", b, result, expected)
+		}
+	}
+	if _, err := newWireValue("key", []interface{}{func() {}}); err == nil {
+		t.Fatalf("No error for a value without wire format")
+	}
+}
+
+func TestWireRequestWithoutGetBody(t *testing.T) {
+	httpReq, _ := http.NewRequest("POST", "http://a.com/search", nil)
+	httpReq.Body = ioutil.NopCloser(strings.NewReader("q=lamp"))
+	req := NewRequest(httpReq)
+	wire, err := req.Wire()
+	if err != nil {
+		t.Fatalf("An error occurs when converting the request: %s", err)
+	}
+	if string(wire.Body) != "q=lamp" {
+		t.Fatalf("Wrong body: %q", wire.Body)
+	}
+	body, _ := ioutil.ReadAll(httpReq.Body)
+	if string(body) != "q=lamp" {
+		t.Fatalf("The body of the request was consumed: %q", body)
+	}
+	if _, err := wire.Request(); err != nil {
+		t.Fatalf("An error occurs when rebuilding the request: %s", err)
+	}
+}
+
+func TestGobRequest(t *testing.T) {
+	req := newPostRequest(t)
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode([]*Request{req}); err != nil {
+		t.Fatalf("An error occurs when encoding the request: %s", err)
+	}
+	reqs := []*Request{}
+	if err := gob.NewDecoder(&buf).Decode(&reqs); err != nil {
+		t.Fatalf("An error occurs when decoding the request: %s", err)
+	}
+	if len(reqs) != 1 {
+		t.Fatalf("Wrong number of requests: %d", len(reqs))
+	}
+	checkRequest(t, req, reqs[0])
+}
+
+func TestWriteRequests(t *testing.T) {
+	req := newPostRequest(t)
+	var buf bytes.Buffer
+	if err := WriteRequests(&buf, []*Request{req, req}); err != nil {
+		t.Fatalf("An error occurs when writing the requests: %s", err)
+	}
+	reqs, err := ReadRequests(&buf)
+	if err != nil {
+		t.Fatalf("An error occurs when reading the requests: %s", err)
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("Wrong number of requests: %d", len(reqs))
+	}
+	checkRequest(t, req, reqs[1])
+	if _, err := ReadRequests(strings.NewReader("{\n")); err == nil {
+		t.Fatalf("No error for a broken checkpoint")
+	}
+}
+
+func TestDownloadReq(t *testing.T) {
+	req := newPostRequest(t)
+	wire, err := req.Wire()
+	if err != nil {
+		t.Fatalf("An error occurs when converting the request: %s", err)
+	}
+	decoded, err := wire.Request()
+	if err != nil {
+		t.Fatalf("An error occurs when decoding the request: %s", err)
+	}
+	for _, r := range []*Request{req, decoded} {
+		for i := 0; i < 2; i++ {
+			httpReq, err := r.DownloadReq()
+			if err != nil {
+				t.Fatalf("An error occurs when getting the request: %s", err)
+			}
+			body, _ := ioutil.ReadAll(httpReq.Body)
+			if string(body) != "q=lamp&page=2" || httpReq.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+				t.Fatalf("Wrong request of attempt %d: %q %v", i, body, httpReq.Header)
+			}
+		}
+	}
+	if decoded.RHttpReq != nil {
+		t.Fatalf("The http request was kept by a download attempt")
+	}
+
+	// the changes of a decoded request are kept by the later attempts
+	decoded.SetHeader("Referer", "http://a.com/")
+	httpReq, err := decoded.DownloadReq()
+	if err != nil || httpReq.Header.Get("Referer") != "http://a.com/" {
+		t.Fatalf("Wrong request after a change: %v %v", httpReq, err)
+	}
+	if wire, err = decoded.Wire(); err != nil || wire.Header.Get("Referer") != "http://a.com/" {
+		t.Fatalf("Wrong wire format after a change: %v %v", wire, err)
+	}
+}
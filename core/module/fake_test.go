@@ -172,6 +172,19 @@ func (analyzer *fakeAnalyzer) Analyze(resp *data.Response) (dataList []data.Data
 	return
 }
 
+/*
+ * (fake)the function to get the source of the provenance
+ */
+func (analyzer *fakeAnalyzer) Provenance() *data.ProvenanceSource {
+	return nil
+}
+
+/*
+ * (fake)the function to set the source of the provenance
+ */
+func (analyzer *fakeAnalyzer) SetProvenance(source *data.ProvenanceSource) {
+}
+
 /*
  * create an instance for fake downloader
  */
@@ -241,3 +254,16 @@ func (pipeline *fakePipeline) FailFast() bool {
 func (pipeline *fakePipeline) SetFailFast(failFast bool) {
 	pipeline.failFast = failFast
 }
+
+/*
+ * (fake)the function to get the item validator
+ */
+func (pipeline *fakePipeline) ItemValidator() ProcessItem {
+	return nil
+}
+
+/*
+ * (fake)the function to set the item validator
+ */
+func (pipeline *fakePipeline) SetItemValidator(validator ProcessItem) {
+}
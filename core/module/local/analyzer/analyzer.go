@@ -42,6 +42,60 @@ func New(
 	return &myAnalyzer{
 		ModuleInternal: moduleBase,
 		respParsers:    innerParsers,
+		routes:         []*module.ParserRoute{{Parsers: innerParsers}},
+	}, nil
+}
+
+/*
+ * create an instance for analyzer dispatching responses by routes
+ * a response is dispatched to the route named by its request,
+ * or to every route accepting it if the request carries no name.
+ */
+func NewRouted(
+	mid module.MID,
+	routes []*module.ParserRoute,
+	scoreCalculator module.CalculateScore) (analyzer module.Analyzer, yierr *constant.YiError) {
+	moduleBase, yierr := stub.NewModuleInternal(mid, scoreCalculator)
+	if yierr != nil {
+		return
+	}
+	if len(routes) == 0 {
+		yierr = constant.NewYiErrorf(constant.ERR_NEW_ANALYZER_FAIL,
+			"Empty parser route list")
+		return
+	}
+	var innerParsers []module.ParseResponse
+	var innerRoutes []*module.ParserRoute
+	names := map[string]bool{}
+	for i, route := range routes {
+		if route == nil {
+			yierr = constant.NewYiErrorf(constant.ERR_NEW_ANALYZER_FAIL,
+				"Nil parser route[%d]", i)
+			return
+		}
+		if route.Name != "" {
+			if names[route.Name] {
+				yierr = constant.NewYiErrorf(constant.ERR_NEW_ANALYZER_FAIL,
+					"Duplicate name of parser route[%d]: %s", i, route.Name)
+				return
+			}
+			names[route.Name] = true
+		}
+		for j, parser := range route.Parsers {
+			if parser == nil {
+				yierr = constant.NewYiErrorf(constant.ERR_NEW_ANALYZER_FAIL,
+					"Nil response parser[%d] of parser route[%d]", j, i)
+				return
+			}
+		}
+		innerParsers = append(innerParsers, route.Parsers...)
+		innerRoute := *route
+		innerRoutes = append(innerRoutes, &innerRoute)
+	}
+	return &myAnalyzer{
+		ModuleInternal: moduleBase,
+		respParsers:    innerParsers,
+		routes:         innerRoutes,
 	}, nil
 }
 
@@ -52,6 +106,8 @@ func New(
 type myAnalyzer struct {
 	stub.ModuleInternal                        // module internal instance
 	respParsers         []module.ParseResponse //response parser list
+	routes              []*module.ParserRoute  //parser routes
+	provenance          *data.ProvenanceSource //source of the provenance of items, nil if disabled
 }
 
 /*
@@ -115,8 +171,17 @@ func (analyzer *myAnalyzer) Analyze(
 		yierrList = append(yierrList, yierr)
 		return
 	}
+	respParsers, yierr := analyzer.route(resp)
+	if yierr != nil {
+		yierrList = append(yierrList, yierr)
+		return
+	}
 	dataList = []data.Data{}
-	for _, respParser := range analyzer.respParsers {
+	var provenance map[string]interface{}
+	if analyzer.provenance != nil {
+		provenance = data.NewProvenance(resp, analyzer.provenance)
+	}
+	for _, respParser := range respParsers {
 		if httpResp.Body != nil {
 			httpResp.Body.Close()
 		}
@@ -124,6 +189,11 @@ func (analyzer *myAnalyzer) Analyze(
 		pDataList, pYierrList := respParser(resp)
 		if pDataList != nil {
 			for _, mdata := range pDataList {
+				if provenance != nil {
+					if yierr := attachProvenance(mdata, provenance); yierr != nil {
+						yierrList = append(yierrList, yierr)
+					}
+				}
 				dataList = appendDataList(dataList, mdata, respDepth)
 			}
 		}
@@ -139,6 +209,78 @@ func (analyzer *myAnalyzer) Analyze(
 	return
 }
 
+/*
+ * get the parsers of the response
+ * the parsers of the route named by the request, or of the routes accepting the response.
+ * the name is ignored if no route is named.
+ */
+func (analyzer *myAnalyzer) route(resp *data.Response) ([]module.ParseResponse, *constant.YiError) {
+	name := resp.Request().Parser()
+	if name != "" {
+		named := false
+		for _, route := range analyzer.routes {
+			if route.Name == name {
+				return route.Parsers, nil
+			}
+			named = named || route.Name != ""
+		}
+		if named {
+			return nil, constant.NewYiErrorf(constant.ERR_CRAWL_ANALYZER,
+				"Parser route not found.(name: %s)", name)
+		}
+	}
+	parsers := []module.ParseResponse{}
+	for _, route := range analyzer.routes {
+		if route.Accept == nil || route.Accept(resp) {
+			parsers = append(parsers, route.Parsers...)
+		}
+	}
+	return parsers, nil
+}
+
+/*
+ * get the source of the provenance
+ */
+func (analyzer *myAnalyzer) Provenance() *data.ProvenanceSource {
+	return analyzer.provenance
+}
+
+/*
+ * set the source of the provenance
+ */
+func (analyzer *myAnalyzer) SetProvenance(source *data.ProvenanceSource) {
+	analyzer.provenance = source
+}
+
+/*
+ * attach the provenance to the item, or set the referer of the request
+ * the reserved field emitted by the parser is replaced and reported.
+ */
+func attachProvenance(mdata data.Data, provenance map[string]interface{}) *constant.YiError {
+	switch v := mdata.(type) {
+	case data.Item:
+		if v == nil {
+			return nil
+		}
+		_, reserved := v[data.PROVENANCE_KEY]
+		copied := make(map[string]interface{}, len(provenance))
+		for key, value := range provenance {
+			copied[key] = value
+		}
+		v[data.PROVENANCE_KEY] = copied
+		if reserved {
+			return constant.NewYiErrorf(constant.ERR_CRAWL_ANALYZER,
+				"Reserved field %s of item is replaced.(url: %s)", data.PROVENANCE_KEY, provenance[data.PROVENANCE_URL])
+		}
+	case *data.Request:
+		// the referer of the request is recorded in the provenance of its items
+		if v != nil && v.HTTPReq() != nil && v.HTTPReq().Referer() == "" {
+			v.SetReferer(provenance[data.PROVENANCE_URL].(string))
+		}
+	}
+	return nil
+}
+
 /*
  * add data(request or item) to data list
  */
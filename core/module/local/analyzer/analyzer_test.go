@@ -240,6 +240,139 @@ func TestCount(t *testing.T) {
 	}
 }
 
+func TestRoute(t *testing.T) {
+	mid := module.MID("A1|127.0.0.1:8080")
+	genNamedParser := func(name string) module.ParseResponse {
+		return func(resp *data.Response) ([]data.Data, []*constant.YiError) {
+			return []data.Data{data.Item{"parser": name}}, nil
+		}
+	}
+	routes := []*module.ParserRoute{
+		{Name: "list", Parsers: []module.ParseResponse{genNamedParser("list")}},
+		{Name: "item", Parsers: []module.ParseResponse{genNamedParser("item")},
+			Accept: func(resp *data.Response) bool {
+				return strings.Contains(resp.HTTPRequest().URL.Path, "/item/")
+			}},
+		{Parsers: []module.ParseResponse{genNamedParser("")},
+			Accept: func(resp *data.Response) bool { return false }},
+	}
+	a, yierr := NewRouted(mid, routes, nil)
+	if yierr != nil {
+		t.Fatalf("An error occurs when creating an analyzer: %s (mid: %s)", yierr, mid)
+	}
+	if len(a.RespParsers()) != 3 {
+		t.Fatalf("Inconsistent response parser number: expected: %d, actual: %d", 3, len(a.RespParsers()))
+	}
+	cases := []struct {
+		url      string
+		parser   string
+		expected []string
+	}{
+		{"http://example.com/item/1", "", []string{"list", "item"}},
+		{"http://example.com/list", "", []string{"list"}},
+		{"http://example.com/list", "item", []string{"item"}},
+		{"http://example.com/item/1", "list", []string{"list"}},
+	}
+	for _, c := range cases {
+		resp := getTestingResps(1, "GET", c.url, 0, t)[0]
+		if c.parser != "" {
+			resp.Request().SetParser(c.parser)
+		}
+		dataList, yierrs := a.Analyze(resp)
+		if len(yierrs) != 0 {
+			t.Fatalf("An error occurs when analyzing: %s (url: %s)", yierrs[0], c.url)
+		}
+		parsers := []string{}
+		for _, d := range dataList {
+			parsers = append(parsers, d.(data.Item)["parser"].(string))
+		}
+		if strings.Join(parsers, ",") != strings.Join(c.expected, ",") {
+			t.Fatalf("Inconsistent parsers: expected: %v, actual: %v (url: %s, parser: %s)",
+				c.expected, parsers, c.url, c.parser)
+		}
+	}
+
+	//unknown parser
+	resp := getTestingResps(1, "GET", "http://example.com/list", 0, t)[0]
+	resp.Request().SetParser("unknown")
+	if _, yierrs := a.Analyze(resp); len(yierrs) == 0 {
+		t.Fatal("No error when analyze response routed to an unknown parser!")
+	}
+
+	//wrong args
+	routeList := [][]*module.ParserRoute{
+		nil,
+		{nil},
+		{{Name: "a"}, {Name: "a"}},
+		{{Parsers: []module.ParseResponse{nil}}},
+	}
+	for _, routes := range routeList {
+		if _, yierr := NewRouted(mid, routes, nil); yierr == nil {
+			t.Fatalf("No error when create an analyzer with illegal routes %v!", routes)
+		}
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	mid := module.MID("A1|127.0.0.1:8080")
+	parser := func(resp *data.Response) ([]data.Data, []*constant.YiError) {
+		httpReq, _ := http.NewRequest("GET", "http://example.com/next", nil)
+		return []data.Data{
+			data.Item{"title": "a"},
+			data.Item{"title": "b", data.PROVENANCE_KEY: "parsed"},
+			data.NewRequest(httpReq),
+		}, nil
+	}
+	a, yierr := New(mid, []module.ParseResponse{parser}, nil)
+	if yierr != nil {
+		t.Fatalf("An error occurs when creating an analyzer: %s (mid: %s)", yierr, mid)
+	}
+	// disabled by default
+	resp := getTestingResps(1, "GET", "http://example.com/list", 2, t)[0]
+	dataList, _ := a.Analyze(resp)
+	if provenance := dataList[0].(data.Item).Provenance(); provenance != nil {
+		t.Fatalf("Provenance attached when disabled: %v", provenance)
+	}
+
+	a.SetProvenance(&data.ProvenanceSource{Spider: "s", Node: "n"})
+	resp = getTestingResps(1, "GET", "http://example.com/list", 2, t)[0]
+	resp.Request().SetReferer("http://example.com/")
+	resp.HTTPResp().StatusCode = 200
+	dataList, yierrs := a.Analyze(resp)
+	if len(yierrs) != 1 || !strings.Contains(yierrs[0].Error(), data.PROVENANCE_KEY) {
+		t.Fatalf("Wrong errors of the reserved field: %v", yierrs)
+	}
+	for _, d := range dataList[:2] {
+		provenance := d.(data.Item).Provenance()
+		if provenance == nil {
+			t.Fatalf("No provenance of item %v", d)
+		}
+		expected := map[string]interface{}{
+			data.PROVENANCE_URL:     "http://example.com/list",
+			data.PROVENANCE_REFERER: "http://example.com/",
+			data.PROVENANCE_DEPTH:   2,
+			data.PROVENANCE_SPIDER:  "s",
+			data.PROVENANCE_NODE:    "n",
+			data.PROVENANCE_STATUS:  200,
+		}
+		for key, value := range expected {
+			if provenance[key] != value {
+				t.Fatalf("Inconsistent provenance %s: expected: %v, actual: %v", key, value, provenance[key])
+			}
+		}
+		if provenance[data.PROVENANCE_CRAWLED_AT] == "" {
+			t.Fatal("Empty crawled time of provenance")
+		}
+		if stripped := d.(data.Item).WithoutProvenance(); stripped["title"] == nil || stripped.Provenance() != nil {
+			t.Fatalf("Wrong item without provenance: %v", stripped)
+		}
+	}
+	// the followed requests carry the referer
+	if referer := dataList[2].(*data.Request).HTTPReq().Referer(); referer != "http://example.com/list" {
+		t.Fatalf("Inconsistent referer of request: expected: %s, actual: %s", "http://example.com/list", referer)
+	}
+}
+
 func genTestingRespParser(fail bool) module.ParseResponse {
 	if fail {
 		return func(resp *data.Response) (data []data.Data, parseErrors []*constant.YiError) {
@@ -5,6 +5,7 @@ import (
 
 	"github.com/l-dandelion/yi-ants-go/core/module"
 	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/module/local/fetcher"
 	"github.com/l-dandelion/yi-ants-go/core/module/stub"
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
 	"github.com/l-dandelion/yi-ants-go/lib/library/pool"
@@ -20,7 +21,8 @@ func New(
 	mid module.MID,
 	client *http.Client,
 	scoreCalculator module.CalculateScore,
-	maxThread int) (downloader module.Downloader, yierr *constant.YiError) {
+	maxThread int,
+	recorders ...ResponseRecorder) (downloader module.Downloader, yierr *constant.YiError) {
 	moduleBase, yierr := stub.NewModuleInternal(mid, scoreCalculator)
 	//check whether the args are vaild
 	if yierr != nil {
@@ -30,10 +32,21 @@ func New(
 		yierr = constant.NewYiErrorf(constant.ERR_NEW_DOWNLOADER_FAIL, "Client is nil.")
 		return
 	}
+	for i, recorder := range recorders {
+		if recorder == nil {
+			yierr = constant.NewYiErrorf(constant.ERR_NEW_DOWNLOADER_FAIL, "Nil response recorder[%d].", i)
+			return
+		}
+	}
 
+	// http(s) by the client, other schemes by the default fetcher registry
+	fetchers := fetcher.NewRegistry(fetcher.Default())
+	fetchers.Register("http", client)
+	fetchers.Register("https", client)
 	return &myDownloader{
 		ModuleInternal: moduleBase,
-		httpClient:     client,
+		fetchers:       fetchers,
+		recorders:      recorders,
 		Pool:           *pool.NewPool(maxThread),
 	}, nil
 }
@@ -42,8 +55,9 @@ func New(
  * implementation of interface module.Downloader
  */
 type myDownloader struct {
-	stub.ModuleInternal              //module internal instance
-	httpClient          *http.Client //http client for downloading
+	stub.ModuleInternal                    //module internal instance
+	fetchers            fetcher.Registry   //scheme-fetcher registry for downloading
+	recorders           []ResponseRecorder //called with every downloaded response
 	pool.Pool
 }
 
@@ -62,6 +76,15 @@ func (downloader *myDownloader) Download(req *data.Request) (*data.Response, *co
 	if req.HTTPReq() == nil {
 		return nil, constant.NewYiErrorf(constant.ERR_CRAWL_DOWNLOADER, "HTTP request is nil.")
 	}
+	if req.HTTPReq().URL == nil {
+		return nil, constant.NewYiErrorf(constant.ERR_CRAWL_DOWNLOADER, "Request URL is nil.")
+	}
+	scheme := req.HTTPReq().URL.Scheme
+	f, ok := downloader.fetchers.Get(scheme)
+	if !ok {
+		return nil, constant.NewYiErrorf(constant.ERR_CRAWL_DOWNLOADER,
+			"Unsupported URL scheme: %q (URL: %s)", scheme, req.HTTPReq().URL)
+	}
 	downloader.IncrAcceptedCount()
 
 	var (
@@ -73,7 +96,11 @@ func (downloader *myDownloader) Download(req *data.Request) (*data.Response, *co
 		req.HTTPReq().URL, req.Depth())
 	// try to download RETRY_TIMES times
 	for i := 0; i < RETRY_TIMES; i++ {
-		httpResp, err = downloader.httpClient.Do(req.HTTPReq())
+		var httpReq *http.Request
+		if httpReq, err = req.DownloadReq(); err != nil {
+			break
+		}
+		httpResp, err = f.Do(httpReq)
 		if err == nil {
 			break
 		}
@@ -84,5 +111,11 @@ func (downloader *myDownloader) Download(req *data.Request) (*data.Response, *co
 	}
 	resp := data.NewResponse(req, httpResp)
 	downloader.IncrCompletedCount()
+	// the response is still returned for parsing when recording fails
+	for _, recorder := range downloader.recorders {
+		if yierr := recorder(resp); yierr != nil {
+			return resp, yierr
+		}
+	}
 	return resp, nil
 }
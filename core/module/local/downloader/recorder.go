@@ -0,0 +1,54 @@
+package downloader
+
+import (
+	"io/ioutil"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+	"github.com/l-dandelion/yi-ants-go/lib/library/reader"
+	"github.com/l-dandelion/yi-ants-go/lib/library/warc"
+)
+
+/*
+ * function called with every downloaded response, used for archiving
+ * it must leave the response body readable for the analyzer.
+ */
+type ResponseRecorder func(resp *data.Response) *constant.YiError
+
+/*
+ * read the whole body of response and reset the body so that it can be read again
+ */
+func ReadBody(resp *data.Response) ([]byte, *constant.YiError) {
+	httpResp := resp.HTTPResp()
+	multiReader, err := reader.NewMultipleReader(httpResp.Body)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_CRAWL_RECORD, err)
+	}
+	if httpResp.Body != nil {
+		httpResp.Body.Close()
+	}
+	httpResp.Body = multiReader.Reader()
+	body, err := ioutil.ReadAll(multiReader.Reader())
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_CRAWL_RECORD, err)
+	}
+	return body, nil
+}
+
+/*
+ * create a recorder which writes the request and response records into warc files
+ */
+func NewWarcRecorder(writer *warc.Writer) ResponseRecorder {
+	return func(resp *data.Response) *constant.YiError {
+		body, yierr := ReadBody(resp)
+		if yierr != nil {
+			return yierr
+		}
+		err := writer.WriteExchange(resp.HTTPRequest(), resp.HTTPResp(), body)
+		if err != nil {
+			return constant.NewYiErrorf(constant.ERR_CRAWL_RECORD,
+				"Write warc records fail: %s (URL: %s)", err, resp.HTTPRequest().URL)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,183 @@
+package fetcher
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+ * interface for fetching the response of a request
+ * *http.Client implements it.
+ * the implementation type of the interface must be concurrent and secure.
+ */
+type Fetcher interface {
+	Do(httpReq *http.Request) (*http.Response, error)
+}
+
+/*
+ * adapter to use an ordinary function as a Fetcher
+ */
+type FetcherFunc func(httpReq *http.Request) (*http.Response, error)
+
+/*
+ * call f(httpReq)
+ */
+func (f FetcherFunc) Do(httpReq *http.Request) (*http.Response, error) {
+	return f(httpReq)
+}
+
+/*
+ * interface for the registry which maps URL schemes to fetchers
+ * schemes are case insensitive.
+ */
+type Registry interface {
+	Register(scheme string, fetcher Fetcher) error // register the fetcher of scheme, replace the old one
+	Unregister(scheme string) bool                 // unregister the fetcher of scheme, return false if not found
+	Get(scheme string) (Fetcher, bool)             // get the fetcher of scheme
+	Supported(scheme string) bool                  // check whether scheme has a fetcher
+	Schemes() []string                             // get all supported schemes
+}
+
+/*
+ * create a registry
+ * fetchers not found in the registry are looked up in parent if it is not nil.
+ */
+func NewRegistry(parent Registry) Registry {
+	return &myRegistry{
+		parent:   parent,
+		fetchers: map[string]Fetcher{},
+	}
+}
+
+/*
+ * implementation of interface Registry
+ */
+type myRegistry struct {
+	parent   Registry           // parent registry
+	fetchers map[string]Fetcher // scheme-fetcher map
+	rwlock   sync.RWMutex       // read/write lock
+}
+
+/*
+ * register the fetcher of scheme
+ */
+func (registry *myRegistry) Register(scheme string, fetcher Fetcher) error {
+	scheme = strings.ToLower(strings.TrimSpace(scheme))
+	if scheme == "" {
+		return errors.New("empty scheme")
+	}
+	if fetcher == nil {
+		return errors.New("nil fetcher for scheme " + scheme)
+	}
+	registry.rwlock.Lock()
+	defer registry.rwlock.Unlock()
+	registry.fetchers[scheme] = fetcher
+	return nil
+}
+
+/*
+ * unregister the fetcher of scheme
+ */
+func (registry *myRegistry) Unregister(scheme string) bool {
+	scheme = strings.ToLower(scheme)
+	registry.rwlock.Lock()
+	defer registry.rwlock.Unlock()
+	if _, ok := registry.fetchers[scheme]; !ok {
+		return false
+	}
+	delete(registry.fetchers, scheme)
+	return true
+}
+
+/*
+ * get the fetcher of scheme
+ */
+func (registry *myRegistry) Get(scheme string) (Fetcher, bool) {
+	scheme = strings.ToLower(scheme)
+	registry.rwlock.RLock()
+	fetcher, ok := registry.fetchers[scheme]
+	registry.rwlock.RUnlock()
+	if !ok && registry.parent != nil {
+		return registry.parent.Get(scheme)
+	}
+	return fetcher, ok
+}
+
+/*
+ * check whether scheme has a fetcher
+ */
+func (registry *myRegistry) Supported(scheme string) bool {
+	_, ok := registry.Get(scheme)
+	return ok
+}
+
+/*
+ * get all supported schemes (sorted)
+ */
+func (registry *myRegistry) Schemes() []string {
+	schemeMap := map[string]struct{}{}
+	if registry.parent != nil {
+		for _, scheme := range registry.parent.Schemes() {
+			schemeMap[scheme] = struct{}{}
+		}
+	}
+	registry.rwlock.RLock()
+	for scheme := range registry.fetchers {
+		schemeMap[scheme] = struct{}{}
+	}
+	registry.rwlock.RUnlock()
+	schemes := []string{}
+	for scheme := range schemeMap {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// default registry: http(s) by http.DefaultClient
+// file is not registered by default, since crawled pages could link to local files,
+// it is opted in by Register("file", NewFileFetcher(root)) with the root of the served files.
+var defaultRegistry = NewRegistry(nil)
+
+func init() {
+	defaultRegistry.Register("http", http.DefaultClient)
+	defaultRegistry.Register("https", http.DefaultClient)
+}
+
+/*
+ * get the default registry
+ */
+func Default() Registry {
+	return defaultRegistry
+}
+
+/*
+ * register a fetcher of scheme into the default registry
+ */
+func Register(scheme string, fetcher Fetcher) error {
+	return defaultRegistry.Register(scheme, fetcher)
+}
+
+/*
+ * unregister the fetcher of scheme from the default registry
+ */
+func Unregister(scheme string) bool {
+	return defaultRegistry.Unregister(scheme)
+}
+
+/*
+ * get the fetcher of scheme from the default registry
+ */
+func Get(scheme string) (Fetcher, bool) {
+	return defaultRegistry.Get(scheme)
+}
+
+/*
+ * check whether scheme is supported by the default registry
+ */
+func Supported(scheme string) bool {
+	return defaultRegistry.Supported(scheme)
+}
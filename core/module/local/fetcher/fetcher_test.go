@@ -0,0 +1,119 @@
+package fetcher
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	parent := NewRegistry(nil)
+	parent.Register("http", http.DefaultClient)
+	registry := NewRegistry(parent)
+	fixtures := NewFixtureFetcher()
+	if err := registry.Register("MEM", fixtures); err != nil {
+		t.Fatalf("An error occurs when registering a fetcher: %s", err)
+	}
+	if err := registry.Register("", fixtures); err == nil {
+		t.Fatal("No error when registering a fetcher with empty scheme!")
+	}
+	if err := registry.Register("x", nil); err == nil {
+		t.Fatal("No error when registering a nil fetcher!")
+	}
+	for _, scheme := range []string{"mem", "Mem", "http"} {
+		if !registry.Supported(scheme) {
+			t.Fatalf("Scheme %q should be supported!", scheme)
+		}
+	}
+	if registry.Supported("ftp") {
+		t.Fatal("Scheme ftp should not be supported!")
+	}
+	schemes := registry.Schemes()
+	if len(schemes) != 2 || schemes[0] != "http" || schemes[1] != "mem" {
+		t.Fatalf("Inconsistent schemes: expected: %v, actual: %v", []string{"http", "mem"}, schemes)
+	}
+	if !registry.Unregister("mem") || registry.Supported("mem") {
+		t.Fatal("Couldn't unregister scheme mem!")
+	}
+	if registry.Unregister("http") {
+		t.Fatal("The scheme of parent registry should not be unregistered!")
+	}
+}
+
+func TestFixtureFetcher(t *testing.T) {
+	fixtures := NewFixtureFetcher()
+	url := "mem://site/a.html"
+	fixtures.AddBody(url, "text/html", []byte("<p>a</p>"))
+	httpReq, _ := http.NewRequest("GET", url, nil)
+	httpResp, err := fixtures.Do(httpReq)
+	if err != nil {
+		t.Fatalf("An error occurs when fetching %s: %s", url, err)
+	}
+	b, _ := ioutil.ReadAll(httpResp.Body)
+	if httpResp.StatusCode != 200 || string(b) != "<p>a</p>" {
+		t.Fatalf("Unexpected fixture response: %d %s", httpResp.StatusCode, b)
+	}
+	if httpResp.Header.Get("Content-Type") != "text/html" {
+		t.Fatalf("Inconsistent content type: %s", httpResp.Header.Get("Content-Type"))
+	}
+	httpReq, _ = http.NewRequest("GET", "mem://site/b.html", nil)
+	if _, err := fixtures.Do(httpReq); err == nil {
+		t.Fatal("No error when fetching a missing fixture!")
+	}
+}
+
+func TestFileFetcher(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "fetcher")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "page.html")
+	ioutil.WriteFile(path, []byte("<html></html>"), 0600)
+
+	if Supported("file") {
+		t.Fatal("Scheme file shouldn't be supported by default!")
+	}
+	registry := NewRegistry(Default())
+	registry.Register("file", NewFileFetcher(dir))
+	f, _ := registry.Get("file")
+	httpReq, _ := http.NewRequest("GET", "file:page.html", nil)
+	httpResp, err := f.Do(httpReq)
+	if err != nil {
+		t.Fatalf("An error occurs when fetching %s: %s", path, err)
+	}
+	b, _ := ioutil.ReadAll(httpResp.Body)
+	if httpResp.StatusCode != 200 || string(b) != "<html></html>" {
+		t.Fatalf("Unexpected file response: %d %s", httpResp.StatusCode, b)
+	}
+	if httpResp.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("Inconsistent content type: %s", httpResp.Header.Get("Content-Type"))
+	}
+
+	httpReq, _ = http.NewRequest("GET", "file:missing.html", nil)
+	httpResp, err = f.Do(httpReq)
+	if err != nil || httpResp.StatusCode != 404 {
+		t.Fatalf("A missing file should be reported as status 404: %v", err)
+	}
+
+	outside, _ := ioutil.TempDir("", "fetcher-outside")
+	defer os.RemoveAll(outside)
+	ioutil.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0600)
+	os.Symlink(outside, filepath.Join(dir, "link"))
+	for _, u := range []string{
+		"file://" + filepath.ToSlash(path),
+		"file:///etc/passwd",
+		"file://host/page.html",
+		"file:../" + filepath.Base(outside) + "/secret",
+		"file:sub/../../" + filepath.Base(outside) + "/secret",
+		"file:link/secret",
+	} {
+		httpReq, _ = http.NewRequest("GET", u, nil)
+		if _, err := f.Do(httpReq); err == nil {
+			t.Fatalf("No error when fetching %s outside root!", u)
+		}
+	}
+	httpReq, _ = http.NewRequest("GET", "file:page.html", nil)
+	if _, err := NewFileFetcher("").Do(httpReq); err == nil {
+		t.Fatal("No error when fetching by a file fetcher without root!")
+	}
+}
@@ -0,0 +1,118 @@
+package fetcher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/*
+ * create a fetcher for file: URLs serving the files under root
+ * the paths of the URLs are relative to root, such as file:pages/a.html,
+ * absolute paths and paths resolved outside root are refused.
+ * a fetcher without root refuses every URL.
+ */
+func NewFileFetcher(root string) Fetcher {
+	return &fileFetcher{root: root}
+}
+
+/*
+ * implementation of interface Fetcher based on the local file system
+ */
+type fileFetcher struct {
+	root string
+}
+
+/*
+ * read the file of the URL, a missing file is reported as status 404
+ */
+func (f *fileFetcher) Do(httpReq *http.Request) (*http.Response, error) {
+	if httpReq.URL == nil {
+		return nil, errors.New("nil URL")
+	}
+	path, err := f.resolve(httpReq.URL)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewResponse(httpReq, http.StatusNotFound, nil, nil), nil
+		}
+		return nil, err
+	}
+	header := http.Header{}
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	return NewResponse(httpReq, http.StatusOK, header, body), nil
+}
+
+/*
+ * get the path of the file of the URL under root
+ * the symbolic links are followed, so a link can't point outside root either.
+ */
+func (f *fileFetcher) resolve(u *url.URL) (string, error) {
+	if f.root == "" {
+		return "", errors.New("file fetcher without root")
+	}
+	if u.Host != "" {
+		return "", fmt.Errorf("file URL with host: %s", u)
+	}
+	path := u.Path
+	if path == "" {
+		// file:relative/path
+		path = u.Opaque
+	}
+	path = filepath.FromSlash(path)
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, string(filepath.Separator)) {
+		return "", fmt.Errorf("absolute or empty file path: %s", u)
+	}
+	root, err := filepath.Abs(f.root)
+	if err != nil {
+		return "", err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return "", err
+	}
+	path = filepath.Join(root, path)
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	if rel, err := filepath.Rel(root, path); err != nil || rel == ".." ||
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file path outside root: %s", u)
+	}
+	return path, nil
+}
+
+/*
+ * create a http response for a request from status code, header and body
+ */
+func NewResponse(httpReq *http.Request, statusCode int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(statusCode) + " " + http.StatusText(statusCode),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       httpReq,
+	}
+}
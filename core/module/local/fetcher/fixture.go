@@ -0,0 +1,74 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+/*
+ * a fixed response served by FixtureFetcher
+ */
+type Fixture struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+/*
+ * in-memory fetcher serving fixed responses by URL, used for tests
+ * register it for a custom scheme or in place of http(s).
+ */
+type FixtureFetcher struct {
+	fixtures map[string]*Fixture
+	rwlock   sync.RWMutex
+}
+
+/*
+ * create an instance of FixtureFetcher
+ */
+func NewFixtureFetcher() *FixtureFetcher {
+	return &FixtureFetcher{fixtures: map[string]*Fixture{}}
+}
+
+/*
+ * add the fixture of url, replace the old one
+ */
+func (f *FixtureFetcher) Add(url string, fixture *Fixture) {
+	f.rwlock.Lock()
+	defer f.rwlock.Unlock()
+	f.fixtures[url] = fixture
+}
+
+/*
+ * add a fixture of url with status 200 and the given content type and body
+ */
+func (f *FixtureFetcher) AddBody(url string, contentType string, body []byte) {
+	f.Add(url, &Fixture{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       body,
+	})
+}
+
+/*
+ * get the fixture of the request URL, it fails on a miss
+ */
+func (f *FixtureFetcher) Do(httpReq *http.Request) (*http.Response, error) {
+	url := httpReq.URL.String()
+	f.rwlock.RLock()
+	fixture, ok := f.fixtures[url]
+	f.rwlock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("fixture not found: %s", url)
+	}
+	header := http.Header{}
+	for key, values := range fixture.Header {
+		header[key] = append([]string{}, values...)
+	}
+	statusCode := fixture.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	return NewResponse(httpReq, statusCode, header, fixture.Body), nil
+}
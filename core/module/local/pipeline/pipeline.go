@@ -15,6 +15,7 @@ type myPipeline struct {
 	stub.ModuleInternal
 	itemProcessors []module.ProcessItem
 	failFast       bool
+	itemValidator  module.ProcessItem
 }
 
 /*
@@ -69,6 +70,16 @@ func (pipeline *myPipeline) Send(item data.Item) (yierrs []*constant.YiError) {
 	pipeline.IncrAcceptedCount()
 	log.Infof("Process item %+v... \n", item)
 	currentItem := item
+	if pipeline.itemValidator != nil {
+		validItem, yierr := pipeline.itemValidator(item)
+		if yierr != nil {
+			yierrs = append(yierrs, yierr)
+			return
+		}
+		if validItem != nil {
+			currentItem = validItem
+		}
+	}
 	for _, processor := range pipeline.itemProcessors {
 		processedItem, yierr := processor(currentItem)
 		if yierr != nil {
@@ -101,6 +112,20 @@ func (pipeline *myPipeline) SetFailFast(failFast bool) {
 	pipeline.failFast = failFast
 }
 
+/*
+ * get the item validator
+ */
+func (pipeline *myPipeline) ItemValidator() module.ProcessItem {
+	return pipeline.itemValidator
+}
+
+/*
+ * set the item validator
+ */
+func (pipeline *myPipeline) SetItemValidator(validator module.ProcessItem) {
+	pipeline.itemValidator = validator
+}
+
 /*
  * used in module.SummaryStruct.Extra
  */
@@ -142,6 +142,44 @@ func TestFailFast(t *testing.T) {
 	}
 }
 
+func TestItemValidator(t *testing.T) {
+	mid := module.MID("D1|127.0.0.1:8080")
+	processors := []module.ProcessItem{genTestingItemProccessor(false)}
+	p, err := New(mid, processors, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a pipeline: %s (mid: %s, processors: %#v)",
+			err, mid, processors)
+	}
+	if p.ItemValidator() != nil {
+		t.Fatal("Non-nil item validator for a new pipeline!")
+	}
+	p.SetItemValidator(func(item data.Item) (data.Item, *constant.YiError) {
+		if item["number"] == nil {
+			return nil, constant.NewYiErrorf(constant.ERR_ITEM_INVALID, "field number: required")
+		}
+		return data.Item{"number": item["number"].(int) + 10}, nil
+	})
+	item := data.Item(map[string]interface{}{"number": 0})
+	errs := p.Send(item)
+	if len(errs) != 0 {
+		t.Fatalf("Inconsistent error number after Send(): expected: %d, actual: %d",
+			0, len(errs))
+	}
+	// the processors get the item returned by the validator
+	if item["number"].(int) != 0 {
+		t.Fatalf("The validated item is not a new item: %v", item)
+	}
+	// the invalid item isn't processed
+	item = data.Item(map[string]interface{}{"other": 0})
+	errs = p.Send(item)
+	if len(errs) != 1 || errs[0].ErrNo != constant.ERR_ITEM_INVALID {
+		t.Fatalf("Wrong errors of an invalid item: %v", errs)
+	}
+	if _, ok := item["number"]; ok {
+		t.Fatalf("The invalid item was processed: %v", item)
+	}
+}
+
 func TestCount(t *testing.T) {
 	mid := module.MID("D1|127.0.0.1:8080")
 	processors := []module.ProcessItem{genTestingItemProccessor(false)}
@@ -0,0 +1,90 @@
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/module/local/downloader"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * create a recorder which stores every downloaded response keyed by request fingerprint
+ */
+func NewRecorder(store Store) downloader.ResponseRecorder {
+	return func(resp *data.Response) *constant.YiError {
+		body, yierr := downloader.ReadBody(resp)
+		if yierr != nil {
+			return yierr
+		}
+		httpReq := resp.HTTPRequest()
+		httpResp := resp.HTTPResp()
+		record := &Record{
+			URL:        httpReq.URL.String(),
+			Method:     httpReq.Method,
+			StatusCode: httpResp.StatusCode,
+			Proto:      httpResp.Proto,
+			Header:     httpResp.Header,
+			Body:       body,
+		}
+		if err := store.Put(resp.Request().Fingerprint(), record); err != nil {
+			return constant.NewYiErrorf(constant.ERR_CRAWL_RECORD,
+				"Store response fail: %s (URL: %s)", err, record.URL)
+		}
+		return nil
+	}
+}
+
+/*
+ * create a http transport which serves responses only from the store
+ * a request not in the store fails, nothing is sent to the network.
+ */
+func NewTransport(store Store) http.RoundTripper {
+	return &transport{store: store}
+}
+
+/*
+ * implementation of interface http.RoundTripper for replaying
+ */
+type transport struct {
+	store Store
+}
+
+/*
+ * get the stored response of the request
+ */
+func (t *transport) RoundTrip(httpReq *http.Request) (*http.Response, error) {
+	if httpReq.Body != nil {
+		httpReq.Body.Close()
+	}
+	record, err := t.store.Get(data.Fingerprint(httpReq))
+	if err != nil {
+		return nil, fmt.Errorf("replay %s %s: %s", httpReq.Method, httpReq.URL, err)
+	}
+	proto := record.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	major, minor, ok := http.ParseHTTPVersion(proto)
+	if !ok {
+		major, minor = 1, 1
+	}
+	header := record.Header
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", record.StatusCode, http.StatusText(record.StatusCode)),
+		StatusCode:    record.StatusCode,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(record.Body)),
+		ContentLength: int64(len(record.Body)),
+		Request:       httpReq,
+	}, nil
+}
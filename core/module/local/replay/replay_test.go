@@ -0,0 +1,93 @@
+package replay
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "replay")
+	defer os.RemoveAll(dir)
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a file store: %s", err)
+	}
+	url := "http://example.com/list?page=2"
+	httpReq, _ := http.NewRequest("GET", url, nil)
+	body := []byte("<html>page 2</html>")
+	httpResp := &http.Response{
+		StatusCode: 404,
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{"X-Test": []string{"yes"}},
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	}
+	resp := data.NewResponse(data.NewRequest(httpReq), httpResp)
+	if yierr := NewRecorder(store)(resp); yierr != nil {
+		t.Fatalf("An error occurs when recording a response: %s", yierr)
+	}
+	// the body must still be readable after recording
+	b, _ := ioutil.ReadAll(httpResp.Body)
+	if !bytes.Equal(b, body) {
+		t.Fatalf("Inconsistent body after recording: expected: %s, actual: %s", body, b)
+	}
+
+	client := &http.Client{Transport: NewTransport(store)}
+	replayed, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("An error occurs when replaying %s: %s", url, err)
+	}
+	if replayed.StatusCode != 404 {
+		t.Fatalf("Inconsistent status code: expected: %d, actual: %d", 404, replayed.StatusCode)
+	}
+	if replayed.Header.Get("X-Test") != "yes" {
+		t.Fatalf("Inconsistent header: expected: %s, actual: %s", "yes", replayed.Header.Get("X-Test"))
+	}
+	b, _ = ioutil.ReadAll(replayed.Body)
+	if !bytes.Equal(b, body) {
+		t.Fatalf("Inconsistent replayed body: expected: %s, actual: %s", body, b)
+	}
+
+	if _, err := client.Get("http://example.com/list?page=3"); err == nil {
+		t.Fatal("No error when replaying a request which is not recorded!")
+	}
+}
+
+func TestConcurrentPut(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "replay")
+	defer os.RemoveAll(dir)
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a file store: %s", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := bytes.Repeat([]byte{byte('a' + i)}, 4096)
+			if err := store.Put("abcdef", &Record{URL: "http://example.com/", Body: body}); err != nil {
+				t.Errorf("An error occurs when putting record %d: %s", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	record, err := store.Get("abcdef")
+	if err != nil {
+		t.Fatalf("An error occurs when getting the record: %s", err)
+	}
+	if len(record.Body) != 4096 || strings.Trim(string(record.Body), string(record.Body[:1])) != "" {
+		t.Fatalf("The record was mixed by concurrent puts")
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "*.tmp*"))
+	if len(files) != 0 {
+		t.Fatalf("Temporary files are left: %v", files)
+	}
+}
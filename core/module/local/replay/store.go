@@ -0,0 +1,113 @@
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/l-dandelion/yi-ants-go/lib/utils"
+)
+
+// the error returned when a response is not in the store
+var ErrNotFound = errors.New("response not found in replay store")
+
+/*
+ * a stored response
+ */
+type Record struct {
+	URL        string      `json:"url"`
+	Method     string      `json:"method"`
+	StatusCode int         `json:"status_code"`
+	Proto      string      `json:"proto"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+/*
+ * interface for the store of recorded responses
+ * the implementation type of the interface must be concurrent and secure.
+ */
+type Store interface {
+	Put(fingerprint string, record *Record) error // store the record under fingerprint
+	Get(fingerprint string) (*Record, error)      // get the record by fingerprint, ErrNotFound on a miss
+}
+
+/*
+ * create a store which keeps every record in a json file under dir
+ */
+func NewFileStore(dir string) (Store, error) {
+	absDir, err := utils.CheckDirPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &fileStore{dir: absDir}, nil
+}
+
+/*
+ * implementation of interface Store based on files
+ */
+type fileStore struct {
+	dir string
+}
+
+/*
+ * store the record, the file is written to a unique temporary file and renamed
+ */
+func (store *fileStore) Put(fingerprint string, record *Record) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	path := store.path(fingerprint)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// a temporary file of its own for every writer, so concurrent puts of a key don't mix
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+/*
+ * get the record by fingerprint
+ */
+func (store *fileStore) Get(fingerprint string) (*Record, error) {
+	b, err := ioutil.ReadFile(store.path(fingerprint))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	record := &Record{}
+	if err := json.Unmarshal(b, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+/*
+ * get the file path of a fingerprint, files are spread over sub directories
+ */
+func (store *fileStore) path(fingerprint string) string {
+	sub := "_"
+	if len(fingerprint) >= 2 {
+		sub = fingerprint[:2]
+	}
+	return filepath.Join(store.dir, sub, fingerprint+".json")
+}
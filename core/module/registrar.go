@@ -111,6 +111,8 @@ func (registrar *myRegistrar) GetAll() map[MID]Module {
  * clear all instances of module
  */
 func (registrar *myRegistrar) Clear() {
+	registrar.rwlock.Lock()
+	defer registrar.rwlock.Unlock()
 	registrar.typeModuleMap = map[int8]map[MID]Module{}
 }
 
@@ -151,7 +153,7 @@ func (registrar *myRegistrar) Get(mtype int8) (module Module, yierr *constant.Yi
 	for _, m := range modules {
 		SetScore(m)
 		score := m.Score()
-		if minScore == 0 || score < minScore {
+		if module == nil || score < minScore {
 			minScore = score
 			module = m
 		}
@@ -0,0 +1,250 @@
+package remote
+
+import (
+	"math"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+// an unhealthy module is not picked again until the interval passes
+const RETRY_INTERVAL = 10 * time.Second
+
+// the interval of pinging the remote modules in use
+const PING_INTERVAL = 5 * time.Second
+
+/*
+ * interface for calling a rpc service, *rpc.Client is an implementation
+ */
+type Caller interface {
+	Call(serviceMethod string, args interface{}, reply interface{}) error
+	Close() error
+}
+
+/*
+ * function for connecting to the node at addr (ip:port)
+ */
+type DialFunc func(addr string) (Caller, error)
+
+/*
+ * dial by the tcp rpc client
+ */
+func DialTCP(addr string) (Caller, error) {
+	return rpc.Dial("tcp", addr)
+}
+
+/*
+ * the health of a remote module
+ */
+type Health struct {
+	Healthy     bool      `json:"healthy"`
+	LastFailure time.Time `json:"last_failure"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+/*
+ * used in module.SummaryStruct.Extra
+ * Remote: the summary reported by the hosting node at the last call
+ */
+type extraSummaryStruct struct {
+	Addr    string               `json:"addr"`
+	Healthy bool                 `json:"healthy"`
+	Remote  module.SummaryStruct `json:"remote"`
+}
+
+/*
+ * connection to the node hosting a module, shared by the remote modules
+ * the implementation is concurrent and secure.
+ */
+type client struct {
+	addr   string
+	dial   DialFunc
+	caller Caller
+	health Health
+	remote module.SummaryStruct
+	rwlock sync.RWMutex
+}
+
+/*
+ * create a client of the node at addr
+ */
+func newClient(addr string, dial DialFunc) *client {
+	if dial == nil {
+		dial = DialTCP
+	}
+	return &client{
+		addr:   addr,
+		dial:   dial,
+		health: Health{Healthy: true},
+	}
+}
+
+/*
+ * call the service method, the connection is created lazily and dropped on failure
+ * only failures of connection mark the module unhealthy.
+ */
+func (c *client) call(serviceMethod string, args interface{}, reply interface{}) *constant.YiError {
+	c.rwlock.Lock()
+	caller := c.caller
+	if caller == nil {
+		var err error
+		caller, err = c.dial(c.addr)
+		if err != nil {
+			c.fail(err)
+			c.rwlock.Unlock()
+			return constant.NewYiErrorf(constant.ERR_RPC_CLIENT_DIAL,
+				"Connect to remote module fail: %s (addr: %s)", err, c.addr)
+		}
+		c.caller = caller
+	}
+	c.rwlock.Unlock()
+
+	if err := caller.Call(serviceMethod, args, reply); err != nil {
+		c.rwlock.Lock()
+		// rpc.ServerError means the connection is fine
+		if _, ok := err.(rpc.ServerError); !ok {
+			if c.caller == caller {
+				c.caller = nil
+				caller.Close()
+			}
+			c.fail(err)
+		}
+		c.rwlock.Unlock()
+		return constant.NewYiErrorf(constant.ERR_RPC_CALL,
+			"Call %s fail: %s (addr: %s)", serviceMethod, err, c.addr)
+	}
+	c.rwlock.Lock()
+	c.health.Healthy = true
+	c.rwlock.Unlock()
+	return nil
+}
+
+/*
+ * mark the module unhealthy, the lock must be held
+ */
+func (c *client) fail(err error) {
+	c.health = Health{
+		Healthy:     false,
+		LastFailure: time.Now(),
+		LastError:   err.Error(),
+	}
+}
+
+/*
+ * get the health of the module
+ */
+func (c *client) Health() Health {
+	c.rwlock.RLock()
+	defer c.rwlock.RUnlock()
+	return c.health
+}
+
+/*
+ * check whether the module could be called
+ * an unhealthy module is given another chance after RETRY_INTERVAL.
+ */
+func (c *client) available() bool {
+	health := c.Health()
+	return health.Healthy || time.Since(health.LastFailure) >= RETRY_INTERVAL
+}
+
+/*
+ * store the summary reported by the hosting node
+ */
+func (c *client) setRemoteSummary(summary module.SummaryStruct) {
+	c.rwlock.Lock()
+	c.remote = summary
+	c.rwlock.Unlock()
+}
+
+/*
+ * get the summary reported by the hosting node at the last call
+ */
+func (c *client) remoteSummary() module.SummaryStruct {
+	c.rwlock.RLock()
+	defer c.rwlock.RUnlock()
+	return c.remote
+}
+
+/*
+ * fetch the summary of the hosted module, used for health checking
+ */
+func (c *client) ping(mid module.MID) *constant.YiError {
+	reply := &SummaryReply{}
+	if yierr := c.call(SERVICE_MODULE_SUMMARY, &SummaryArgs{MID: mid}, reply); yierr != nil {
+		return yierr
+	}
+	if reply.Yierr != nil {
+		return reply.Yierr
+	}
+	c.setRemoteSummary(reply.Summary)
+	return nil
+}
+
+/*
+ * ping the modules every interval until stop is closed
+ * the health and the handling number reported by the hosting node are kept fresh for picking,
+ * and an unhealthy module turns healthy once its node could be reached again.
+ */
+func KeepPinging(modules []Module, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, m := range modules {
+				m.Ping()
+			}
+		}
+	}
+}
+
+/*
+ * wrap the score calculator
+ * the score of an unavailable module is the max, so that it will not be picked,
+ * and the handling number reported by the hosting node is taken into account.
+ */
+func (c *client) scoreCalculator(calculator module.CalculateScore) module.CalculateScore {
+	if calculator == nil {
+		calculator = module.CalculateScoreSimple
+	}
+	return func(counts module.Counts) uint64 {
+		if !c.available() {
+			return math.MaxUint64
+		}
+		if remote := c.remoteSummary(); remote.Handling > counts.HandlingNumber {
+			counts.HandlingNumber = remote.Handling
+		}
+		return calculator(counts)
+	}
+}
+
+/*
+ * the extra summary of the module
+ */
+func (c *client) extraSummary() extraSummaryStruct {
+	return extraSummaryStruct{
+		Addr:    c.addr,
+		Healthy: c.Health().Healthy,
+		Remote:  c.remoteSummary(),
+	}
+}
+
+/*
+ * close the connection
+ */
+func (c *client) close() error {
+	c.rwlock.Lock()
+	defer c.rwlock.Unlock()
+	if c.caller == nil {
+		return nil
+	}
+	err := c.caller.Close()
+	c.caller = nil
+	return err
+}
@@ -0,0 +1,132 @@
+package remote
+
+import (
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/module/stub"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+	"github.com/l-dandelion/yi-ants-go/lib/library/pool"
+)
+
+/*
+ * interface for the modules running on another node
+ */
+type Module interface {
+	module.Module
+	Health() Health          // get the health of the module
+	Ping() *constant.YiError // fetch the summary from the hosting node and update the health
+	Close() error            // close the connection to the hosting node
+}
+
+/*
+ * create a downloader which forwards the downloading to the node in the mid
+ * the mid must contain the network address, such as D1|192.168.1.2:8300
+ */
+func NewDownloader(
+	mid module.MID,
+	scoreCalculator module.CalculateScore,
+	maxThread int,
+	dial DialFunc) (downloader module.Downloader, yierr *constant.YiError) {
+	moduleBase, yierr := newModuleBase(mid, module.TYPE_DOWNLOADER)
+	if yierr != nil {
+		return
+	}
+	c := newClient(moduleBase.Addr(), dial)
+	return &myDownloader{
+		ModuleInternal:  moduleBase,
+		client:          c,
+		scoreCalculator: c.scoreCalculator(scoreCalculator),
+		Pool:            *pool.NewPool(maxThread),
+	}, nil
+}
+
+/*
+ * create the module internal of a remote module and check the mid
+ */
+func newModuleBase(mid module.MID, mtype int8) (stub.ModuleInternal, *constant.YiError) {
+	actualType, yierr := module.GetType(mid)
+	if yierr != nil {
+		return nil, yierr
+	}
+	if actualType != mtype {
+		return nil, constant.NewYiErrorf(constant.ERR_REMOTE_MODULE,
+			"Inconsistent module type: expected: %d, actual: %d (mid: %s)", mtype, actualType, mid)
+	}
+	moduleBase, yierr := stub.NewModuleInternal(mid, nil)
+	if yierr != nil {
+		return nil, yierr
+	}
+	if moduleBase.Addr() == "" {
+		return nil, constant.NewYiErrorf(constant.ERR_REMOTE_MODULE,
+			"Empty network address in mid: %s", mid)
+	}
+	return moduleBase, nil
+}
+
+/*
+ * implementation of interface module.Downloader and Module
+ */
+type myDownloader struct {
+	stub.ModuleInternal
+	*client
+	scoreCalculator module.CalculateScore
+	pool.Pool
+}
+
+/*
+ * download by the downloader on the hosting node
+ */
+func (downloader *myDownloader) Download(req *data.Request) (*data.Response, *constant.YiError) {
+	downloader.IncrHandlingNumber()
+	defer downloader.DecrHandlingNumber()
+	downloader.IncrCalledCount()
+	if req == nil || !req.Valid() {
+		return nil, constant.NewYiErrorf(constant.ERR_CRAWL_DOWNLOADER, "Invalid request.")
+	}
+	downloader.IncrAcceptedCount()
+
+	reply := &DownloadReply{}
+	args := &DownloadArgs{MID: downloader.ID(), Req: req}
+	if yierr := downloader.call(SERVICE_DOWNLOAD, args, reply); yierr != nil {
+		return nil, yierr
+	}
+	downloader.setRemoteSummary(reply.Summary)
+	if reply.Resp == nil {
+		if reply.Yierr == nil {
+			reply.Yierr = constant.NewYiErrorf(constant.ERR_REMOTE_MODULE, "Nil remote response.")
+		}
+		return nil, reply.Yierr
+	}
+	downloader.IncrCompletedCount()
+	return reply.Resp.DataResponse(req), reply.Yierr
+}
+
+/*
+ * rewrite ScoreCalculator(), unhealthy module gets the max score
+ */
+func (downloader *myDownloader) ScoreCalculator() module.CalculateScore {
+	return downloader.scoreCalculator
+}
+
+/*
+ * fetch the summary from the hosting node and update the health
+ */
+func (downloader *myDownloader) Ping() *constant.YiError {
+	return downloader.ping(downloader.ID())
+}
+
+/*
+ * close the connection to the hosting node
+ */
+func (downloader *myDownloader) Close() error {
+	return downloader.close()
+}
+
+/*
+ * rewrite Summary()
+ */
+func (downloader *myDownloader) Summary() module.SummaryStruct {
+	summary := downloader.ModuleInternal.Summary()
+	summary.Extra = downloader.extraSummary()
+	return summary
+}
@@ -0,0 +1,106 @@
+package remote
+
+import (
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * handler of remote module calls on the hosting node
+ * the methods have the form of net/rpc, so that a rpc server could delegate to them,
+ * or the handler could be registered by rpc.RegisterName("RpcServer", handler) directly.
+ */
+type Handler struct {
+	registrar module.Registrar
+}
+
+/*
+ * create a handler serving the modules in the registrar
+ */
+func NewHandler(registrar module.Registrar) *Handler {
+	return &Handler{registrar: registrar}
+}
+
+/*
+ * get the hosted module by mid
+ */
+func (h *Handler) getModule(mid module.MID) (module.Module, *constant.YiError) {
+	mtype, yierr := module.GetType(mid)
+	if yierr != nil {
+		return nil, yierr
+	}
+	modules, yierr := h.registrar.GetAllByType(mtype)
+	if yierr != nil {
+		return nil, yierr
+	}
+	m, ok := modules[mid]
+	if !ok {
+		return nil, constant.NewYiErrorf(constant.ERR_MODULE_NOT_FOUND,
+			"The module instance not found.(mid: %s)", mid)
+	}
+	return m, nil
+}
+
+/*
+ * download by the hosted downloader
+ */
+func (h *Handler) Download(args *DownloadArgs, reply *DownloadReply) error {
+	m, yierr := h.getModule(args.MID)
+	if yierr != nil {
+		reply.Yierr = yierr
+		return nil
+	}
+	downloader, ok := m.(module.Downloader)
+	if !ok {
+		reply.Yierr = constant.NewYiErrorf(constant.ERR_REMOTE_MODULE,
+			"The module is not a downloader.(mid: %s)", args.MID)
+		return nil
+	}
+	downloader.Add()
+	defer downloader.Done()
+	resp, yierr := downloader.Download(args.Req)
+	reply.Yierr = yierr
+	if resp != nil {
+		wireResp, err := NewResponse(resp)
+		if err != nil {
+			reply.Yierr = constant.NewYiErrore(constant.ERR_CRAWL_DOWNLOADER, err)
+		} else {
+			reply.Resp = wireResp
+		}
+	}
+	reply.Summary = wireSummary(downloader)
+	return nil
+}
+
+/*
+ * send the item to the hosted pipeline
+ */
+func (h *Handler) SendItem(args *SendItemArgs, reply *SendItemReply) error {
+	m, yierr := h.getModule(args.MID)
+	if yierr != nil {
+		reply.Yierrs = []*constant.YiError{yierr}
+		return nil
+	}
+	pipeline, ok := m.(module.Pipeline)
+	if !ok {
+		reply.Yierrs = []*constant.YiError{constant.NewYiErrorf(constant.ERR_REMOTE_MODULE,
+			"The module is not a pipeline.(mid: %s)", args.MID)}
+		return nil
+	}
+	reply.Yierrs = pipeline.Send(args.Item)
+	reply.Summary = wireSummary(pipeline)
+	return nil
+}
+
+/*
+ * get the summary of the hosted module
+ */
+func (h *Handler) ModuleSummary(args *SummaryArgs, reply *SummaryReply) error {
+	m, yierr := h.getModule(args.MID)
+	if yierr != nil {
+		reply.Yierr = yierr
+		return nil
+	}
+	reply.Summary = wireSummary(m)
+	return nil
+}
@@ -0,0 +1,132 @@
+package remote
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+// service methods of the rpc server hosting the real modules
+const (
+	SERVICE_DOWNLOAD       = "RpcServer.Download"
+	SERVICE_SEND_ITEM      = "RpcServer.SendItem"
+	SERVICE_MODULE_SUMMARY = "RpcServer.ModuleSummary"
+)
+
+/*
+ * args of a remote download
+ * MID: the id of the hosted downloader
+ * Req: the request to download
+ */
+type DownloadArgs struct {
+	MID module.MID
+	Req *data.Request
+}
+
+/*
+ * reply of a remote download
+ * Resp is nil if the download failed
+ */
+type DownloadReply struct {
+	Resp    *Response
+	Yierr   *constant.YiError
+	Summary module.SummaryStruct
+}
+
+/*
+ * args of sending an item to a remote pipeline
+ */
+type SendItemArgs struct {
+	MID  module.MID
+	Item data.Item
+}
+
+/*
+ * reply of sending an item to a remote pipeline
+ */
+type SendItemReply struct {
+	Yierrs  []*constant.YiError
+	Summary module.SummaryStruct
+}
+
+/*
+ * args of getting the summary of a hosted module
+ */
+type SummaryArgs struct {
+	MID module.MID
+}
+
+/*
+ * reply of getting the summary of a hosted module
+ */
+type SummaryReply struct {
+	Yierr   *constant.YiError
+	Summary module.SummaryStruct
+}
+
+/*
+ * http response in wire format
+ * the body is read completely, since a http response can not be encoded.
+ */
+type Response struct {
+	Status     string
+	StatusCode int
+	Proto      string
+	Header     http.Header
+	Body       []byte
+}
+
+/*
+ * convert a downloaded response into wire format
+ */
+func NewResponse(resp *data.Response) (*Response, error) {
+	httpResp := resp.HTTPResp()
+	var body []byte
+	if httpResp.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(httpResp.Body)
+		httpResp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Response{
+		Status:     httpResp.Status,
+		StatusCode: httpResp.StatusCode,
+		Proto:      httpResp.Proto,
+		Header:     httpResp.Header,
+		Body:       body,
+	}, nil
+}
+
+/*
+ * rebuild the response of the request from wire format
+ */
+func (r *Response) DataResponse(req *data.Request) *data.Response {
+	httpResp := &http.Response{
+		Status:        r.Status,
+		StatusCode:    r.StatusCode,
+		Proto:         r.Proto,
+		Header:        r.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req.HTTPReq(),
+	}
+	if httpResp.Header == nil {
+		httpResp.Header = http.Header{}
+	}
+	return data.NewResponse(req, httpResp)
+}
+
+/*
+ * summary without extra information, which can not be encoded by gob
+ */
+func wireSummary(m module.Module) module.SummaryStruct {
+	summary := m.Summary()
+	summary.Extra = nil
+	return summary
+}
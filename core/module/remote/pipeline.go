@@ -0,0 +1,139 @@
+package remote
+
+import (
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/module/stub"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * create a pipeline which forwards the items to the node in the mid
+ * the mid must contain the network address, such as P1|192.168.1.2:8300
+ */
+func NewPipeline(
+	mid module.MID,
+	scoreCalculator module.CalculateScore,
+	dial DialFunc) (pipeline module.Pipeline, yierr *constant.YiError) {
+	moduleBase, yierr := newModuleBase(mid, module.TYPE_PIPELINE)
+	if yierr != nil {
+		return
+	}
+	c := newClient(moduleBase.Addr(), dial)
+	return &myPipeline{
+		ModuleInternal:  moduleBase,
+		client:          c,
+		scoreCalculator: c.scoreCalculator(scoreCalculator),
+	}, nil
+}
+
+/*
+ * implementation of interface module.Pipeline and Module
+ */
+type myPipeline struct {
+	stub.ModuleInternal
+	*client
+	scoreCalculator module.CalculateScore
+	failFast        bool
+	itemValidator   module.ProcessItem
+}
+
+/*
+ * the item processors run on the hosting node
+ */
+func (pipeline *myPipeline) ItemProcessors() []module.ProcessItem {
+	return []module.ProcessItem{}
+}
+
+/*
+ * send the item to the pipeline on the hosting node
+ */
+func (pipeline *myPipeline) Send(item data.Item) []*constant.YiError {
+	pipeline.IncrHandlingNumber()
+	defer pipeline.DecrHandlingNumber()
+	pipeline.IncrCalledCount()
+	if item == nil {
+		return []*constant.YiError{constant.NewYiErrorf(constant.ERR_CRAWL_PIPELINE, "Nil item")}
+	}
+	pipeline.IncrAcceptedCount()
+	// invalid items are dropped before being sent
+	if pipeline.itemValidator != nil {
+		validItem, yierr := pipeline.itemValidator(item)
+		if yierr != nil {
+			return []*constant.YiError{yierr}
+		}
+		if validItem != nil {
+			item = validItem
+		}
+	}
+
+	reply := &SendItemReply{}
+	args := &SendItemArgs{MID: pipeline.ID(), Item: item}
+	if yierr := pipeline.call(SERVICE_SEND_ITEM, args, reply); yierr != nil {
+		return []*constant.YiError{yierr}
+	}
+	pipeline.setRemoteSummary(reply.Summary)
+	if len(reply.Yierrs) == 0 {
+		pipeline.IncrCompletedCount()
+	}
+	return reply.Yierrs
+}
+
+/*
+ * get failFast
+ * it is only kept locally, the pipeline on the hosting node decides by itself.
+ */
+func (pipeline *myPipeline) FailFast() bool {
+	return pipeline.failFast
+}
+
+/*
+ * set failFast, it takes no effect on the pipeline on the hosting node
+ */
+func (pipeline *myPipeline) SetFailFast(failFast bool) {
+	pipeline.failFast = failFast
+}
+
+/*
+ * get the item validator
+ */
+func (pipeline *myPipeline) ItemValidator() module.ProcessItem {
+	return pipeline.itemValidator
+}
+
+/*
+ * set the item validator, which runs locally before the item is sent
+ */
+func (pipeline *myPipeline) SetItemValidator(validator module.ProcessItem) {
+	pipeline.itemValidator = validator
+}
+
+/*
+ * rewrite ScoreCalculator(), unhealthy module gets the max score
+ */
+func (pipeline *myPipeline) ScoreCalculator() module.CalculateScore {
+	return pipeline.scoreCalculator
+}
+
+/*
+ * fetch the summary from the hosting node and update the health
+ */
+func (pipeline *myPipeline) Ping() *constant.YiError {
+	return pipeline.ping(pipeline.ID())
+}
+
+/*
+ * close the connection to the hosting node
+ */
+func (pipeline *myPipeline) Close() error {
+	return pipeline.close()
+}
+
+/*
+ * rewrite Summary()
+ */
+func (pipeline *myPipeline) Summary() module.SummaryStruct {
+	summary := pipeline.ModuleInternal.Summary()
+	summary.Extra = pipeline.extraSummary()
+	return summary
+}
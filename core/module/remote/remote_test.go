@@ -0,0 +1,204 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/module/local/downloader"
+	"github.com/l-dandelion/yi-ants-go/core/module/local/pipeline"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+// start a rpc server hosting the modules, return its address
+func startServer(t *testing.T, modules ...module.Module) string {
+	registrar := module.NewRegistrar()
+	for _, m := range modules {
+		if yierr := registrar.Register(m); yierr != nil {
+			t.Fatalf("An error occurs when registering a module: %s", yierr)
+		}
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("RpcServer", NewHandler(registrar)); err != nil {
+		t.Fatalf("An error occurs when registering the handler: %s", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("An error occurs when listening: %s", err)
+	}
+	go server.Accept(listener)
+	return listener.Addr().String()
+}
+
+// dial to addr whatever the address in mid is
+func dialTo(addr string) DialFunc {
+	return func(string) (Caller, error) {
+		return DialTCP(addr)
+	}
+}
+
+func TestRemoteDownloader(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "hello %s", r.URL.Path)
+	}))
+	defer site.Close()
+
+	// the hosting node is reached by the dial function instead of the address in mid
+	mid := module.MID("D1|127.0.0.1:1")
+	hosted, yierr := downloader.New(mid, &http.Client{}, nil, 1)
+	if yierr != nil {
+		t.Fatalf("An error occurs when creating a downloader: %s", yierr)
+	}
+	addr := startServer(t, hosted)
+	d, yierr := NewDownloader(mid, nil, 1, dialTo(addr))
+	if yierr != nil {
+		t.Fatalf("An error occurs when creating a remote downloader: %s", yierr)
+	}
+	httpReq, _ := http.NewRequest("GET", site.URL+"/a", nil)
+	resp, yierr := d.Download(data.NewRequest(httpReq))
+	if yierr != nil {
+		t.Fatalf("An error occurs when downloading: %s", yierr)
+	}
+	body, _ := ioutil.ReadAll(resp.HTTPResp().Body)
+	if string(body) != "hello /a" || resp.HTTPResp().StatusCode != 200 {
+		t.Fatalf("Unexpected remote response: %d %s", resp.HTTPResp().StatusCode, body)
+	}
+	if resp.HTTPRequest() != httpReq {
+		t.Fatal("The response should refer to the original request!")
+	}
+	summary := d.Summary()
+	if summary.Completed != 1 {
+		t.Fatalf("Inconsistent completed count: expected: %d, actual: %d", 1, summary.Completed)
+	}
+	if extra := summary.Extra.(extraSummaryStruct); !extra.Healthy || extra.Remote.Called != 1 {
+		t.Fatalf("Unexpected extra summary: %+v", extra)
+	}
+	d.(Module).Close()
+}
+
+func TestRemotePipeline(t *testing.T) {
+	items := make(chan data.Item, 1)
+	processor := func(item data.Item) (data.Item, *constant.YiError) {
+		items <- item
+		return nil, nil
+	}
+	hosted, yierr := pipeline.New("P1|127.0.0.1:1", []module.ProcessItem{processor}, nil)
+	if yierr != nil {
+		t.Fatalf("An error occurs when creating a pipeline: %s", yierr)
+	}
+	addr := startServer(t, hosted)
+
+	// the module is hosted with another mid
+	p, yierr := NewPipeline(module.MID("P1|"+addr), nil, nil)
+	if yierr != nil {
+		t.Fatalf("An error occurs when creating a remote pipeline: %s", yierr)
+	}
+	if yierrs := p.Send(data.Item{"a": "b"}); len(yierrs) == 0 {
+		t.Fatal("No error when sending to a module not hosted!")
+	}
+	if !p.(Module).Health().Healthy {
+		t.Fatal("A module not found should not be unhealthy!")
+	}
+	if yierr := p.(Module).Ping(); yierr == nil {
+		t.Fatal("No error when pinging a module not hosted!")
+	}
+
+	p, _ = NewPipeline("P1|127.0.0.1:1", nil, dialTo(addr))
+	if yierrs := p.Send(data.Item{"a": "b"}); len(yierrs) != 0 {
+		t.Fatalf("An error occurs when sending an item: %v", yierrs)
+	}
+	if item := <-items; item["a"] != "b" {
+		t.Fatalf("Inconsistent item: %v", item)
+	}
+	if yierr := p.(Module).Ping(); yierr != nil {
+		t.Fatalf("An error occurs when pinging: %s", yierr)
+	}
+}
+
+func TestUnhealthy(t *testing.T) {
+	dial := func(string) (Caller, error) {
+		return nil, errors.New("connection refused")
+	}
+	d, yierr := NewDownloader("D2|127.0.0.1:1", nil, 1, dial)
+	if yierr != nil {
+		t.Fatalf("An error occurs when creating a remote downloader: %s", yierr)
+	}
+	if score := d.ScoreCalculator()(d.Counts()); score == math.MaxUint64 {
+		t.Fatal("A new module should be healthy!")
+	}
+	httpReq, _ := http.NewRequest("GET", "http://example.com", nil)
+	if _, yierr := d.Download(data.NewRequest(httpReq)); yierr == nil {
+		t.Fatal("No error when downloading by an unreachable node!")
+	}
+	if d.(Module).Health().Healthy {
+		t.Fatal("An unreachable module should be unhealthy!")
+	}
+	if score := d.ScoreCalculator()(d.Counts()); score != math.MaxUint64 {
+		t.Fatalf("Inconsistent score of unhealthy module: expected: %d, actual: %d", uint64(math.MaxUint64), score)
+	}
+}
+
+func TestNewRemoteModule(t *testing.T) {
+	for _, mid := range []module.MID{"D1", "P1|127.0.0.1:1", "X1|127.0.0.1:1"} {
+		if _, yierr := NewDownloader(mid, nil, 1, nil); yierr == nil {
+			t.Fatalf("No error when creating a remote downloader with mid %s!", mid)
+		}
+	}
+	if _, yierr := NewPipeline("P1", nil, nil); yierr == nil {
+		t.Fatal("No error when creating a remote pipeline without address!")
+	}
+}
+
+func TestKeepPinging(t *testing.T) {
+	hosted, yierr := downloader.New("D1|127.0.0.1:1", &http.Client{}, nil, 1)
+	if yierr != nil {
+		t.Fatalf("An error occurs when creating a downloader: %s", yierr)
+	}
+	addr := startServer(t, hosted)
+	var lock sync.Mutex
+	down := true
+	dial := func(string) (Caller, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if down {
+			return nil, errors.New("connection refused")
+		}
+		return DialTCP(addr)
+	}
+	d, yierr := NewDownloader("D1|127.0.0.1:1", nil, 1, dial)
+	if yierr != nil {
+		t.Fatalf("An error occurs when creating a remote downloader: %s", yierr)
+	}
+	if yierr := d.(Module).Ping(); yierr == nil || d.(Module).Health().Healthy {
+		t.Fatal("An unreachable module should be unhealthy!")
+	}
+
+	// the module turns healthy by pinging once the node comes back
+	stop := make(chan struct{})
+	go KeepPinging([]Module{d.(Module)}, 10*time.Millisecond, stop)
+	defer close(stop)
+	lock.Lock()
+	down = false
+	lock.Unlock()
+	deadline := time.Now().Add(time.Second)
+	for !d.(Module).Health().Healthy {
+		if time.Now().After(deadline) {
+			t.Fatal("The module is still unhealthy after the node comes back!")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if extra := d.Summary().Extra.(extraSummaryStruct); extra.Remote.ID != "D1|127.0.0.1:1" {
+		t.Fatalf("Unexpected remote summary: %+v", extra.Remote)
+	}
+}
@@ -5,6 +5,15 @@ import (
 	"github.com/l-dandelion/yi-ants-go/core/crawler"
 	"strconv"
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/local/downloader"
+	"github.com/l-dandelion/yi-ants-go/core/module/local/pipeline"
+	"github.com/l-dandelion/yi-ants-go/core/processors"
+	processormodel "github.com/l-dandelion/yi-ants-go/core/processors/model"
+	"github.com/l-dandelion/yi-ants-go/lib/library/plugin"
+	"github.com/l-dandelion/yi-ants-go/core/spider"
+	"net"
+	"os"
 )
 
 type NodeInfo struct {
@@ -18,30 +27,173 @@ type Node interface {
 	crawler.Crawler
 	GetNodeInfo() *NodeInfo
 	IsMe(nodeName string) bool
+	ModuleRegistrar() module.Registrar // modules hosted for the remote modules of other nodes
+	SaveCheckpoint() *constant.YiError
 }
 
 type myNode struct {
 	crawler.Crawler
-	NodeInfo *NodeInfo
+	NodeInfo  *NodeInfo
+	registrar module.Registrar
 }
 
 /*
  * create an instance of Node
  */
 func New(settings *utils.Settings) (Node, *constant.YiError){
+	if settings.PluginDir != "" {
+		plugin.SetCacheDir(settings.PluginDir)
+	}
 	ip := utils.GetLocalIp()
 	name := ip + ":" + strconv.Itoa(settings.TcpPort)
+	spider.SetNodeName(name)
 	nodeInfo := &NodeInfo{name, ip, settings.TcpPort, settings}
 	crawler, yierr := crawler.NewCrawler()
 	if yierr != nil {
 		return nil, yierr
 	}
+	if yierr := restoreCheckpoint(crawler, settings.CheckpointFile); yierr != nil {
+		return nil, yierr
+	}
+	registrar, yierr := newHostedRegistrar(nodeInfo)
+	if yierr != nil {
+		return nil, yierr
+	}
 	return &myNode{
 		NodeInfo: nodeInfo,
 		Crawler: crawler,
+		registrar: registrar,
 	}, nil
 }
 
+/*
+ * create the registrar of hosted modules
+ * the mids contain the address of the node, such as D1|192.168.1.2:8300
+ * the hosted downloaders use the http clients of the downloaders of spiders.
+ */
+func newHostedRegistrar(nodeInfo *NodeInfo) (module.Registrar, *constant.YiError) {
+	registrar := module.NewRegistrar()
+	settings := nodeInfo.Settings
+	if settings.HostedDownloaders <= 0 && settings.HostedPipelines <= 0 {
+		return registrar, nil
+	}
+	addr, err := net.ResolveTCPAddr("tcp", nodeInfo.Name)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_NEW_ADDRESS, err)
+	}
+	if settings.HostedDownloaders > 0 {
+		client, yierr := spider.NewHTTPClient(spider.ClientArgs{
+			Proxy:   settings.HostedProxy,
+			Timeout: settings.HostedTimeout,
+		})
+		if yierr != nil {
+			return nil, yierr
+		}
+		for i := 0; i < settings.HostedDownloaders; i++ {
+			mid, yierr := module.GenMID(module.TYPE_DOWNLOADER, uint64(i+1), addr)
+			if yierr != nil {
+				return nil, yierr
+			}
+			d, yierr := downloader.New(mid, client, module.CalculateScoreSimple, constant.MaxThread)
+			if yierr != nil {
+				return nil, yierr
+			}
+			if yierr := registrar.Register(d); yierr != nil {
+				return nil, yierr
+			}
+		}
+	}
+	if settings.HostedPipelines > 0 {
+		processorTypes := settings.HostedProcessors
+		if len(processorTypes) == 0 {
+			processorTypes = []string{"console"}
+		}
+		models := []*processormodel.Model{}
+		for _, t := range processorTypes {
+			models = append(models, &processormodel.Model{Type: t})
+		}
+		itemProcessors, yierr := processors.GenProcessorsByModels(models)
+		if yierr != nil {
+			return nil, yierr
+		}
+		for i := 0; i < settings.HostedPipelines; i++ {
+			mid, yierr := module.GenMID(module.TYPE_PIPELINE, uint64(i+1), addr)
+			if yierr != nil {
+				return nil, yierr
+			}
+			p, yierr := pipeline.New(mid, itemProcessors, module.CalculateScoreSimple)
+			if yierr != nil {
+				return nil, yierr
+			}
+			if yierr := registrar.Register(p); yierr != nil {
+				return nil, yierr
+			}
+		}
+	}
+	return registrar, nil
+}
+
+/*
+ * restore the distribute queue from the checkpoint file if it exists
+ * the file is removed once restored, so the requests are not restored twice.
+ */
+func restoreCheckpoint(crawler crawler.Crawler, fileName string) *constant.YiError {
+	if fileName == "" {
+		return nil
+	}
+	file, err := os.Open(fileName)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return constant.NewYiErrore(constant.ERR_CHECKPOINT_READ, err)
+	}
+	yierr := crawler.RestoreCheckpoint(file)
+	file.Close()
+	if yierr != nil {
+		return yierr
+	}
+	if err = os.Remove(fileName); err != nil {
+		return constant.NewYiErrore(constant.ERR_CHECKPOINT_READ, err)
+	}
+	return nil
+}
+
+/*
+ * write the checkpoint of the distribute queue to the checkpoint file
+ * it is written to a temporary file first, so a broken write keeps the old checkpoint.
+ */
+func (node *myNode) SaveCheckpoint() *constant.YiError {
+	fileName := node.NodeInfo.Settings.CheckpointFile
+	if fileName == "" {
+		return constant.NewYiErrorf(constant.ERR_CHECKPOINT_WRITE, "No checkpoint file in the settings.")
+	}
+	tmpName := fileName + ".tmp"
+	file, err := os.Create(tmpName)
+	if err != nil {
+		return constant.NewYiErrore(constant.ERR_CHECKPOINT_WRITE, err)
+	}
+	yierr := node.Checkpoint(file)
+	if err = file.Close(); yierr == nil && err != nil {
+		yierr = constant.NewYiErrore(constant.ERR_CHECKPOINT_WRITE, err)
+	}
+	if yierr != nil {
+		os.Remove(tmpName)
+		return yierr
+	}
+	if err = os.Rename(tmpName, fileName); err != nil {
+		return constant.NewYiErrore(constant.ERR_CHECKPOINT_WRITE, err)
+	}
+	return nil
+}
+
+/*
+ * get the registrar of hosted modules
+ */
+func (node *myNode) ModuleRegistrar() module.Registrar {
+	return node.registrar
+}
+
 /*
  * get node info
  */
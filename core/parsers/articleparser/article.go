@@ -0,0 +1,238 @@
+package articleparser
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/templateparser"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+	"github.com/l-dandelion/yi-ants-go/lib/utils"
+)
+
+// the separators of the site name in titles, such as "Title | Site"
+var titleSeparators = []string{" | ", " - ", " – ", " — ", " :: ", " » ", " / "}
+
+// layouts of published times
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+}
+
+/*
+ * extract the article of the page according to the rule
+ * the detected fields are replaced by the fields of the selectors of the rule if they match.
+ */
+func ArticleRuleProcess(m *model.Model, rule *articleRule, resp *data.Response) (dataList []data.Data, errorList []*constant.YiError) {
+	dataList = []data.Data{}
+	errorList = []*constant.YiError{}
+
+	doc, err := resp.GetDom()
+	if err != nil {
+		errorList = append(errorList, constant.NewYiErrore(constant.ERR_CRAWL_GET_DOM, err))
+		return
+	}
+	page := resp.HTTPRequest().URL
+
+	var content *goquery.Selection
+	if rule.content != nil {
+		content = cleanContent(rule.content.Find(doc.Selection).Clone(), page)
+	} else {
+		content = extractContent(doc, page)
+	}
+	text := textOf(content)
+	if text == "" || len([]rune(text)) < rule.minLength {
+		return
+	}
+	html := ""
+	content.Each(func(_ int, sel *goquery.Selection) {
+		if s, err := goquery.OuterHtml(sel); err == nil {
+			html += s
+		}
+	})
+
+	item := data.Item{
+		ARTICLE_URL:       page.String(),
+		ARTICLE_TITLE:     override(rule.title, doc, "content", detectTitle(doc)),
+		ARTICLE_BYLINE:    override(rule.byline, doc, "content", detectByline(doc)),
+		ARTICLE_PUBLISHED: normalizeTime(override(rule.published, doc, "datetime", detectPublished(doc))),
+		ARTICLE_HTML:      html,
+		ARTICLE_TEXT:      text,
+		ARTICLE_IMAGE:     absolute(page, override(rule.image, doc, "src", detectImage(doc, content))),
+	}
+	dataList = append(dataList, item)
+	return
+}
+
+/*
+ * get the value of the first node of the selector, or the detected value if nothing matches
+ * the value is the attribute (or the content attribute of meta) of the node, or its text.
+ */
+func override(sel templateparser.Selector, doc *goquery.Document, attr string, detected string) string {
+	if sel == nil {
+		return detected
+	}
+	node := sel.Find(doc.Selection).First()
+	if node.Length() == 0 {
+		return detected
+	}
+	for _, name := range []string{attr, "content"} {
+		if v, ok := templateparser.Attr(node, name); ok && strings.TrimSpace(v) != "" {
+			return collapse(v)
+		}
+	}
+	if v := collapse(node.Text()); v != "" {
+		return v
+	}
+	return detected
+}
+
+/*
+ * get the content attribute of the first meta tag of the names or properties
+ */
+func metaContent(doc *goquery.Document, names ...string) string {
+	for _, name := range names {
+		for _, attr := range []string{"property", "name", "itemprop"} {
+			node := doc.Find("meta[" + attr + "=\"" + name + "\"]").First()
+			if v := collapse(node.AttrOr("content", "")); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+/*
+ * detect the title by the meta tags, the only h1 and the title without the site name
+ */
+func detectTitle(doc *goquery.Document) string {
+	if v := metaContent(doc, "og:title", "twitter:title"); v != "" {
+		return v
+	}
+	title := collapse(doc.Find("title").First().Text())
+	if h1 := doc.Find("h1"); h1.Length() == 1 {
+		if v := collapse(h1.Text()); v != "" && (title == "" || strings.Contains(title, v)) {
+			return v
+		}
+	}
+	for _, sep := range titleSeparators {
+		if i := strings.LastIndex(title, sep); i > 0 {
+			if head := strings.TrimSpace(title[:i]); len(strings.Fields(head)) >= 3 {
+				return head
+			}
+		}
+	}
+	return title
+}
+
+/*
+ * detect the byline by the meta tags and the author nodes
+ */
+func detectByline(doc *goquery.Document) string {
+	if v := metaContent(doc, "author", "article:author", "dc.creator", "byl"); v != "" && !isURL(v) {
+		return trimBy(v)
+	}
+	node := doc.Find("[rel=author], [itemprop=author], .byline, .author").First()
+	if v, ok := node.Attr("content"); ok && v != "" && !isURL(v) {
+		return trimBy(collapse(v))
+	}
+	if v := trimBy(collapse(node.Text())); v != "" && len(v) < 100 {
+		return v
+	}
+	return ""
+}
+
+/*
+ * detect the published time by the meta tags and the time nodes
+ */
+func detectPublished(doc *goquery.Document) string {
+	if v := metaContent(doc, "article:published_time", "datePublished", "pubdate", "publishdate",
+		"dc.date.issued", "dc.date", "date"); v != "" {
+		return v
+	}
+	node := doc.Find("[itemprop=datePublished], time[pubdate], time[datetime]").First()
+	for _, attr := range []string{"datetime", "content"} {
+		if v := collapse(node.AttrOr(attr, "")); v != "" {
+			return v
+		}
+	}
+	return collapse(node.Text())
+}
+
+/*
+ * detect the lead image by the meta tags, or the first image of the content
+ */
+func detectImage(doc *goquery.Document, content *goquery.Selection) string {
+	if v := metaContent(doc, "og:image", "twitter:image", "twitter:image:src"); v != "" {
+		return v
+	}
+	src := ""
+	content.Find("img[src]").EachWithBreak(func(_ int, img *goquery.Selection) bool {
+		if v := strings.TrimSpace(img.AttrOr("src", "")); v != "" && !strings.HasPrefix(v, "data:") {
+			src = v
+			return false
+		}
+		return true
+	})
+	return src
+}
+
+/*
+ * format the time in RFC3339, the string itself if it can't be parsed
+ */
+func normalizeTime(s string) string {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(time.RFC3339)
+		}
+	}
+	return s
+}
+
+/*
+ * get the complete url relative to the page, the url itself if it is illegal
+ */
+func absolute(page *url.URL, u string) string {
+	if u == "" {
+		return ""
+	}
+	if complete, err := utils.GetComplateUrl(page, u); err == nil {
+		return complete
+	}
+	return u
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+/*
+ * remove the "By" before the names of a byline
+ */
+func trimBy(s string) string {
+	if len(s) > 3 && strings.EqualFold(s[:3], "by ") {
+		return strings.TrimSpace(s[3:])
+	}
+	return s
+}
+
+/*
+ * collapse the whitespaces into single spaces
+ */
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
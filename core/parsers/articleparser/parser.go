@@ -0,0 +1,109 @@
+package articleparser
+
+import (
+	"strconv"
+
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/templateparser"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * fields of the items of article models
+ * url: url of the page
+ * title: title of the article, without the site name
+ * byline: author of the article
+ * published: published time in RFC3339, or as written on the page if it can't be parsed
+ * html: cleaned html of the main content
+ * text: plain text of the main content, a line per paragraph
+ * image: url of the lead image
+ */
+const (
+	ARTICLE_URL       = "url"
+	ARTICLE_TITLE     = "title"
+	ARTICLE_BYLINE    = "byline"
+	ARTICLE_PUBLISHED = "published"
+	ARTICLE_HTML      = "html"
+	ARTICLE_TEXT      = "text"
+	ARTICLE_IMAGE     = "image"
+)
+
+/*
+ * keys of the rule of article models, all are optional
+ * title, byline, published, image: selectors overriding the detection of the field,
+ *   the value is the content (or datetime, src) attribute of the first matched node, or its text
+ * content: selector of the main content overriding the detection, all matched nodes are kept
+ * min_length: pages whose text is shorter are not articles and emit no item, 0 by default
+ * the selectors are css selectors or xpath selectors with templateparser.XPATH_PREFIX.
+ */
+const (
+	RULE_TITLE      = ARTICLE_TITLE
+	RULE_BYLINE     = ARTICLE_BYLINE
+	RULE_PUBLISHED  = ARTICLE_PUBLISHED
+	RULE_IMAGE      = ARTICLE_IMAGE
+	RULE_CONTENT    = "content"
+	RULE_MIN_LENGTH = "min_length"
+)
+
+/*
+ * compiled rule of an article model
+ */
+type articleRule struct {
+	title     templateparser.Selector
+	byline    templateparser.Selector
+	published templateparser.Selector
+	image     templateparser.Selector
+	content   templateparser.Selector
+	minLength int
+}
+
+/*
+ * compile the rule
+ */
+func compileRule(m *model.Model) (*articleRule, *constant.YiError) {
+	rule := &articleRule{}
+	selectors := []struct {
+		key string
+		sel *templateparser.Selector
+	}{
+		{RULE_TITLE, &rule.title},
+		{RULE_BYLINE, &rule.byline},
+		{RULE_PUBLISHED, &rule.published},
+		{RULE_IMAGE, &rule.image},
+		{RULE_CONTENT, &rule.content},
+	}
+	for _, s := range selectors {
+		expr, ok := m.Rule[s.key]
+		if !ok || expr == "" {
+			continue
+		}
+		sel, err := templateparser.CompileSelector(expr)
+		if err != nil {
+			return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "%s: %s", s.key, err)
+		}
+		*s.sel = sel
+	}
+	if v, ok := m.Rule[RULE_MIN_LENGTH]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "%s: illegal length %q", RULE_MIN_LENGTH, v)
+		}
+		rule.minLength = n
+	}
+	return rule, nil
+}
+
+/*
+ * generate a parser extracting the main content of articles, such as news and blog posts
+ */
+func GenArticleParser(model *model.Model) (module.ParseResponse, *constant.YiError) {
+	rule, yierr := compileRule(model)
+	if yierr != nil {
+		return nil, yierr
+	}
+	return func(resp *data.Response) ([]data.Data, []*constant.YiError) {
+		return ArticleRuleProcess(model, rule, resp)
+	}, nil
+}
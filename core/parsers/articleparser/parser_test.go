@@ -0,0 +1,137 @@
+package articleparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/parsertest"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+const articlePage = `<html><head>
+<title>Library opens a new reading room | City News</title>
+<meta name="author" content="By Ann Lee">
+<meta property="article:published_time" content="2018-03-04T05:06:07Z">
+</head><body>
+<div class="nav"><a href="/">Home</a> <a href="/sports">Sports</a></div>
+<div class="article-body">
+  <h1>Library opens a new reading room</h1>
+  <p class="lead">The city library opened a new reading room on Monday, with two hundred seats and a quiet area for students.</p>
+  <img src="/img/room.jpg" alt="room">
+  <p>The room is open every day from nine in the morning to ten in the evening, and it is free for all residents.</p>
+  <p class="note">Written by <span class="who">Tom Hill</span> <time datetime="2018-03-05">March 5</time></p>
+</div>
+<div class="footer">Copyright City News</div>
+</body></html>`
+
+func TestCompileRule(t *testing.T) {
+	compile := func(m *model.Model) *constant.YiError {
+		_, yierr := GenArticleParser(m)
+		return yierr
+	}
+	parsertest.CheckCompile(t, compile, constant.ERR_RULE_SCHEMA, []parsertest.CompileTest{
+		{Name: "no rule", Model: &model.Model{}},
+		{Name: "selectors", Model: &model.Model{Rule: map[string]string{"title": "h1", "byline": "xpath://span[@class='who']", "content": "div.article-body p", "min_length": "10"}}},
+		{Name: "empty selector", Model: &model.Model{Rule: map[string]string{"title": ""}}},
+		{Name: "illegal css", Model: &model.Model{Rule: map[string]string{"content": "div["}}, ErrMsg: "content: "},
+		{Name: "illegal xpath", Model: &model.Model{Rule: map[string]string{"title": "xpath://h1["}}, ErrMsg: "title: "},
+		{Name: "illegal image", Model: &model.Model{Rule: map[string]string{"image": "img:unknown"}}, ErrMsg: "image: "},
+		{Name: "illegal length", Model: &model.Model{Rule: map[string]string{"min_length": "x"}}, ErrMsg: `min_length: illegal length "x"`},
+		{Name: "negative length", Model: &model.Model{Rule: map[string]string{"min_length": "-1"}}, ErrMsg: `min_length: illegal length "-1"`},
+	})
+}
+
+func TestArticleRuleProcess(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  map[string]string
+		body  string
+		items []data.Item
+	}{
+		{
+			name: "detected",
+			body: articlePage,
+			items: []data.Item{{
+				ARTICLE_TITLE:     "Library opens a new reading room",
+				ARTICLE_BYLINE:    "Ann Lee",
+				ARTICLE_PUBLISHED: "2018-03-04T05:06:07Z",
+				ARTICLE_IMAGE:     "http://news.example.com/img/room.jpg",
+				ARTICLE_TEXT: "Library opens a new reading room\n" +
+					"The city library opened a new reading room on Monday, with two hundred seats and a quiet area for students.\n" +
+					"The room is open every day from nine in the morning to ten in the evening, and it is free for all residents.\n" +
+					"Written by Tom Hill March 5",
+			}},
+		},
+		{
+			name: "selectors",
+			rule: map[string]string{
+				"title":     "xpath://title",
+				"byline":    ".who",
+				"published": "xpath://p[@class='note']/time",
+				"image":     "img.missing",
+				"content":   "p.lead",
+			},
+			body: articlePage,
+			items: []data.Item{{
+				ARTICLE_TITLE:     "Library opens a new reading room | City News",
+				ARTICLE_BYLINE:    "Tom Hill",
+				ARTICLE_PUBLISHED: "2018-03-05T00:00:00Z",
+				ARTICLE_IMAGE:     "",
+				ARTICLE_TEXT:      "The city library opened a new reading room on Monday, with two hundred seats and a quiet area for students.",
+			}},
+		},
+		{
+			name:  "shorter than min_length",
+			rule:  map[string]string{"min_length": "1000"},
+			body:  articlePage,
+			items: []data.Item{},
+		},
+		{
+			name:  "content not found",
+			rule:  map[string]string{"content": "div.missing"},
+			body:  articlePage,
+			items: []data.Item{},
+		},
+		{
+			name:  "empty document",
+			body:  "",
+			items: []data.Item{},
+		},
+		{
+			name:  "empty document with selectors",
+			rule:  map[string]string{"title": "h1", "content": "xpath://article"},
+			body:  "",
+			items: []data.Item{},
+		},
+	}
+	for _, test := range tests {
+		parser, yierr := GenArticleParser(&model.Model{Rule: test.rule})
+		if yierr != nil {
+			t.Fatalf("%s: GenArticleParser fail: %s", test.name, yierr)
+		}
+		dataList, yierrs := parser(parsertest.NewResponse(t, "http://news.example.com/2018/03/library", parsertest.CONTENT_TYPE_HTML, []byte(test.body)))
+		if len(yierrs) != 0 {
+			t.Errorf("%s: errors: %v", test.name, yierrs)
+		}
+		items := []data.Item{}
+		for _, d := range dataList {
+			item := d.(data.Item)
+			if item[ARTICLE_URL] != "http://news.example.com/2018/03/library" {
+				t.Errorf("%s: wrong url: %v", test.name, item[ARTICLE_URL])
+			}
+			if html, _ := item[ARTICLE_HTML].(string); strings.Contains(html, "class=") || strings.Contains(html, "Copyright") {
+				t.Errorf("%s: wrong html: %s", test.name, html)
+			}
+			// the html is checked above
+			delete(item, ARTICLE_URL)
+			delete(item, ARTICLE_HTML)
+			items = append(items, item)
+		}
+		if !reflect.DeepEqual(items, test.items) {
+			t.Errorf("%s: wrong items: %#v", test.name, items)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package articleparser
+
+import (
+	"bytes"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/l-dandelion/yi-ants-go/lib/utils"
+	"golang.org/x/net/html"
+)
+
+/*
+ * patterns of the class and id of the nodes
+ * unlikely: boilerplate removed before scoring, unless it is maybe content
+ * positive and negative: weight of the scores of the candidates
+ */
+var (
+	unlikelyPattern = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|header|menu|modal|nav|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|promo|subscribe|advert`)
+	maybePattern    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positivePattern = regexp.MustCompile(`(?i)article|body|content|entry|hentry|main|page|post|text|blog|story`)
+	negativePattern = regexp.MustCompile(`(?i)hidden|banner|combx|comment|contact|foot|footnote|masthead|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|advert`)
+)
+
+// the tags removed from the content
+const removedTags = "script, style, noscript, iframe, object, embed, form, nav, footer, aside, link, meta, svg, button, input, select, textarea"
+
+// the tags of blocks, a div without them is scored as a paragraph
+const blockTags = "p, div, pre, table, ul, ol, dl, blockquote, section, article, h1, h2, h3, h4, h5, h6"
+
+// the tags breaking the lines of the text
+var lineTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "header": true, "main": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"pre": true, "blockquote": true, "table": true, "tr": true, "figure": true, "figcaption": true,
+}
+
+// the attributes kept in the cleaned html
+var keptAttrs = map[string]bool{"href": true, "src": true, "alt": true, "title": true, "datetime": true}
+
+/*
+ * extract the main content of the page by the readability scores of the nodes
+ * the paragraphs add their scores to their parents and half to their grandparents,
+ * the candidate of the best score and its siblings alike are the content.
+ * the dom of the response is not modified, the content is a cleaned copy.
+ */
+func extractContent(doc *goquery.Document, page *url.URL) *goquery.Selection {
+	body := doc.Find("body").First()
+	if body.Length() == 0 {
+		body = doc.Selection
+	}
+	body = body.Clone()
+	body.Find(removedTags).Remove()
+	body.Find("*").Each(func(_ int, sel *goquery.Selection) {
+		switch goquery.NodeName(sel) {
+		case "html", "body", "article", "main":
+			return
+		}
+		match := classAndID(sel)
+		if match != "" && unlikelyPattern.MatchString(match) && !maybePattern.MatchString(match) {
+			sel.Remove()
+		}
+	})
+
+	scores := map[*html.Node]float64{}
+	candidates := []*html.Node{}
+	addScore := func(node *html.Node, score float64) {
+		if node == nil || node.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[node]; !ok {
+			scores[node] = initialScore(node)
+			candidates = append(candidates, node)
+		}
+		scores[node] += score
+	}
+	body.Find("p, pre, td, blockquote, div").Each(func(_ int, sel *goquery.Selection) {
+		if goquery.NodeName(sel) == "div" && sel.Find(blockTags).Length() > 0 {
+			return
+		}
+		text := collapse(sel.Text())
+		n := len([]rune(text))
+		if n < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(n/100), 3)
+		node := sel.Nodes[0]
+		addScore(node.Parent, score)
+		if node.Parent != nil {
+			addScore(node.Parent.Parent, score/2)
+		}
+	})
+
+	// the scores are scaled by the text out of the links
+	var top *html.Node
+	for _, node := range candidates {
+		scores[node] *= 1 - linkDensity(goquery.NewDocumentFromNode(node).Selection)
+		if top == nil || scores[node] > scores[top] {
+			top = node
+		}
+	}
+	if top == nil || top == body.Nodes[0] {
+		return cleanContent(body, page)
+	}
+
+	nodes := []*html.Node{}
+	threshold := math.Max(10, scores[top]*0.2)
+	for child := top.Parent.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if child == top {
+			nodes = append(nodes, child)
+			continue
+		}
+		if score, ok := scores[child]; ok && score >= threshold {
+			nodes = append(nodes, child)
+			continue
+		}
+		if child.Data == "p" {
+			sel := goquery.NewDocumentFromNode(child).Selection
+			text := collapse(sel.Text())
+			n, density := len([]rune(text)), linkDensity(sel)
+			if n > 80 && density < 0.25 || n > 0 && n <= 80 && density == 0 && strings.HasSuffix(text, ".") {
+				nodes = append(nodes, child)
+			}
+		}
+	}
+	return cleanContent(body.FindNodes(nodes...), page)
+}
+
+/*
+ * clean the content
+ * the boilerplate nodes, the nodes of many links or of little text are removed,
+ * and only the attributes of keptAttrs are kept, whose urls are completed.
+ */
+func cleanContent(content *goquery.Selection, page *url.URL) *goquery.Selection {
+	content.Find(removedTags).Remove()
+	content = content.Not(removedTags)
+	// the deepest nodes first, so a parent is checked without its removed children
+	nodes := content.Find("div, section, ul, ol, table, h1, h2, h3, h4, h5, h6")
+	for i := nodes.Length() - 1; i >= 0; i-- {
+		if sel := nodes.Eq(i); isBoilerplate(sel) {
+			sel.Remove()
+		}
+	}
+	content.Find("p").Each(func(_ int, sel *goquery.Selection) {
+		if strings.TrimSpace(sel.Text()) == "" && sel.Find("img, video, picture").Length() == 0 {
+			sel.Remove()
+		}
+	})
+	content.Find("*").AddSelection(content).Each(func(_ int, sel *goquery.Selection) {
+		node := sel.Nodes[0]
+		attrs := []html.Attribute{}
+		for _, attr := range node.Attr {
+			if !keptAttrs[attr.Key] {
+				continue
+			}
+			if (attr.Key == "href" || attr.Key == "src") && !strings.HasPrefix(attr.Val, "#") {
+				if u, err := utils.GetComplateUrl(page, strings.TrimSpace(attr.Val)); err == nil {
+					attr.Val = u
+				}
+			}
+			attrs = append(attrs, attr)
+		}
+		node.Attr = attrs
+	})
+	return content
+}
+
+/*
+ * check whether the node in the content is boilerplate
+ */
+func isBoilerplate(sel *goquery.Selection) bool {
+	if classWeight(sel) < 0 {
+		return true
+	}
+	density := linkDensity(sel)
+	switch goquery.NodeName(sel) {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		return density > 0.33
+	}
+	if density > 0.5 {
+		return true
+	}
+	return len([]rune(collapse(sel.Text()))) < 25 && sel.Find("img, video, picture").Length() == 0
+}
+
+/*
+ * get the plain text of the content, a line per paragraph
+ */
+func textOf(content *goquery.Selection) string {
+	lines := []string{}
+	var line bytes.Buffer
+	flush := func() {
+		if s := collapse(line.String()); s != "" {
+			lines = append(lines, s)
+		}
+		line.Reset()
+	}
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		switch node.Type {
+		case html.TextNode:
+			line.WriteString(node.Data)
+		case html.ElementNode:
+			switch {
+			case node.Data == "br":
+				flush()
+				return
+			case node.Data == "td" || node.Data == "th":
+				line.WriteString(" ")
+			}
+			if lineTags[node.Data] {
+				flush()
+			}
+			for child := node.FirstChild; child != nil; child = child.NextSibling {
+				walk(child)
+			}
+			if lineTags[node.Data] {
+				flush()
+			}
+		}
+	}
+	for _, node := range content.Nodes {
+		walk(node)
+		flush()
+	}
+	return strings.Join(lines, "\n")
+}
+
+/*
+ * initial score of a candidate by its tag and its class and id
+ */
+func initialScore(node *html.Node) float64 {
+	score := classWeight(goquery.NewDocumentFromNode(node).Selection)
+	switch node.Data {
+	case "div", "article":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	return score
+}
+
+/*
+ * weight of the class and id of the node
+ */
+func classWeight(sel *goquery.Selection) float64 {
+	weight := 0.0
+	for _, attr := range []string{"class", "id"} {
+		v := sel.AttrOr(attr, "")
+		if v == "" {
+			continue
+		}
+		if negativePattern.MatchString(v) {
+			weight -= 25
+		}
+		if positivePattern.MatchString(v) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+/*
+ * get the ratio of the text of links to all the text of the node
+ */
+func linkDensity(sel *goquery.Selection) float64 {
+	n := len([]rune(collapse(sel.Text())))
+	if n == 0 {
+		return 0
+	}
+	links := 0
+	sel.Find("a").Each(func(_ int, a *goquery.Selection) {
+		links += len([]rune(collapse(a.Text())))
+	})
+	return float64(links) / float64(n)
+}
+
+func classAndID(sel *goquery.Selection) string {
+	return strings.TrimSpace(sel.AttrOr("class", "") + " " + sel.AttrOr("id", ""))
+}
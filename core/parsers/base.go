@@ -1,34 +1,218 @@
 package parsers
 
 import (
+
 	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/articleparser"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/feedparser"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/filter"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/form"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/jsonparser"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/metaparser"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/regexparser"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/scriptparser"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/sourceparser"
 	"github.com/l-dandelion/yi-ants-go/core/parsers/templateparser"
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
-	"github.com/l-dandelion/yi-ants-go/core/parsers/sourceparser"
-	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
 )
 
-
-
-func GenParsersByModel(model *model.Model) ([]module.ParseResponse, *constant.YiError){
+/*
+ * generate the parsers of the model
+ * model.AcceptedRegUrls is not checked, it is checked by the route of the model.
+ */
+func GenParsersByModel(model *model.Model) ([]module.ParseResponse, *constant.YiError) {
 	switch model.Type {
 	case "template":
-		return []module.ParseResponse{templateparser.GenTemplateParser(model)}, nil
+		parser, yierr := templateparser.GenTemplateParser(model)
+		if yierr != nil {
+			return nil, yierr
+		}
+		return []module.ParseResponse{parser}, nil
 	case "source":
 		return sourceparser.GetSourceParsersFromModel(model)
+	case "json":
+		parser, yierr := jsonparser.GenJSONParser(model)
+		if yierr != nil {
+			return nil, yierr
+		}
+		return []module.ParseResponse{parser}, nil
+	case "regex":
+		parser, yierr := regexparser.GenRegexParser(model)
+		if yierr != nil {
+			return nil, yierr
+		}
+		return []module.ParseResponse{parser}, nil
+	case "script":
+		parser, yierr := scriptparser.GenScriptParser(model)
+		if yierr != nil {
+			return nil, yierr
+		}
+		return []module.ParseResponse{parser}, nil
+	case "sitemap":
+		parser, yierr := feedparser.GenSitemapParser(model)
+		if yierr != nil {
+			return nil, yierr
+		}
+		return []module.ParseResponse{parser}, nil
+	case "feed":
+		parser, yierr := feedparser.GenFeedParser(model)
+		if yierr != nil {
+			return nil, yierr
+		}
+		return []module.ParseResponse{parser}, nil
+	case "article":
+		parser, yierr := articleparser.GenArticleParser(model)
+		if yierr != nil {
+			return nil, yierr
+		}
+		return []module.ParseResponse{parser}, nil
+	case "metadata":
+		parser, yierr := metaparser.GenMetadataParser(model)
+		if yierr != nil {
+			return nil, yierr
+		}
+		return []module.ParseResponse{parser}, nil
 	default:
 		return nil, constant.NewYiErrorf(constant.ERR_UNSUPPORTED_MODEL_TYPE, "Unsupported model type.(modelType: %s)", model.Type)
 	}
 }
 
-func GenParsersByModels(models []*model.Model) ([]module.ParseResponse, *constant.YiError){
-	parsers := []module.ParseResponse{}
-	for _, model := range models {
-		ps, yierr := GenParsersByModel(model)
+/*
+ * generate the parser routes of the models, one route per model
+ * a request carrying the name of a model is only handled by the model,
+ * other requests are handled by the models whose AcceptedRegUrls match the url.
+ */
+func GenRoutesByModels(models []*model.Model) ([]*module.ParserRoute, *constant.YiError) {
+	if yierr := checkModelNames(models); yierr != nil {
+		return nil, yierr
+	}
+	routes := []*module.ParserRoute{}
+	for i, model := range models {
+		parsers, yierr := GenParsersByModel(model)
+		if yierr != nil {
+			// the errors of rules are reported with the path of the model, such as models[0].fields[1].selector
+			if yierr.ErrNo == constant.ERR_RULE_SCHEMA {
+				return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "models[%d].%s", i, yierr.ErrDesc)
+			}
+			return nil, yierr
+		}
+		formParser, yierr := form.GenFormParser(model)
 		if yierr != nil {
 			return nil, yierr
 		}
-		parsers = append(parsers, ps...)
+		if formParser != nil {
+			parsers = append(parsers, formParser)
+		}
+		accept, yierr := genAccept(model, i)
+		if yierr != nil {
+			return nil, yierr
+		}
+		routes = append(routes, &module.ParserRoute{
+			Name:    model.Name,
+			Accept:  accept,
+			Parsers: parsers,
+		})
+	}
+	return routes, nil
+}
+
+/*
+ * generate the parsers of the models as a list
+ * every parser checks the routing by itself.
+ */
+func GenParsersByModels(models []*model.Model) ([]module.ParseResponse, *constant.YiError) {
+	routes, yierr := GenRoutesByModels(models)
+	if yierr != nil {
+		return nil, yierr
+	}
+	parsers := []module.ParseResponse{}
+	for _, route := range routes {
+		for _, parser := range route.Parsers {
+			parsers = append(parsers, routeParser(route, parser))
+		}
 	}
 	return parsers, nil
-}
\ No newline at end of file
+}
+
+/*
+ * wrap the parser to skip the responses not routed to it
+ */
+func routeParser(route *module.ParserRoute, parser module.ParseResponse) module.ParseResponse {
+	return func(resp *data.Response) ([]data.Data, []*constant.YiError) {
+		name := ""
+		if req := resp.Request(); req != nil {
+			name = req.Parser()
+		}
+		if name != "" && name != route.Name {
+			return nil, nil
+		}
+		if name == "" && route.Accept != nil && !route.Accept(resp) {
+			return nil, nil
+		}
+		return parser(resp)
+	}
+}
+
+/*
+ * generate the url check of model.AcceptedRegUrls, nil if any url is accepted
+ * the patterns are regexes, or globs with filter.PREFIX_GLOB.
+ */
+func genAccept(model *model.Model, index int) (func(resp *data.Response) bool, *constant.YiError) {
+	if len(model.AcceptedRegUrls) == 0 {
+		return nil, nil
+	}
+	patterns, err := filter.CompilePatterns(model.AcceptedRegUrls)
+	if err != nil {
+		return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "models[%d].AcceptedRegUrls%s", index, err)
+	}
+	urlFilter := filter.New(patterns, nil)
+	return func(resp *data.Response) bool {
+		return urlFilter.Allowed(resp.HTTPRequest().URL.String())
+	}, nil
+}
+
+/*
+ * check that the names of the models are unique
+ * and the parser names of link rules, AddQueue, forms and seeds refer to the models.
+ */
+func checkModelNames(models []*model.Model) *constant.YiError {
+	names := map[string]bool{}
+	for i, m := range models {
+		if m.Name == "" {
+			if len(m.Seeds) > 0 || len(m.SeedForms) > 0 {
+				return constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "models[%d].name: required by seeds", i)
+			}
+			continue
+		}
+		if names[m.Name] {
+			return constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "models[%d].name: duplicate name %q", i, m.Name)
+		}
+		names[m.Name] = true
+	}
+	unknown := func(name string) bool {
+		return name != "" && !names[name]
+	}
+	for i, m := range models {
+		for j, link := range m.Links {
+			if link != nil && unknown(link.Parser) {
+				return constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "models[%d].links[%d].parser: unknown model %q", i, j, link.Parser)
+			}
+		}
+		if unknown(m.AddQueueParser) {
+			return constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "models[%d].AddQueueParser: unknown model %q", i, m.AddQueueParser)
+		}
+		for j, spec := range m.SeedForms {
+			if spec != nil && unknown(spec.Parser) {
+				return constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "models[%d].SeedForms[%d].parser: unknown model %q", i, j, spec.Parser)
+			}
+		}
+		for j, spec := range m.Forms {
+			if spec != nil && unknown(spec.Parser) {
+				return constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "models[%d].Forms[%d].parser: unknown model %q", i, j, spec.Parser)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+func TestRuleErrorPath(t *testing.T) {
+	tests := []struct {
+		models []*model.Model
+		errMsg string
+	}{
+		{
+			[]*model.Model{{Type: "template", Rule: map[string]string{"title": "txt|h1"}}},
+			`models[0].Rule["title"]: unknown extractor "txt"`,
+		},
+		{
+			[]*model.Model{
+				{Type: "template", Rule: map[string]string{"title": "text|h1"}},
+				{Type: "template", Rule: map[string]string{"href": "atr.href|a"}},
+			},
+			`models[1].Rule["href"]: unknown extractor "atr"`,
+		},
+		{
+			[]*model.Model{{Type: "template", Schema: &model.Schema{Fields: []*model.FieldSchema{{Name: "title", Selector: "h1["}}}}},
+			"models[0].fields[0].selector: ",
+		},
+	}
+	for _, test := range tests {
+		_, yierr := GenRoutesByModels(test.models)
+		if yierr == nil || yierr.ErrNo != constant.ERR_RULE_SCHEMA || !strings.Contains(yierr.Error(), test.errMsg) {
+			t.Errorf("%s: wrong error: %v", test.errMsg, yierr)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package feedparser
+
+import (
+	"strings"
+	"time"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * rss 2.0, rss 1.0 (rdf) or atom document
+ * rss items are under the channel, rdf items and atom entries are under the root.
+ */
+type feedDoc struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+/*
+ * item of rss
+ */
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Description string   `xml:"description"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories  []string `xml:"category"`
+}
+
+/*
+ * entry of atom
+ */
+type atomEntry struct {
+	Title     string `xml:"title"`
+	ID        string `xml:"id"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Links     []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Authors []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Categories []struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+}
+
+/*
+ * entry of any feed
+ */
+type feedEntry struct {
+	title      string
+	link       string
+	id         string
+	published  time.Time
+	updated    time.Time
+	summary    string
+	author     string
+	categories []string
+}
+
+/*
+ * parse the rss or atom feed according to the rule
+ * an entry is kept if its updated (or published) time is fresh.
+ */
+func FeedRuleProcess(m *model.Model, rule *feedRule, resp *data.Response) (dataList []data.Data, errorList []*constant.YiError) {
+	dataList = []data.Data{}
+	errorList = []*constant.YiError{}
+
+	decoder, yierr := rule.newDecoder(resp)
+	if yierr != nil {
+		errorList = append(errorList, yierr)
+		return
+	}
+	doc := &feedDoc{}
+	if err := decoder.Decode(doc); err != nil {
+		errorList = append(errorList, constant.NewYiErrore(constant.ERR_PARSE_XML, err))
+		return
+	}
+
+	for _, entry := range doc.entries() {
+		t := entry.updated
+		if t.IsZero() {
+			t = entry.published
+		}
+		if !rule.fresh(t) {
+			continue
+		}
+		dataList, errorList = rule.emit(m, resp, "link", entry.item(), dataList, errorList)
+	}
+	return
+}
+
+/*
+ * get the entries of the document
+ */
+func (doc *feedDoc) entries() []*feedEntry {
+	entries := []*feedEntry{}
+	items := append(doc.Channel.Items, doc.Items...)
+	for _, item := range items {
+		entry := &feedEntry{
+			title:      strings.TrimSpace(item.Title),
+			link:       strings.TrimSpace(item.Link),
+			id:         strings.TrimSpace(item.GUID),
+			summary:    strings.TrimSpace(item.Description),
+			author:     strings.TrimSpace(item.Author),
+			categories: trimAll(item.Categories),
+		}
+		if entry.author == "" {
+			entry.author = strings.TrimSpace(item.Creator)
+		}
+		pubDate := item.PubDate
+		if strings.TrimSpace(pubDate) == "" {
+			pubDate = item.Date
+		}
+		entry.published, _ = parseTime(pubDate)
+		// a permalink guid is the link of the item without link
+		if entry.link == "" && strings.HasPrefix(entry.id, "http") {
+			entry.link = entry.id
+		}
+		entries = append(entries, entry)
+	}
+	for _, e := range doc.Entries {
+		entry := &feedEntry{
+			title:   strings.TrimSpace(e.Title),
+			id:      strings.TrimSpace(e.ID),
+			summary: strings.TrimSpace(e.Summary),
+		}
+		if entry.summary == "" {
+			entry.summary = strings.TrimSpace(e.Content)
+		}
+		for _, link := range e.Links {
+			if link.Rel == "" || link.Rel == "alternate" {
+				entry.link = strings.TrimSpace(link.Href)
+				break
+			}
+		}
+		names := []string{}
+		for _, author := range e.Authors {
+			if name := strings.TrimSpace(author.Name); name != "" {
+				names = append(names, name)
+			}
+		}
+		entry.author = strings.Join(names, ", ")
+		for _, category := range e.Categories {
+			if term := strings.TrimSpace(category.Term); term != "" {
+				entry.categories = append(entry.categories, term)
+			}
+		}
+		entry.published, _ = parseTime(e.Published)
+		entry.updated, _ = parseTime(e.Updated)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+/*
+ * get the item of the entry
+ */
+func (entry *feedEntry) item() data.Item {
+	// []interface{} is registered to gob
+	categories := []interface{}{}
+	for _, category := range entry.categories {
+		categories = append(categories, category)
+	}
+	return data.Item{
+		"title":      entry.title,
+		"link":       entry.link,
+		"id":         entry.id,
+		"published":  formatTime(entry.published),
+		"updated":    formatTime(entry.updated),
+		"summary":    entry.summary,
+		"author":     entry.author,
+		"categories": categories,
+	}
+}
+
+/*
+ * trim the strings and drop the empty ones
+ */
+func trimAll(strs []string) []string {
+	result := []string{}
+	for _, s := range strs {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+package feedparser
+
+import (
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * generate a parser for sitemaps and sitemap indexes
+ * the sitemaps of an index are tagged with the model name, so the model handles them itself,
+ * and the name is required.
+ */
+func GenSitemapParser(model *model.Model) (module.ParseResponse, *constant.YiError) {
+	if model.Name == "" {
+		return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "name: required by the sitemaps of sitemap indexes")
+	}
+	rule, yierr := compileRule(model)
+	if yierr != nil {
+		return nil, yierr
+	}
+	return func(resp *data.Response) ([]data.Data, []*constant.YiError) {
+		return SitemapRuleProcess(model, rule, resp)
+	}, nil
+}
+
+/*
+ * generate a parser for rss and atom feeds
+ */
+func GenFeedParser(model *model.Model) (module.ParseResponse, *constant.YiError) {
+	rule, yierr := compileRule(model)
+	if yierr != nil {
+		return nil, yierr
+	}
+	return func(resp *data.Response) ([]data.Data, []*constant.YiError) {
+		return FeedRuleProcess(model, rule, resp)
+	}, nil
+}
@@ -0,0 +1,300 @@
+package feedparser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/parsertest"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+const rssFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel>
+  <title>News</title>
+  <item>
+    <title> First </title>
+    <link>/news/1</link>
+    <guid>news-1</guid>
+    <pubDate>Sun, 04 Mar 2018 10:00:00 +0000</pubDate>
+    <description>The first news</description>
+    <dc:creator>Ann</dc:creator>
+    <category>world</category>
+    <category> </category>
+  </item>
+  <item>
+    <title>Second</title>
+    <guid>http://news.example.com/news/2</guid>
+    <dc:date>2017-12-01T08:00:00Z</dc:date>
+  </item>
+  <item>
+    <title>No link</title>
+  </item>
+</channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Blog</title>
+  <entry>
+    <title>Post</title>
+    <id>tag:blog,2018:1</id>
+    <link rel="edit" href="/edit/1"/>
+    <link rel="alternate" href="http://blog.example.com/post/1"/>
+    <published>2018-03-01T00:00:00Z</published>
+    <updated>2018-03-05T00:00:00Z</updated>
+    <content>Full text</content>
+    <author><name>Ann</name></author>
+    <author><name>Bob</name></author>
+    <category term="go"/>
+  </entry>
+  <entry>
+    <title>Old post</title>
+    <link href="/post/0"/>
+    <updated>2017-01-01T00:00:00Z</updated>
+  </entry>
+</feed>`
+
+const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc> http://shop.example.com/item/1 </loc><lastmod>2018-03-04</lastmod><changefreq>daily</changefreq><priority>0.8</priority></url>
+  <url><loc>http://shop.example.com/item/2</loc><lastmod>2017-01-01</lastmod></url>
+  <url><loc>http://shop.example.com/about</loc></url>
+  <url><loc></loc></url>
+</urlset>`
+
+const sitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>/sitemap-1.xml.gz</loc><lastmod>2018-03-04T00:00:00Z</lastmod></sitemap>
+  <sitemap><loc>http://shop.example.com/sitemap-0.xml</loc><lastmod>2016-01-01</lastmod></sitemap>
+</sitemapindex>`
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+/*
+ * the url and the parser of a request
+ */
+type requestRecord struct {
+	url    string
+	parser string
+}
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		name     string
+		gen      func(*model.Model) (module.ParseResponse, *constant.YiError)
+		model    *model.Model
+		url      string
+		body     []byte
+		items    []data.Item
+		requests []requestRecord
+		errors   int
+	}{
+		{
+			name:  "rss",
+			gen:   GenFeedParser,
+			model: &model.Model{Rule: map[string]string{"items": "true"}, AddQueueParser: "news"},
+			url:   "http://news.example.com/rss",
+			body:  []byte(rssFeed),
+			items: []data.Item{
+				{
+					"title": "First", "link": "http://news.example.com/news/1", "id": "news-1",
+					"published": "2018-03-04T10:00:00Z", "updated": "", "summary": "The first news",
+					"author": "Ann", "categories": []interface{}{"world"},
+				},
+				{
+					"title": "Second", "link": "http://news.example.com/news/2", "id": "http://news.example.com/news/2",
+					"published": "2017-12-01T08:00:00Z", "updated": "", "summary": "",
+					"author": "", "categories": []interface{}{},
+				},
+			},
+			requests: []requestRecord{
+				{"http://news.example.com/news/1", "news"},
+				{"http://news.example.com/news/2", "news"},
+			},
+		},
+		{
+			name:     "rss after",
+			gen:      GenFeedParser,
+			model:    &model.Model{Rule: map[string]string{"after": "2018-01-01"}},
+			url:      "http://news.example.com/rss",
+			body:     []byte(rssFeed),
+			items:    []data.Item{},
+			requests: []requestRecord{{"http://news.example.com/news/1", ""}},
+		},
+		{
+			name:  "atom",
+			gen:   GenFeedParser,
+			model: &model.Model{Rule: map[string]string{"items": "true", "follow": "false"}},
+			url:   "http://blog.example.com/atom.xml",
+			body:  []byte(atomFeed),
+			items: []data.Item{
+				{
+					"title": "Post", "link": "http://blog.example.com/post/1", "id": "tag:blog,2018:1",
+					"published": "2018-03-01T00:00:00Z", "updated": "2018-03-05T00:00:00Z", "summary": "Full text",
+					"author": "Ann, Bob", "categories": []interface{}{"go"},
+				},
+				{
+					"title": "Old post", "link": "http://blog.example.com/post/0", "id": "",
+					"published": "", "updated": "2017-01-01T00:00:00Z", "summary": "",
+					"author": "", "categories": []interface{}{},
+				},
+			},
+			requests: []requestRecord{},
+		},
+		{
+			name:     "atom wanted",
+			gen:      GenFeedParser,
+			model:    &model.Model{WantedRegUrls: []string{"/post/1$"}},
+			url:      "http://blog.example.com/atom.xml",
+			body:     []byte(atomFeed),
+			items:    []data.Item{},
+			requests: []requestRecord{{"http://blog.example.com/post/1", ""}},
+		},
+		{
+			name:  "sitemap",
+			gen:   GenSitemapParser,
+			model: &model.Model{Name: "sitemap", Rule: map[string]string{"items": "true", "after": "2018-01-01"}, AddQueueParser: "item"},
+			url:   "http://shop.example.com/sitemap.xml",
+			body:  []byte(sitemap),
+			items: []data.Item{
+				{"loc": "http://shop.example.com/item/1", "lastmod": "2018-03-04T00:00:00Z", "changefreq": "daily", "priority": "0.8"},
+				{"loc": "http://shop.example.com/about", "lastmod": "", "changefreq": "", "priority": ""},
+			},
+			requests: []requestRecord{
+				{"http://shop.example.com/item/1", "item"},
+				{"http://shop.example.com/about", "item"},
+			},
+		},
+		{
+			name:     "gzipped sitemap",
+			gen:      GenSitemapParser,
+			model:    &model.Model{Name: "sitemap", WantedRegUrls: []string{"/item/"}},
+			url:      "http://shop.example.com/sitemap.xml.gz",
+			body:     gzipped(t, sitemap),
+			items:    []data.Item{},
+			requests: []requestRecord{{"http://shop.example.com/item/1", ""}, {"http://shop.example.com/item/2", ""}},
+		},
+		{
+			name:     "sitemap index",
+			gen:      GenSitemapParser,
+			model:    &model.Model{Name: "sitemap", Rule: map[string]string{"within": "1000000h"}},
+			url:      "http://shop.example.com/sitemap.xml",
+			body:     []byte(sitemapIndex),
+			items:    []data.Item{},
+			requests: []requestRecord{{"http://shop.example.com/sitemap-1.xml.gz", "sitemap"}, {"http://shop.example.com/sitemap-0.xml", "sitemap"}},
+		},
+		{
+			name:     "fresh sitemap index",
+			gen:      GenSitemapParser,
+			model:    &model.Model{Name: "sitemap", Rule: map[string]string{"after": "2018-01-01"}},
+			url:      "http://shop.example.com/sitemap.xml",
+			body:     []byte(sitemapIndex),
+			items:    []data.Item{},
+			requests: []requestRecord{{"http://shop.example.com/sitemap-1.xml.gz", "sitemap"}},
+		},
+		{
+			name:     "empty feed",
+			gen:      GenFeedParser,
+			model:    &model.Model{},
+			url:      "http://news.example.com/rss",
+			body:     []byte{},
+			items:    []data.Item{},
+			requests: []requestRecord{},
+			errors:   1,
+		},
+		{
+			name:     "empty sitemap",
+			gen:      GenSitemapParser,
+			model:    &model.Model{Name: "sitemap"},
+			url:      "http://shop.example.com/sitemap.xml",
+			body:     []byte(`<urlset></urlset>`),
+			items:    []data.Item{},
+			requests: []requestRecord{},
+		},
+		{
+			name:     "broken gzip",
+			gen:      GenSitemapParser,
+			model:    &model.Model{Name: "sitemap"},
+			url:      "http://shop.example.com/sitemap.xml.gz",
+			body:     gzipped(t, sitemap)[:20],
+			items:    []data.Item{},
+			requests: []requestRecord{},
+			errors:   1,
+		},
+		{
+			name:     "too large gzip",
+			gen:      GenSitemapParser,
+			model:    &model.Model{Name: "sitemap", Rule: map[string]string{"max_size": "100"}},
+			url:      "http://shop.example.com/sitemap.xml.gz",
+			body:     gzipped(t, sitemap),
+			items:    []data.Item{},
+			requests: []requestRecord{},
+			errors:   1,
+		},
+	}
+	for _, test := range tests {
+		parser, yierr := test.gen(test.model)
+		if yierr != nil {
+			t.Fatalf("%s: gen parser fail: %s", test.name, yierr)
+		}
+		dataList, yierrs := parser(parsertest.NewResponse(t, test.url, parsertest.CONTENT_TYPE_XML, test.body))
+		if len(yierrs) != test.errors {
+			t.Errorf("%s: wrong errors: %v", test.name, yierrs)
+		}
+		items := []data.Item{}
+		requests := []requestRecord{}
+		for _, d := range dataList {
+			switch v := d.(type) {
+			case data.Item:
+				items = append(items, v)
+			case *data.Request:
+				requests = append(requests, requestRecord{v.HTTPReq().URL.String(), v.Parser()})
+			}
+		}
+		if !reflect.DeepEqual(items, test.items) {
+			t.Errorf("%s: wrong items: %#v", test.name, items)
+		}
+		if !reflect.DeepEqual(requests, test.requests) {
+			t.Errorf("%s: wrong requests: %v", test.name, requests)
+		}
+	}
+}
+
+func TestIllegalRule(t *testing.T) {
+	compileSitemap := func(m *model.Model) *constant.YiError {
+		_, yierr := GenSitemapParser(m)
+		return yierr
+	}
+	parsertest.CheckCompile(t, compileSitemap, constant.ERR_RULE_SCHEMA, []parsertest.CompileTest{
+		{Name: "no name", Model: &model.Model{}, ErrMsg: "name: "},
+		{Name: "zero max size", Model: &model.Model{Name: "sitemap", Rule: map[string]string{"max_size": "0"}}, ErrMsg: "max_size: "},
+	})
+	compileFeed := func(m *model.Model) *constant.YiError {
+		_, yierr := GenFeedParser(m)
+		return yierr
+	}
+	parsertest.CheckCompile(t, compileFeed, constant.ERR_RULE_SCHEMA, []parsertest.CompileTest{
+		// a feed model needs no name
+		{Name: "no name", Model: &model.Model{}},
+		{Name: "illegal max size", Model: &model.Model{Rule: map[string]string{"max_size": "1M"}}, ErrMsg: "max_size: "},
+		{Name: "illegal after", Model: &model.Model{Rule: map[string]string{"after": "yesterday"}}, ErrMsg: "after: "},
+		{Name: "illegal within", Model: &model.Model{Rule: map[string]string{"within": "3 days"}}, ErrMsg: "within: "},
+		{Name: "illegal url pattern", Model: &model.Model{WantedRegUrls: []string{"/post/", "(["}}, ErrMsg: "WantedRegUrls[1]: "},
+	})
+}
@@ -0,0 +1,217 @@
+package feedparser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/filter"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+	"github.com/l-dandelion/yi-ants-go/lib/utils"
+	"golang.org/x/net/html/charset"
+)
+
+/*
+ * keys of the rule of sitemap and feed models, all are optional
+ * after: only the entries modified (or published) since the time, such as "2018-01-02" or RFC3339
+ * within: only the entries modified (or published) within the duration, such as "72h"
+ * items: "true" to emit an item per entry
+ * follow: "false" not to emit the requests of entries
+ * max_size: the max bytes of a gzipped document after decompression, DEFAULT_MAX_SIZE by default
+ * entries without time are always kept.
+ * model.WantedRegUrls limits the urls of entries, and model.AddQueueParser handles them.
+ */
+const (
+	RULE_AFTER    = "after"
+	RULE_WITHIN   = "within"
+	RULE_ITEMS    = "items"
+	RULE_FOLLOW   = "follow"
+	RULE_MAX_SIZE = "max_size"
+)
+
+// the default max size of a decompressed document, the limit of the sitemap protocol
+const DEFAULT_MAX_SIZE = 50 * 1024 * 1024
+
+// layouts of times in sitemaps and feeds
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+}
+
+/*
+ * compiled rule of a sitemap or feed model
+ */
+type feedRule struct {
+	after     time.Time
+	within    time.Duration
+	items     bool
+	follow    bool
+	maxSize   int64
+	urlFilter *filter.URLFilter
+}
+
+/*
+ * compile the rule
+ */
+func compileRule(m *model.Model) (*feedRule, *constant.YiError) {
+	rule := &feedRule{follow: true, maxSize: DEFAULT_MAX_SIZE}
+	var err error
+	if v, ok := m.Rule[RULE_AFTER]; ok {
+		if rule.after, err = parseTime(v); err != nil {
+			return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "%s: illegal time %q", RULE_AFTER, v)
+		}
+	}
+	if v, ok := m.Rule[RULE_WITHIN]; ok {
+		if rule.within, err = time.ParseDuration(v); err != nil {
+			return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "%s: %s", RULE_WITHIN, err)
+		}
+	}
+	if v, ok := m.Rule[RULE_MAX_SIZE]; ok {
+		if rule.maxSize, err = strconv.ParseInt(v, 10, 64); err != nil || rule.maxSize <= 0 {
+			return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "%s: illegal size %q", RULE_MAX_SIZE, v)
+		}
+	}
+	rule.items = m.Rule[RULE_ITEMS] == "true"
+	rule.follow = m.Rule[RULE_FOLLOW] != "false"
+	if rule.urlFilter, err = filter.Compile(m.WantedRegUrls, nil); err != nil {
+		return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "WantedRegUrls%s", strings.TrimPrefix(err.Error(), "allow"))
+	}
+	return rule, nil
+}
+
+/*
+ * check whether the entry modified at the time is kept
+ * an entry without time is always kept.
+ */
+func (rule *feedRule) fresh(t time.Time) bool {
+	if t.IsZero() {
+		return true
+	}
+	if !rule.after.IsZero() && t.Before(rule.after) {
+		return false
+	}
+	if rule.within > 0 && time.Since(t) > rule.within {
+		return false
+	}
+	return true
+}
+
+/*
+ * parse a time of the layouts, the zero time if it is empty
+ */
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	var err error
+	for _, layout := range timeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+/*
+ * format the time in RFC3339, empty for the zero time
+ */
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+/*
+ * get the xml decoder of the response body
+ * a gzipped body is decompressed, and fails if it is larger than the max size of the rule.
+ */
+func (rule *feedRule) newDecoder(resp *data.Response) (*xml.Decoder, *constant.YiError) {
+	body, err := resp.GetBody()
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_CRAWL_ANALYZER, err)
+	}
+	if len(body) >= 2 && body[0] == 0x1f && body[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, constant.NewYiErrore(constant.ERR_PARSE_XML, err)
+		}
+		defer gz.Close()
+		if body, err = ioutil.ReadAll(io.LimitReader(gz, rule.maxSize+1)); err != nil {
+			return nil, constant.NewYiErrore(constant.ERR_PARSE_XML, err)
+		}
+		if int64(len(body)) > rule.maxSize {
+			return nil, constant.NewYiErrorf(constant.ERR_PARSE_XML, "The decompressed document is larger than %d bytes.", rule.maxSize)
+		}
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+	return decoder, nil
+}
+
+/*
+ * create a GET request of the url, which may be relative to the response url
+ */
+func newRequest(resp *data.Response, u string, parser string) (*data.Request, *constant.YiError) {
+	u, err := utils.GetComplateUrl(resp.HTTPRequest().URL, strings.TrimSpace(u))
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_CRAWL_GET_COMPLATE_URL, err)
+	}
+	httpReq, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_CRAWL_NEW_HTTP_REQUEST, err)
+	}
+	req := data.NewRequest(httpReq)
+	if parser != "" {
+		req.SetParser(parser)
+	}
+	return req, nil
+}
+
+/*
+ * emit the request and the item of an entry
+ * the link field of the item is set to the complete url.
+ */
+func (rule *feedRule) emit(m *model.Model, resp *data.Response, linkField string, item data.Item,
+	dataList []data.Data, errorList []*constant.YiError) ([]data.Data, []*constant.YiError) {
+	link, _ := item[linkField].(string)
+	if link == "" {
+		return dataList, errorList
+	}
+	req, yierr := newRequest(resp, link, m.AddQueueParser)
+	if yierr != nil {
+		return dataList, append(errorList, yierr)
+	}
+	link = req.HTTPReq().URL.String()
+	if !rule.urlFilter.Allowed(link) {
+		return dataList, errorList
+	}
+	if rule.items {
+		item[linkField] = link
+		dataList = append(dataList, item)
+	}
+	if rule.follow {
+		dataList = append(dataList, req)
+	}
+	return dataList, errorList
+}
@@ -0,0 +1,84 @@
+package feedparser
+
+import (
+	"strings"
+	"time"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * sitemap or sitemap index
+ */
+type sitemapDoc struct {
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+/*
+ * url or sitemap entry
+ */
+type sitemapEntry struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+/*
+ * parse the sitemap (or sitemap index) according to the rule
+ * the sitemaps of an index are requested again and handled by the same model.
+ */
+func SitemapRuleProcess(m *model.Model, rule *feedRule, resp *data.Response) (dataList []data.Data, errorList []*constant.YiError) {
+	dataList = []data.Data{}
+	errorList = []*constant.YiError{}
+
+	decoder, yierr := rule.newDecoder(resp)
+	if yierr != nil {
+		errorList = append(errorList, yierr)
+		return
+	}
+	doc := &sitemapDoc{}
+	if err := decoder.Decode(doc); err != nil {
+		errorList = append(errorList, constant.NewYiErrore(constant.ERR_PARSE_XML, err))
+		return
+	}
+
+	for _, sitemap := range doc.Sitemaps {
+		if strings.TrimSpace(sitemap.Loc) == "" || !rule.fresh(entryTime(sitemap)) {
+			continue
+		}
+		req, yierr := newRequest(resp, sitemap.Loc, m.Name)
+		if yierr != nil {
+			errorList = append(errorList, yierr)
+			continue
+		}
+		dataList = append(dataList, req)
+	}
+
+	for _, u := range doc.URLs {
+		loc := strings.TrimSpace(u.Loc)
+		t := entryTime(u)
+		if loc == "" || !rule.fresh(t) {
+			continue
+		}
+		item := data.Item{
+			"loc":        loc,
+			"lastmod":    formatTime(t),
+			"changefreq": strings.TrimSpace(u.ChangeFreq),
+			"priority":   strings.TrimSpace(u.Priority),
+		}
+		dataList, errorList = rule.emit(m, resp, "loc", item, dataList, errorList)
+	}
+	return
+}
+
+/*
+ * get the time of the entry, the zero time if it is missing or illegal
+ */
+func entryTime(entry sitemapEntry) time.Time {
+	t, _ := parseTime(entry.LastMod)
+	return t
+}
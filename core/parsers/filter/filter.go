@@ -1,18 +1,183 @@
 package filter
 
 import (
+	"fmt"
 	"regexp"
+	"strings"
+	"sync"
 )
 
+/*
+ * prefixes of patterns, a pattern without prefix is a regex
+ * regex: such as "regex:/item/\d+", matching any part of the url
+ * glob:  such as "glob:https://*.example.com/item/*", matching the whole url,
+ *        "*" matches any characters and "?" matches one character
+ */
+const (
+	PREFIX_REGEX = "regex:"
+	PREFIX_GLOB  = "glob:"
+)
+
+/*
+ * compiled url pattern
+ */
+type Pattern struct {
+	expr string
+	re   *regexp.Regexp
+}
+
+/*
+ * compile a pattern
+ */
+func CompilePattern(expr string) (*Pattern, error) {
+	source := expr
+	switch {
+	case strings.HasPrefix(expr, PREFIX_GLOB):
+		source = globToRegex(strings.TrimPrefix(expr, PREFIX_GLOB))
+	case strings.HasPrefix(expr, PREFIX_REGEX):
+		source = strings.TrimPrefix(expr, PREFIX_REGEX)
+	}
+	re, err := regexp.Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{expr: expr, re: re}, nil
+}
+
+/*
+ * compile the patterns
+ * the error starts with the index of the illegal pattern, such as "[1]: ..."
+ */
+func CompilePatterns(exprs []string) ([]*Pattern, error) {
+	patterns := []*Pattern{}
+	for i, expr := range exprs {
+		p, err := CompilePattern(expr)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %s", i, err)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+/*
+ * get the expression of the pattern
+ */
+func (p *Pattern) String() string {
+	return p.expr
+}
+
+/*
+ * check whether the url matches the pattern
+ */
+func (p *Pattern) Match(url string) bool {
+	return p.re.MatchString(url)
+}
+
+/*
+ * convert a glob into an anchored regex
+ */
+func globToRegex(glob string) string {
+	var buf strings.Builder
+	buf.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			buf.WriteString(".*")
+		case '?':
+			buf.WriteString(".")
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	buf.WriteString("$")
+	return buf.String()
+}
+
+/*
+ * url filter with allow and deny patterns
+ * a url matching any deny pattern is denied,
+ * otherwise it is allowed if there is no allow pattern or it matches any allow pattern.
+ * the filter is safe for concurrent use.
+ */
+type URLFilter struct {
+	allow []*Pattern
+	deny  []*Pattern
+}
+
+/*
+ * create a filter of the patterns
+ */
+func New(allow, deny []*Pattern) *URLFilter {
+	return &URLFilter{allow: allow, deny: deny}
+}
+
+/*
+ * compile the patterns and create a filter
+ * the error starts with the list and the index of the illegal pattern, such as "deny[1]: ..."
+ */
+func Compile(allow, deny []string) (*URLFilter, error) {
+	allowPatterns, err := CompilePatterns(allow)
+	if err != nil {
+		return nil, fmt.Errorf("allow%s", err)
+	}
+	denyPatterns, err := CompilePatterns(deny)
+	if err != nil {
+		return nil, fmt.Errorf("deny%s", err)
+	}
+	return New(allowPatterns, denyPatterns), nil
+}
+
+/*
+ * check whether the filter has no pattern, which allows every url
+ */
+func (f *URLFilter) Empty() bool {
+	return len(f.allow) == 0 && len(f.deny) == 0
+}
+
+/*
+ * check whether the url is allowed
+ */
+func (f *URLFilter) Allowed(url string) bool {
+	for _, p := range f.deny {
+		if p.Match(url) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, p := range f.allow {
+		if p.Match(url) {
+			return true
+		}
+	}
+	return false
+}
+
+// compiled patterns of function Filter
+var cache sync.Map
+
+/*
+ * check whether the url matches any of the patterns
+ * the patterns are compiled once and cached, an illegal pattern matches nothing.
+ * it is kept for compatibility, compiling a URLFilter in advance is preferred.
+ */
 func Filter(url string, regUrls []string) bool {
 	if len(url) == 0 {
 		return false
 	}
 
 	for _, regUrl := range regUrls {
-		reg := regexp.MustCompile(regUrl)
-		match := reg.MatchString(url)
-		if match {
+		v, ok := cache.Load(regUrl)
+		if !ok {
+			p, err := CompilePattern(regUrl)
+			if err != nil {
+				p = nil
+			}
+			v, _ = cache.LoadOrStore(regUrl, p)
+		}
+		if p := v.(*Pattern); p != nil && p.Match(url) {
 			return true
 		}
 	}
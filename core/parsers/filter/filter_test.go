@@ -0,0 +1,64 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestURLFilter(t *testing.T) {
+	f, err := Compile(
+		[]string{`/item/\d+`, "glob:https://*.example.com/list?page=*"},
+		[]string{"regex:/item/0", "glob:*.jpg"})
+	if err != nil {
+		t.Fatalf("Compile fail: %s", err)
+	}
+	cases := map[string]bool{
+		"http://example.com/item/12":             true,
+		"http://example.com/item/01":             false,
+		"http://example.com/item/12/a.jpg":       false,
+		"https://www.example.com/list?page=2":    true,
+		"https://www.example.com/list?page=2#x":  true,
+		"http://www.example.com/list?page=2":     false,
+		"https://www.example.com/listing?page=2": false,
+		"http://example.com/about":               false,
+	}
+	for u, expected := range cases {
+		if f.Allowed(u) != expected {
+			t.Errorf("Wrong result of %s: expected: %v", u, expected)
+		}
+	}
+
+	empty, _ := Compile(nil, nil)
+	if !empty.Empty() || !empty.Allowed("http://example.com/") {
+		t.Fatalf("An empty filter should allow every url")
+	}
+	denyOnly, _ := Compile(nil, []string{"logout"})
+	if denyOnly.Allowed("http://example.com/logout") || !denyOnly.Allowed("http://example.com/") {
+		t.Fatalf("Wrong result of a filter with deny patterns only")
+	}
+}
+
+func TestCompileIllegal(t *testing.T) {
+	_, err := Compile([]string{"a", "("}, nil)
+	if err == nil || err.Error()[:len("allow[1]")] != "allow[1]" {
+		t.Fatalf("Wrong error of an illegal allow pattern: %v", err)
+	}
+	_, err = Compile(nil, []string{"regex:["})
+	if err == nil || err.Error()[:len("deny[0]")] != "deny[0]" {
+		t.Fatalf("Wrong error of an illegal deny pattern: %v", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	regUrls := []string{"(", `example\.com/item`}
+	for i := 0; i < 2; i++ {
+		if !Filter("http://example.com/item/1", regUrls) {
+			t.Fatalf("The url should match")
+		}
+		if Filter("http://example.com/list", regUrls) {
+			t.Fatalf("The url should not match")
+		}
+	}
+	if Filter("", regUrls) {
+		t.Fatalf("An empty url should not match")
+	}
+}
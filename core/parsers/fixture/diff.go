@@ -0,0 +1,179 @@
+package fixture
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lines of context around the changes of a diff
+const DIFF_CONTEXT = 3
+
+// max product of the numbers of the changed lines compared by lcs,
+// the changed lines are shown as a whole beyond it
+const maxDiffCells = 4000000
+
+/*
+ * get the line diff from want to got, empty if they are the same
+ * the diff is in the unified format without the file headers:
+ * removed lines start with "-", added lines start with "+",
+ * and every hunk starts with "@@ -start,count +start,count @@".
+ */
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	a, b := splitLines(want), splitLines(got)
+	ops := diffLines(a, b)
+
+	var buf strings.Builder
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// a hunk from the context before the change to the context after the last close change
+		start := i - DIFF_CONTEXT
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*DIFF_CONTEXT {
+				break
+			}
+			end = next
+		}
+		stop := end + DIFF_CONTEXT
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+		countA, countB := 0, 0
+		for _, op := range ops[start:stop] {
+			if op.kind != '+' {
+				countA++
+			}
+			if op.kind != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(ops[start].a, countA), hunkRange(ops[start].b, countB))
+		for _, op := range ops[start:stop] {
+			buf.WriteByte(op.kind)
+			buf.WriteString(op.line)
+			buf.WriteByte('\n')
+		}
+		i = stop
+	}
+	return buf.String()
+}
+
+/*
+ * line of a diff
+ * kind is ' ', '-' or '+', and a and b are the indexes of the line in the texts.
+ */
+type diffOp struct {
+	kind byte
+	line string
+	a, b int
+}
+
+/*
+ * get the range of the lines from the index in a hunk header
+ * the count is omitted if it is 1, and an empty range starts at the line before it.
+ */
+func hunkRange(index int, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", index)
+	case 1:
+		return fmt.Sprintf("%d", index+1)
+	}
+	return fmt.Sprintf("%d,%d", index+1, count)
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "\n")
+}
+
+/*
+ * get the diff of the lines by the longest common subsequence
+ */
+func diffLines(a, b []string) []diffOp {
+	ops := []diffOp{}
+	// the common prefix and suffix are kept
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{' ', a[i], i, i})
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(ma)*len(mb) > maxDiffCells {
+		for i, line := range ma {
+			ops = append(ops, diffOp{'-', line, prefix + i, prefix})
+		}
+		for j, line := range mb {
+			ops = append(ops, diffOp{'+', line, prefix + len(ma), prefix + j})
+		}
+	} else {
+		ops = append(ops, lcsOps(ma, mb, prefix)...)
+	}
+	for k := 0; k < suffix; k++ {
+		i, j := len(a)-suffix+k, len(b)-suffix+k
+		ops = append(ops, diffOp{' ', a[i], i, j})
+	}
+	return ops
+}
+
+func lcsOps(a, b []string, offset int) []diffOp {
+	// lengths[i][j] is the lcs length of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	ops := []diffOp{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], offset + i, offset + j})
+			i++
+			j++
+		case j == len(b) || i < len(a) && lengths[i+1][j] >= lengths[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i], offset + i, offset + j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], offset + i, offset + j})
+			j++
+		}
+	}
+	return ops
+}
@@ -0,0 +1,289 @@
+package fixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/module/local/analyzer"
+	"github.com/l-dandelion/yi-ants-go/core/parsers"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+)
+
+/*
+ * files of a fixture suite directory
+ *   models.json      the parser models, []*model.Model
+ *   fixtures.json    the fixtures, []*Fixture
+ *   golden/<name>.json  the expected result of every fixture
+ * the body files of the fixtures are relative to the directory.
+ */
+const (
+	MODELS_FILE   = "models.json"
+	FIXTURES_FILE = "fixtures.json"
+	GOLDEN_DIR    = "golden"
+)
+
+/*
+ * saved response
+ * Parser: the parser model name carried by the request, empty for unnamed requests
+ * Status: 200 by default
+ * Parent: the parent item carried by the request
+ */
+type Fixture struct {
+	Name   string            `json:"name"`
+	URL    string            `json:"url"`
+	File   string            `json:"file"`
+	Parser string            `json:"parser,omitempty"`
+	Depth  uint32            `json:"depth,omitempty"`
+	Status int               `json:"status,omitempty"`
+	Header map[string]string `json:"header,omitempty"`
+	Parent data.Item         `json:"parent,omitempty"`
+}
+
+/*
+ * emitted request recorded in a result
+ */
+type RequestRecord struct {
+	Method string    `json:"method"`
+	URL    string    `json:"url"`
+	Parser string    `json:"parser,omitempty"`
+	Depth  uint32    `json:"depth"`
+	Body   string    `json:"body,omitempty"`
+	Parent data.Item `json:"parent,omitempty"`
+}
+
+/*
+ * items, requests and errors emitted by the analyzer for a fixture
+ */
+type Result struct {
+	Items    []data.Item      `json:"items"`
+	Requests []*RequestRecord `json:"requests"`
+	Errors   []string         `json:"errors"`
+}
+
+/*
+ * parser models and their fixtures
+ */
+type Suite struct {
+	Dir      string
+	Models   []*model.Model
+	Fixtures []*Fixture
+	analyzer module.Analyzer
+}
+
+/*
+ * load the suite in the directory, and compile the models
+ */
+func Load(dir string) (*Suite, error) {
+	suite := &Suite{Dir: dir}
+	if err := readJSON(filepath.Join(dir, MODELS_FILE), &suite.Models); err != nil {
+		return nil, err
+	}
+	if err := readJSON(filepath.Join(dir, FIXTURES_FILE), &suite.Fixtures); err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for i, fixture := range suite.Fixtures {
+		if fixture.Name == "" || fixture.URL == "" || fixture.File == "" {
+			return nil, fmt.Errorf("fixture[%d]: name, url and file are required", i)
+		}
+		if names[fixture.Name] {
+			return nil, fmt.Errorf("fixture[%d]: duplicate name %s", i, fixture.Name)
+		}
+		names[fixture.Name] = true
+	}
+	respParsers, yierr := parsers.GenParsersByModels(suite.Models)
+	if yierr != nil {
+		return nil, yierr
+	}
+	var err error
+	if suite.analyzer, err = newAnalyzer(respParsers); err != nil {
+		return nil, err
+	}
+	return suite, nil
+}
+
+func newAnalyzer(respParsers []module.ParseResponse) (module.Analyzer, error) {
+	analyzer, yierr := analyzer.New("A1", respParsers, module.CalculateScoreSimple)
+	if yierr != nil {
+		return nil, yierr
+	}
+	return analyzer, nil
+}
+
+func readJSON(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return nil
+}
+
+/*
+ * run the fixture through the analyzer
+ */
+func (suite *Suite) Run(fixture *Fixture) (*Result, error) {
+	body, err := ioutil.ReadFile(filepath.Join(suite.Dir, fixture.File))
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequest("GET", fixture.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req := data.NewRequest(httpReq)
+	if fixture.Parser != "" {
+		req.SetParser(fixture.Parser)
+	}
+	req.SetDepth(fixture.Depth)
+	if fixture.Parent != nil {
+		req.SetParentItem(fixture.Parent)
+	}
+	status := fixture.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	header := http.Header{}
+	for key, value := range fixture.Header {
+		header.Set(key, value)
+	}
+	httpResp := &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Request:    httpReq,
+	}
+	dataList, yierrs := suite.analyzer.Analyze(data.NewResponse(req, httpResp))
+
+	result := &Result{Items: []data.Item{}, Requests: []*RequestRecord{}, Errors: []string{}}
+	for _, d := range dataList {
+		switch v := d.(type) {
+		case data.Item:
+			result.Items = append(result.Items, v)
+		case *data.Request:
+			record, err := recordRequest(v)
+			if err != nil {
+				return nil, err
+			}
+			result.Requests = append(result.Requests, record)
+		}
+	}
+	for _, yierr := range yierrs {
+		result.Errors = append(result.Errors, yierr.Error())
+	}
+	return result, nil
+}
+
+func recordRequest(req *data.Request) (*RequestRecord, error) {
+	httpReq := req.HTTPReq()
+	record := &RequestRecord{
+		Method: httpReq.Method,
+		URL:    httpReq.URL.String(),
+		Parser: req.Parser(),
+		Depth:  req.Depth(),
+		Parent: req.ParentItem(),
+	}
+	if httpReq.GetBody != nil {
+		body, err := httpReq.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		record.Body = string(b)
+	}
+	return record, nil
+}
+
+/*
+ * encode the result as the content of a golden file
+ * the keys of items are sorted by encoding/json, so the content is stable.
+ */
+func (result *Result) Encode() ([]byte, error) {
+	b, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+/*
+ * get the path of the golden file of the fixture
+ */
+func (suite *Suite) GoldenPath(fixture *Fixture) string {
+	return filepath.Join(suite.Dir, GOLDEN_DIR, fixture.Name+".json")
+}
+
+/*
+ * outcome of checking a fixture
+ * Diff is empty if the result is the same as the golden file.
+ */
+type Outcome struct {
+	Fixture *Fixture
+	Got     []byte
+	Want    []byte
+	Diff    string
+	Updated bool
+	Err     error
+}
+
+/*
+ * check whether the outcome passes
+ */
+func (outcome *Outcome) OK() bool {
+	return outcome.Err == nil && (outcome.Diff == "" || outcome.Updated)
+}
+
+/*
+ * run every fixture and compare the result with its golden file
+ * the golden files of the different results are rewritten if update is true.
+ */
+func (suite *Suite) Check(update bool) []*Outcome {
+	outcomes := []*Outcome{}
+	for _, fixture := range suite.Fixtures {
+		outcomes = append(outcomes, suite.check(fixture, update))
+	}
+	return outcomes
+}
+
+func (suite *Suite) check(fixture *Fixture, update bool) *Outcome {
+	outcome := &Outcome{Fixture: fixture}
+	result, err := suite.Run(fixture)
+	if err != nil {
+		outcome.Err = err
+		return outcome
+	}
+	if outcome.Got, err = result.Encode(); err != nil {
+		outcome.Err = err
+		return outcome
+	}
+	path := suite.GoldenPath(fixture)
+	outcome.Want, err = ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		outcome.Err = err
+		return outcome
+	}
+	if bytes.Equal(outcome.Got, outcome.Want) {
+		return outcome
+	}
+	outcome.Diff = Diff(string(outcome.Want), string(outcome.Got))
+	if update {
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = ioutil.WriteFile(path, outcome.Got, 0644)
+		}
+		outcome.Err = err
+		outcome.Updated = err == nil
+	}
+	return outcome
+}
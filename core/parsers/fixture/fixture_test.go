@@ -0,0 +1,133 @@
+package fixture
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/*
+ * read the fixtures of the directory without loading the suite
+ */
+func readFixtures(t *testing.T, dir string) []*Fixture {
+	b, err := ioutil.ReadFile(filepath.Join(dir, FIXTURES_FILE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := []*Fixture{}
+	if err = json.Unmarshal(b, &fixtures); err != nil {
+		t.Fatal(err)
+	}
+	return fixtures
+}
+
+func TestCheck(t *testing.T) {
+	suite, err := Load("testdata")
+	if err != nil {
+		t.Fatalf("Load fail: %s", err)
+	}
+	fixtures := readFixtures(t, "testdata")
+	outcomes := suite.Check(false)
+	if len(outcomes) != len(fixtures) {
+		t.Fatalf("Wrong number of outcomes: %d", len(outcomes))
+	}
+	for i, outcome := range outcomes {
+		if outcome.Fixture.Name != fixtures[i].Name {
+			t.Fatalf("Wrong fixture of outcome %d: %s", i, outcome.Fixture.Name)
+		}
+		if !outcome.OK() {
+			t.Fatalf("Fixture %s fail: %v\n%s", outcome.Fixture.Name, outcome.Err, outcome.Diff)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixture-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := []string{MODELS_FILE, FIXTURES_FILE}
+	for _, fixture := range readFixtures(t, "testdata") {
+		files = append(files, fixture.File)
+	}
+	for _, name := range files {
+		b, err := ioutil.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	suite, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load fail: %s", err)
+	}
+	outcomes := suite.Check(false)
+	if len(outcomes) != len(suite.Fixtures) {
+		t.Fatalf("Wrong number of outcomes: %d", len(outcomes))
+	}
+	for _, outcome := range outcomes {
+		if outcome.OK() || outcome.Diff == "" {
+			t.Fatalf("Fixture %s without golden file passes", outcome.Fixture.Name)
+		}
+	}
+	for _, outcome := range suite.Check(true) {
+		if !outcome.OK() || !outcome.Updated {
+			t.Fatalf("Fixture %s is not updated: %v", outcome.Fixture.Name, outcome.Err)
+		}
+	}
+	for _, outcome := range suite.Check(false) {
+		if !outcome.OK() || outcome.Updated {
+			t.Fatalf("Fixture %s fail after update: %s", outcome.Fixture.Name, outcome.Diff)
+		}
+	}
+
+	// a changed page is reported by the diff
+	var outcome *Outcome
+	html := "<html><body><div class=\"item\"><h2>Apple</h2><span class=\"price\">3</span></div></body></html>"
+	if err = ioutil.WriteFile(filepath.Join(dir, "list.html"), []byte(html), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range suite.Check(false) {
+		if o.Fixture.File == "list.html" {
+			outcome = o
+		}
+	}
+	if outcome == nil {
+		t.Fatalf("No fixture of list.html")
+	}
+	if outcome.OK() || !strings.Contains(outcome.Diff, "-      \"price\": 1.5,") ||
+		!strings.Contains(outcome.Diff, "+      \"price\": 3,") {
+		t.Fatalf("Wrong diff:\n%s", outcome.Diff)
+	}
+}
+
+func TestLoadIllegal(t *testing.T) {
+	if _, err := Load("nothing"); err == nil {
+		t.Fatalf("Expected an error")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	if diff := Diff("a\nb\n", "a\nb\n"); diff != "" {
+		t.Fatalf("Unexpected diff: %s", diff)
+	}
+	want := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	got := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n13\n"
+	expected := "@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n" +
+		"@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+13\n"
+	if diff := Diff(want, got); diff != expected {
+		t.Fatalf("Wrong diff:\n%s", diff)
+	}
+	if diff := Diff("", "a\n"); diff != "@@ -0,0 +1 @@\n+a\n" {
+		t.Fatalf("Wrong diff:\n%s", diff)
+	}
+	if diff := Diff("a\nb\nc\n", "a\nc\n"); diff != "@@ -1,3 +1,2 @@\n a\n-b\n c\n" {
+		t.Fatalf("Wrong diff:\n%s", diff)
+	}
+}
@@ -0,0 +1,340 @@
+package form
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/templateparser"
+	"github.com/l-dandelion/yi-ants-go/lib/library/parseurl"
+	"github.com/l-dandelion/yi-ants-go/lib/utils"
+)
+
+// max number of the submissions of a form, the combinations of the values of its fields
+const MAX_SUBMISSIONS = 10000
+
+// input types never submitted
+var unsubmittedInputs = []string{"submit", "button", "image", "reset", "file"}
+
+/*
+ * compiled form submission
+ * combinations are the values of fields, one per submission.
+ */
+type Form struct {
+	method       string
+	action       string
+	sel          templateparser.Selector
+	encoding     string
+	parser       string
+	fields       []string
+	combinations [][]string
+}
+
+/*
+ * check and compile the form submission
+ */
+func Compile(spec *model.FormSpec) (*Form, error) {
+	if spec == nil {
+		return nil, fmt.Errorf("null form")
+	}
+	form := &Form{
+		method:   strings.ToUpper(spec.Method),
+		action:   spec.Action,
+		encoding: spec.Encoding,
+		parser:   spec.Parser,
+	}
+	switch form.method {
+	case "", "GET", "POST":
+	default:
+		return nil, fmt.Errorf("method: unsupported method %q", spec.Method)
+	}
+	switch form.encoding {
+	case "", model.FORM_ENCODING_URLENCODED:
+	case model.FORM_ENCODING_MULTIPART, model.FORM_ENCODING_JSON:
+		if form.method == "GET" {
+			return nil, fmt.Errorf("encoding: %s is not allowed with GET", form.encoding)
+		}
+	default:
+		return nil, fmt.Errorf("encoding: unknown encoding %q", spec.Encoding)
+	}
+	if spec.Selector != "" {
+		var err error
+		if form.sel, err = templateparser.CompileSelector(spec.Selector); err != nil {
+			return nil, fmt.Errorf("selector: %s", err)
+		}
+	}
+	form.combinations = [][]string{{}}
+	names := map[string]bool{}
+	for i, field := range spec.Fields {
+		if field == nil || field.Name == "" {
+			return nil, fmt.Errorf("fields[%d].name: empty name", i)
+		}
+		if names[field.Name] {
+			return nil, fmt.Errorf("fields[%d].name: duplicate name %q", i, field.Name)
+		}
+		names[field.Name] = true
+		if len(field.Values) == 0 {
+			return nil, fmt.Errorf("fields[%d].values: empty values", i)
+		}
+		values := parseurl.ParseReqUrl(field.Values, nil)
+		if len(form.combinations)*len(values) > MAX_SUBMISSIONS {
+			return nil, fmt.Errorf("fields[%d].values: more than %d submissions", i, MAX_SUBMISSIONS)
+		}
+		// the values of the last field vary fastest
+		combinations := [][]string{}
+		for _, combination := range form.combinations {
+			for _, value := range values {
+				next := make([]string, len(combination), len(combination)+1)
+				copy(next, combination)
+				combinations = append(combinations, append(next, value))
+			}
+		}
+		form.combinations = combinations
+		form.fields = append(form.fields, field.Name)
+	}
+	return form, nil
+}
+
+/*
+ * check whether the form is taken from the page
+ */
+func (form *Form) FromPage() bool {
+	return form.sel != nil
+}
+
+/*
+ * values of a form in the order of the inputs
+ */
+type formValues struct {
+	names  []string
+	values url.Values
+}
+
+func (v *formValues) add(name, value string) {
+	if _, ok := v.values[name]; !ok {
+		v.names = append(v.names, name)
+	}
+	v.values.Add(name, value)
+}
+
+func (v *formValues) set(name, value string) {
+	if _, ok := v.values[name]; !ok {
+		v.names = append(v.names, name)
+	}
+	v.values.Set(name, value)
+}
+
+func (v *formValues) copy() *formValues {
+	result := &formValues{names: append([]string{}, v.names...), values: url.Values{}}
+	for name, values := range v.values {
+		result.values[name] = append([]string{}, values...)
+	}
+	return result
+}
+
+/*
+ * create the requests of the submissions
+ * page is the url of the page, nil for seeds whose action must be absolute.
+ * doc is the page, nothing is submitted if the form is taken from the page and it has no such form.
+ */
+func (form *Form) Requests(page *url.URL, doc *goquery.Selection) ([]*data.Request, error) {
+	method, action, encoding := "GET", "", model.FORM_ENCODING_URLENCODED
+	defaults := &formValues{values: url.Values{}}
+	if form.sel != nil {
+		if doc == nil {
+			return nil, fmt.Errorf("no page of the form")
+		}
+		node := form.sel.Find(doc).First()
+		if node.Length() == 0 {
+			return nil, nil
+		}
+		if v, ok := node.Attr("method"); ok && strings.EqualFold(strings.TrimSpace(v), "POST") {
+			method = "POST"
+		}
+		if v, ok := node.Attr("action"); ok {
+			action = strings.TrimSpace(v)
+		}
+		if v, ok := node.Attr("enctype"); ok && strings.EqualFold(strings.TrimSpace(v), "multipart/form-data") && method == "POST" {
+			encoding = model.FORM_ENCODING_MULTIPART
+		}
+		readInputs(node, defaults)
+	}
+	if form.method != "" {
+		method = form.method
+	}
+	if form.action != "" {
+		action = form.action
+	}
+	if form.encoding != "" {
+		encoding = form.encoding
+	}
+	if method == "GET" {
+		if encoding != model.FORM_ENCODING_URLENCODED {
+			return nil, fmt.Errorf("encoding %s is not allowed with GET", encoding)
+		}
+	}
+
+	var target *url.URL
+	var err error
+	switch {
+	case page != nil:
+		var u string
+		if u, err = utils.GetComplateUrl(page, action); err == nil {
+			target, err = url.Parse(u)
+		}
+	case action == "":
+		err = fmt.Errorf("empty action")
+	default:
+		if target, err = url.Parse(action); err == nil && !target.IsAbs() {
+			err = fmt.Errorf("action %q is not absolute", action)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	requests := []*data.Request{}
+	for _, combination := range form.combinations {
+		values := defaults.copy()
+		for i, name := range form.fields {
+			values.set(name, combination[i])
+		}
+		httpReq, err := newHTTPRequest(method, target, encoding, values)
+		if err != nil {
+			return nil, err
+		}
+		req := data.NewRequest(httpReq)
+		if form.parser != "" {
+			req.SetParser(form.parser)
+		}
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
+
+/*
+ * create the http request of a submission
+ */
+func newHTTPRequest(method string, target *url.URL, encoding string, values *formValues) (*http.Request, error) {
+	if method == "GET" {
+		u := *target
+		u.RawQuery = values.values.Encode()
+		return http.NewRequest(method, u.String(), nil)
+	}
+	var (
+		body        []byte
+		contentType string
+	)
+	switch encoding {
+	case model.FORM_ENCODING_MULTIPART:
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		// the boundary depends on the values only, so the same submission has the same fingerprint
+		if err := writer.SetBoundary(boundaryOf(values)); err != nil {
+			return nil, err
+		}
+		for _, name := range values.names {
+			for _, value := range values.values[name] {
+				if err := writer.WriteField(name, value); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		body, contentType = buf.Bytes(), writer.FormDataContentType()
+	case model.FORM_ENCODING_JSON:
+		object := map[string]interface{}{}
+		for name, vals := range values.values {
+			if len(vals) == 1 {
+				object[name] = vals[0]
+			} else {
+				object[name] = vals
+			}
+		}
+		var err error
+		if body, err = json.Marshal(object); err != nil {
+			return nil, err
+		}
+		contentType = "application/json"
+	default:
+		body, contentType = []byte(values.values.Encode()), "application/x-www-form-urlencoded"
+	}
+	httpReq, err := http.NewRequest(method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+	return httpReq, nil
+}
+
+func boundaryOf(values *formValues) string {
+	h := sha1.New()
+	for _, name := range values.names {
+		for _, value := range values.values[name] {
+			fmt.Fprintf(h, "%q=%q\n", name, value)
+		}
+	}
+	return "yiants" + hex.EncodeToString(h.Sum(nil))[:24]
+}
+
+/*
+ * read the values of the inputs of the form as a browser submits them
+ */
+func readInputs(node *goquery.Selection, values *formValues) {
+	node.Find("input, select, textarea").Each(func(_ int, input *goquery.Selection) {
+		name, _ := input.Attr("name")
+		if name == "" {
+			return
+		}
+		if _, disabled := input.Attr("disabled"); disabled {
+			return
+		}
+		switch goquery.NodeName(input) {
+		case "input":
+			inputType := strings.ToLower(input.AttrOr("type", "text"))
+			for _, t := range unsubmittedInputs {
+				if inputType == t {
+					return
+				}
+			}
+			if inputType == "checkbox" || inputType == "radio" {
+				if _, checked := input.Attr("checked"); !checked {
+					return
+				}
+				values.add(name, input.AttrOr("value", "on"))
+				return
+			}
+			values.add(name, input.AttrOr("value", ""))
+		case "textarea":
+			values.add(name, input.Text())
+		case "select":
+			options := input.Find("option")
+			selected := options.FilterFunction(func(_ int, option *goquery.Selection) bool {
+				_, ok := option.Attr("selected")
+				return ok
+			})
+			_, multiple := input.Attr("multiple")
+			if selected.Length() == 0 && !multiple {
+				selected = options.First()
+			}
+			selected.Each(func(_ int, option *goquery.Selection) {
+				if value, ok := option.Attr("value"); ok {
+					values.add(name, value)
+				} else {
+					values.add(name, strings.TrimSpace(option.Text()))
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package form
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+)
+
+const searchPage = `<html><body>
+<form id="search" action="/search" method="post">
+  <input type="hidden" name="token" value="abc">
+  <input type="text" name="q" value="default">
+  <input type="checkbox" name="fast" value="1" checked>
+  <input type="checkbox" name="slow" value="1">
+  <input type="text" name="off" value="x" disabled>
+  <select name="sort"><option value="new">New</option><option value="old" selected>Old</option></select>
+  <select name="lang"><option>en</option><option>fr</option></select>
+  <textarea name="note">hi</textarea>
+  <input type="submit" name="go" value="Go">
+</form>
+</body></html>`
+
+func body(t *testing.T, req *data.Request) string {
+	r, err := req.HTTPReq().GetBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(r)
+	return string(b)
+}
+
+func TestSeedRequests(t *testing.T) {
+	m := &model.Model{Name: "result", SeedForms: []*model.FormSpec{{
+		Action: "http://example.com/search?x=1",
+		Fields: []*model.FormField{
+			{Name: "q", Values: []string{"apple", "{pear|plum}"}},
+			{Name: "page", Values: []string{"{1-2,1}"}},
+		},
+	}}}
+	reqs, yierr := SeedRequests(m)
+	if yierr != nil {
+		t.Fatalf("SeedRequests fail: %s", yierr)
+	}
+	urls := []string{}
+	for _, req := range reqs {
+		if req.Parser() != "result" || req.HTTPReq().Method != "GET" {
+			t.Fatalf("Wrong request: %s %s", req.HTTPReq().Method, req.Parser())
+		}
+		urls = append(urls, req.HTTPReq().URL.String())
+	}
+	expected := []string{
+		"http://example.com/search?page=1&q=apple",
+		"http://example.com/search?page=2&q=apple",
+		"http://example.com/search?page=1&q=pear",
+		"http://example.com/search?page=2&q=pear",
+		"http://example.com/search?page=1&q=plum",
+		"http://example.com/search?page=2&q=plum",
+	}
+	if !reflect.DeepEqual(urls, expected) {
+		t.Fatalf("Wrong urls: %v", urls)
+	}
+
+	illegal := []*model.FormSpec{
+		{Action: "/search"},
+		{Action: "http://example.com/", Selector: "form"},
+		{Action: "http://example.com/", Method: "PUT"},
+		{Action: "http://example.com/", Encoding: "json"},
+		{Action: "http://example.com/", Method: "POST", Encoding: "xml"},
+		{Action: "http://example.com/", Fields: []*model.FormField{{Name: "q"}}},
+		{Action: "http://example.com/", Fields: []*model.FormField{{Name: "q", Values: []string{"a"}}, {Name: "q", Values: []string{"b"}}}},
+		{Action: "http://example.com/", Fields: []*model.FormField{{Name: "a", Values: []string{"{1-1000,1}"}}, {Name: "b", Values: []string{"{1-11,1}"}}}},
+	}
+	for _, spec := range illegal {
+		if _, yierr := SeedRequests(&model.Model{Name: "a", SeedForms: []*model.FormSpec{spec}}); yierr == nil {
+			t.Fatalf("No error of the illegal form %+v", spec)
+		}
+	}
+}
+
+func TestPageForm(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(searchPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := url.Parse("http://example.com/index")
+	form, err := Compile(&model.FormSpec{
+		Selector: "form#search",
+		Fields:   []*model.FormField{{Name: "q", Values: []string{"lamp", "desk"}}},
+		Parser:   "result",
+	})
+	if err != nil {
+		t.Fatalf("Compile fail: %s", err)
+	}
+	reqs, err := form.Requests(page, doc.Selection)
+	if err != nil {
+		t.Fatalf("Requests fail: %s", err)
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("Wrong number of requests: %d", len(reqs))
+	}
+	httpReq := reqs[0].HTTPReq()
+	if httpReq.Method != "POST" || httpReq.URL.String() != "http://example.com/search" ||
+		httpReq.Header.Get("Content-Type") != "application/x-www-form-urlencoded" || reqs[0].Parser() != "result" {
+		t.Fatalf("Wrong request: %s %s %s", httpReq.Method, httpReq.URL, httpReq.Header)
+	}
+	values, _ := url.ParseQuery(body(t, reqs[0]))
+	expected := url.Values{
+		"token": {"abc"}, "q": {"lamp"}, "fast": {"1"}, "sort": {"old"}, "lang": {"en"}, "note": {"hi"},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("Wrong values: %v", values)
+	}
+	// the submissions are different requests
+	if reqs[0].UniqueKey() == reqs[1].UniqueKey() || reqs[0].Fingerprint() == reqs[1].Fingerprint() {
+		t.Fatal("The submissions have the same key")
+	}
+
+	// no such form
+	form, _ = Compile(&model.FormSpec{Selector: "form#login"})
+	if reqs, err = form.Requests(page, doc.Selection); err != nil || len(reqs) != 0 {
+		t.Fatalf("Wrong requests of a missing form: %v %v", reqs, err)
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	spec := &model.FormSpec{
+		Method: "post",
+		Action: "http://example.com/api",
+		Fields: []*model.FormField{{Name: "q", Values: []string{"lamp"}}, {Name: "page", Values: []string{"2"}}},
+	}
+
+	spec.Encoding = model.FORM_ENCODING_JSON
+	reqs, yierr := SeedRequests(&model.Model{Name: "a", SeedForms: []*model.FormSpec{spec}})
+	if yierr != nil {
+		t.Fatalf("SeedRequests fail: %s", yierr)
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(body(t, reqs[0])), &object); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(object, map[string]interface{}{"q": "lamp", "page": "2"}) ||
+		reqs[0].HTTPReq().Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Wrong json body: %v", object)
+	}
+
+	spec.Encoding = model.FORM_ENCODING_MULTIPART
+	reqs, yierr = SeedRequests(&model.Model{Name: "a", SeedForms: []*model.FormSpec{spec}})
+	if yierr != nil {
+		t.Fatalf("SeedRequests fail: %s", yierr)
+	}
+	mediaType, params, err := mime.ParseMediaType(reqs[0].HTTPReq().Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		t.Fatalf("Wrong content type: %s", reqs[0].HTTPReq().Header.Get("Content-Type"))
+	}
+	form, err := multipart.NewReader(strings.NewReader(body(t, reqs[0])), params["boundary"]).ReadForm(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(form.Value, map[string][]string{"q": {"lamp"}, "page": {"2"}}) {
+		t.Fatalf("Wrong multipart body: %v", form.Value)
+	}
+	// the same submission has the same fingerprint
+	again, _ := SeedRequests(&model.Model{Name: "a", SeedForms: []*model.FormSpec{spec}})
+	if again[0].Fingerprint() != reqs[0].Fingerprint() {
+		t.Fatal("The same submission has different fingerprints")
+	}
+	get, _ := http.NewRequest("POST", "http://example.com/api", nil)
+	if data.NewRequest(get).Fingerprint() == reqs[0].Fingerprint() {
+		t.Fatal("The body is not in the fingerprint")
+	}
+}
@@ -0,0 +1,93 @@
+package form
+
+import (
+	"fmt"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * compile the form submissions of the model under the path, such as "models[0].forms"
+ * the parser of the submissions is the default parser if it is not set.
+ */
+func compileForms(specs []*model.FormSpec, path string, parser string) ([]*Form, *constant.YiError) {
+	forms := []*Form{}
+	for i, spec := range specs {
+		form, err := Compile(spec)
+		if err != nil {
+			return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "%s[%d]: %s", path, i, err)
+		}
+		if form.parser == "" {
+			form.parser = parser
+		}
+		forms = append(forms, form)
+	}
+	return forms, nil
+}
+
+/*
+ * create the initial requests of model.SeedForms, handled by the model by default
+ */
+func SeedRequests(m *model.Model) ([]*data.Request, *constant.YiError) {
+	forms, yierr := compileForms(m.SeedForms, "SeedForms", m.Name)
+	if yierr != nil {
+		return nil, yierr
+	}
+	requests := []*data.Request{}
+	for i, form := range forms {
+		if form.FromPage() {
+			return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "SeedForms[%d].selector: not allowed without page", i)
+		}
+		reqs, err := form.Requests(nil, nil)
+		if err != nil {
+			return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "SeedForms[%d]: %s", i, err)
+		}
+		requests = append(requests, reqs...)
+	}
+	return requests, nil
+}
+
+/*
+ * generate the parser submitting model.Forms from every response, nil if the model has no forms
+ * the submissions are handled by model.AddQueueParser by default.
+ */
+func GenFormParser(m *model.Model) (module.ParseResponse, *constant.YiError) {
+	if len(m.Forms) == 0 {
+		return nil, nil
+	}
+	forms, yierr := compileForms(m.Forms, "Forms", m.AddQueueParser)
+	if yierr != nil {
+		return nil, yierr
+	}
+	needDom := false
+	for _, form := range forms {
+		needDom = needDom || form.FromPage()
+	}
+	return func(resp *data.Response) ([]data.Data, []*constant.YiError) {
+		dataList := []data.Data{}
+		var doc *goquery.Selection
+		if needDom {
+			dom, err := resp.GetDom()
+			if err != nil {
+				return nil, []*constant.YiError{constant.NewYiErrore(constant.ERR_CRAWL_GET_DOM, err)}
+			}
+			doc = dom.Selection
+		}
+		errorList := []*constant.YiError{}
+		for i, form := range forms {
+			reqs, err := form.Requests(resp.HTTPRequest().URL, doc)
+			if err != nil {
+				errorList = append(errorList, constant.NewYiErrore(constant.ERR_CRAWL_ANALYZER, fmt.Errorf("Forms[%d]: %s", i, err)))
+				continue
+			}
+			for _, req := range reqs {
+				dataList = append(dataList, req)
+			}
+		}
+		return dataList, errorList
+	}, nil
+}
@@ -0,0 +1,22 @@
+package jsonparser
+
+import (
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * generate a parser for json responses
+ * the json paths in the rule are compiled once here.
+ */
+func GenJSONParser(model *model.Model) (module.ParseResponse, *constant.YiError) {
+	rule, yierr := compileRule(model.Rule)
+	if yierr != nil {
+		return nil, yierr
+	}
+	return func(resp *data.Response) ([]data.Data, []*constant.YiError) {
+		return JSONRuleProcess(model, rule, resp)
+	}, nil
+}
@@ -0,0 +1,183 @@
+package jsonparser
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/parsertest"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+func TestCompileRule(t *testing.T) {
+	compile := func(m *model.Model) *constant.YiError {
+		_, yierr := compileRule(m.Rule)
+		return yierr
+	}
+	parsertest.CheckCompile(t, compile, constant.ERR_JSON_PATH, []parsertest.CompileTest{
+		{Name: "fields", Model: &model.Model{Rule: map[string]string{"node": "$.items[*]", "title": "@.title", "site": "example"}}},
+		{Name: "no rule", Model: &model.Model{}},
+		{Name: "empty node", Model: &model.Model{Rule: map[string]string{"node": ""}}, ErrMsg: "key: node"},
+		{Name: "unclosed bracket", Model: &model.Model{Rule: map[string]string{"title": "$.items[0"}}, ErrMsg: "key: title"},
+		{Name: "empty bracket", Model: &model.Model{Rule: map[string]string{"title": "$.items[]"}}, ErrMsg: "key: title"},
+		{Name: "illegal index", Model: &model.Model{Rule: map[string]string{"title": "@.tags[x]"}}, ErrMsg: "key: title"},
+		{Name: "empty key", Model: &model.Model{Rule: map[string]string{"title": "$..", "node": "$.items"}}, ErrMsg: "key: title"},
+		{Name: "illegal next url", Model: &model.Model{Rule: map[string]string{"next_url": "$.next[", "title": "@.title"}}, ErrMsg: "key: next_url"},
+		{Name: "template without cursor", Model: &model.Model{Rule: map[string]string{"next_url": "/list?page={$cursor}"}}, ErrMsg: "next_url"},
+		{Name: "param without cursor", Model: &model.Model{Rule: map[string]string{"next_param": "page"}}, ErrMsg: "next_param"},
+		{Name: "param with cursor", Model: &model.Model{Rule: map[string]string{"next_param": "page", "next_cursor": "$.page.next"}}},
+	})
+}
+
+func TestJSONRuleProcess(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    *model.Model
+		url      string
+		body     string
+		items    []data.Item
+		requests []string
+		errors   int
+	}{
+		{
+			name: "items",
+			model: &model.Model{Rule: map[string]string{
+				"node": "$.items", "title": "@.title", "tags": "@.tags[*]", "total": "$.total", "site": "example",
+			}},
+			url:  "http://api.example.com/list",
+			body: `{"total": 2, "items": [{"title": "a", "tags": ["x", "y"]}, {"title": "b", "tags": ["z"]}, {"other": 1}]}`,
+			items: []data.Item{
+				{"title": "a", "tags": []interface{}{"x", "y"}, "total": json.Number("2"), "site": "example"},
+				// a wildcard path is always a list
+				{"title": "b", "tags": []interface{}{"z"}, "total": json.Number("2"), "site": "example"},
+				// the total of the document root matches
+				{"title": nil, "tags": []interface{}{}, "total": json.Number("2"), "site": "example"},
+			},
+			requests: []string{},
+		},
+		{
+			name:     "no match",
+			model:    &model.Model{Rule: map[string]string{"node": "$.items", "title": "@.title"}},
+			url:      "http://api.example.com/list",
+			body:     `{"data": []}`,
+			items:    []data.Item{},
+			requests: []string{},
+		},
+		{
+			name:     "empty object",
+			model:    &model.Model{Rule: map[string]string{"title": "$.title"}},
+			url:      "http://api.example.com/list",
+			body:     `{}`,
+			items:    []data.Item{},
+			requests: []string{},
+		},
+		{
+			name:     "empty document",
+			model:    &model.Model{Rule: map[string]string{"title": "$.title"}},
+			url:      "http://api.example.com/list",
+			body:     ``,
+			items:    []data.Item{},
+			requests: []string{},
+			errors:   1,
+		},
+		{
+			name:     "illegal document",
+			model:    &model.Model{Rule: map[string]string{"title": "$.title"}},
+			url:      "http://api.example.com/list",
+			body:     `{"title": `,
+			items:    []data.Item{},
+			requests: []string{},
+			errors:   1,
+		},
+		{
+			name: "add queue",
+			model: &model.Model{
+				Rule:     map[string]string{"node": "$.items[*]", "id": "@.id"},
+				AddQueue: []string{"/item/{$id}", "/user/{$user}"},
+			},
+			url:      "http://api.example.com/list",
+			body:     `{"items": [{"id": 1}, {"id": 2}]}`,
+			items:    []data.Item{{"id": json.Number("1")}, {"id": json.Number("2")}},
+			requests: []string{"http://api.example.com/item/1", "http://api.example.com/item/2"},
+		},
+		{
+			name: "next param",
+			model: &model.Model{Name: "list", Rule: map[string]string{
+				"node": "$.items", "id": "@.id", "next_cursor": "$.next", "next_param": "page",
+			}},
+			url:      "http://api.example.com/list?page=1",
+			body:     `{"items": [{"id": 1}], "next": 2}`,
+			items:    []data.Item{{"id": json.Number("1")}},
+			requests: []string{"http://api.example.com/list?page=2"},
+		},
+		{
+			name: "next template",
+			model: &model.Model{Rule: map[string]string{
+				"id": "$.id", "next_cursor": "$.cursor", "next_url": "/list?after={$cursor}",
+			}},
+			url:      "http://api.example.com/list",
+			body:     `{"id": 1, "cursor": "abc"}`,
+			items:    []data.Item{{"id": json.Number("1")}},
+			requests: []string{"http://api.example.com/list?after=abc"},
+		},
+		{
+			name: "escaped cursor",
+			model: &model.Model{Rule: map[string]string{
+				"id": "$.id", "next_cursor": "$.cursor", "next_url": "/list?after={$cursor}&size=10",
+			}},
+			url:      "http://api.example.com/list",
+			body:     `{"id": 1, "cursor": "a+b/c=&d"}`,
+			items:    []data.Item{{"id": json.Number("1")}},
+			requests: []string{"http://api.example.com/list?after=a%2Bb%2Fc%3D%26d&size=10"},
+		},
+		{
+			name: "last page",
+			model: &model.Model{Rule: map[string]string{
+				"id": "$.id", "next_cursor": "$.cursor", "next_param": "page",
+			}},
+			url:      "http://api.example.com/list",
+			body:     `{"id": 1, "cursor": false}`,
+			items:    []data.Item{{"id": json.Number("1")}},
+			requests: []string{},
+		},
+		{
+			name:     "same page",
+			model:    &model.Model{Rule: map[string]string{"id": "$.id", "next_url": "$.next"}},
+			url:      "http://api.example.com/list?page=3",
+			body:     `{"id": 1, "next": "/list?page=3"}`,
+			items:    []data.Item{{"id": json.Number("1")}},
+			requests: []string{},
+		},
+	}
+	for _, test := range tests {
+		parser, yierr := GenJSONParser(test.model)
+		if yierr != nil {
+			t.Fatalf("%s: GenJSONParser fail: %s", test.name, yierr)
+		}
+		dataList, yierrs := parser(parsertest.NewResponse(t, test.url, parsertest.CONTENT_TYPE_JSON, []byte(test.body)))
+		if len(yierrs) != test.errors {
+			t.Errorf("%s: wrong errors: %v", test.name, yierrs)
+		}
+		items := []data.Item{}
+		requests := []string{}
+		for _, d := range dataList {
+			switch v := d.(type) {
+			case data.Item:
+				items = append(items, v)
+			case *data.Request:
+				requests = append(requests, v.HTTPReq().URL.String())
+				if test.model.Name != "" && v.Parser() != test.model.Name {
+					t.Errorf("%s: wrong parser of the next page: %q", test.name, v.Parser())
+				}
+			}
+		}
+		if !reflect.DeepEqual(items, test.items) {
+			t.Errorf("%s: wrong items: %v", test.name, items)
+		}
+		if !reflect.DeepEqual(requests, test.requests) {
+			t.Errorf("%s: wrong requests: %v", test.name, requests)
+		}
+	}
+}
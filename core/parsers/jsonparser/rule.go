@@ -0,0 +1,279 @@
+package jsonparser
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+	"github.com/l-dandelion/yi-ants-go/lib/library/jsonpath"
+	"github.com/l-dandelion/yi-ants-go/lib/library/parseurl"
+	"github.com/l-dandelion/yi-ants-go/lib/utils"
+)
+
+/*
+ * reserved keys of the rule, other keys are item fields
+ * a field value starting with "$" is a json path from the document root,
+ * starting with "@" is a json path from the item node, otherwise it is a constant.
+ */
+const (
+	RULE_NODE        = "node"        // json path of the items, the whole document is one item if not set
+	RULE_NEXT_CURSOR = "next_cursor" // json path of the cursor (or page token) of the next page
+	RULE_NEXT_URL    = "next_url"    // json path of the next page url, or a url template with {$cursor} escaped as a query value
+	RULE_NEXT_PARAM  = "next_param"  // query parameter set to the cursor on the current url
+)
+
+// the placeholder of the cursor in the next url template
+const CURSOR_PLACEHOLDER = "{$cursor}"
+
+/*
+ * an item field of the rule
+ * path is nil if the field is a constant.
+ */
+type field struct {
+	name  string
+	path  *jsonpath.Path
+	value string
+}
+
+/*
+ * compiled rule of a json model
+ */
+type jsonRule struct {
+	node            *jsonpath.Path
+	fields          []field
+	nextCursor      *jsonpath.Path
+	nextURL         *jsonpath.Path
+	nextURLTemplate string
+	nextParam       string
+}
+
+/*
+ * compile the json paths of the rule
+ */
+func compileRule(rule map[string]string) (*jsonRule, *constant.YiError) {
+	result := &jsonRule{}
+	var yierr *constant.YiError
+	compile := func(key, expr string) *jsonpath.Path {
+		p, err := jsonpath.Compile(expr)
+		if err != nil && yierr == nil {
+			yierr = constant.NewYiErrorf(constant.ERR_JSON_PATH, "%s (key: %s)", err, key)
+		}
+		return p
+	}
+	keys := make([]string, 0, len(rule))
+	for key := range rule {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := rule[key]
+		switch key {
+		case RULE_NODE:
+			result.node = compile(key, value)
+		case RULE_NEXT_CURSOR:
+			result.nextCursor = compile(key, value)
+		case RULE_NEXT_URL:
+			if isPath(value) {
+				result.nextURL = compile(key, value)
+			} else {
+				result.nextURLTemplate = value
+			}
+		case RULE_NEXT_PARAM:
+			result.nextParam = value
+		default:
+			f := field{name: key, value: value}
+			if isPath(value) {
+				f.path = compile(key, value)
+			}
+			result.fields = append(result.fields, f)
+		}
+	}
+	if yierr != nil {
+		return nil, yierr
+	}
+	if result.nextCursor == nil && (result.nextURLTemplate != "" || result.nextParam != "") {
+		return nil, constant.NewYiErrorf(constant.ERR_JSON_PATH,
+			"%s is required by %s and %s.", RULE_NEXT_CURSOR, RULE_NEXT_URL, RULE_NEXT_PARAM)
+	}
+	return result, nil
+}
+
+/*
+ * check whether the value is a json path
+ */
+func isPath(value string) bool {
+	return strings.HasPrefix(value, "$") || strings.HasPrefix(value, "@")
+}
+
+/*
+ * parse the json response according to the rule
+ * return items, follow-up requests of model.AddQueue and the request of the next page
+ */
+func JSONRuleProcess(model *model.Model, rule *jsonRule, resp *data.Response) (dataList []data.Data, errorList []*constant.YiError) {
+	dataList = []data.Data{}
+	errorList = []*constant.YiError{}
+
+	text, err := resp.GetText()
+	if err != nil {
+		errorList = append(errorList, constant.NewYiErrore(constant.ERR_CRAWL_ANALYZER, err))
+		return
+	}
+	doc, err := jsonpath.Decode(text)
+	if err != nil {
+		errorList = append(errorList, constant.NewYiErrore(constant.ERR_PARSE_JSON, err))
+		return
+	}
+
+	for _, node := range rule.itemNodes(doc) {
+		item := rule.getItem(doc, node)
+		if item == nil {
+			continue
+		}
+		dataList = append(dataList, item)
+		if len(model.AddQueue) == 0 {
+			continue
+		}
+		for _, u := range parseurl.ParseReqUrl(model.AddQueue, item) {
+			// the url refers to a missing field
+			if strings.Contains(u, "{$") {
+				continue
+			}
+			req, yierr := newRequest(resp, u)
+			if yierr != nil {
+				errorList = append(errorList, yierr)
+				continue
+			}
+			if model.AddQueueParser != "" {
+				req.SetParser(model.AddQueueParser)
+			}
+			dataList = append(dataList, req)
+		}
+	}
+
+	next, yierr := rule.nextPage(doc, resp)
+	if yierr != nil {
+		errorList = append(errorList, yierr)
+	}
+	if next != nil {
+		// the next page is handled by the same model
+		if model.Name != "" {
+			next.SetParser(model.Name)
+		}
+		dataList = append(dataList, next)
+	}
+	return
+}
+
+/*
+ * get the nodes of items
+ * an array matched by the node path is expanded into its elements.
+ */
+func (rule *jsonRule) itemNodes(doc interface{}) []interface{} {
+	if rule.node == nil {
+		return []interface{}{doc}
+	}
+	nodes := []interface{}{}
+	for _, v := range rule.node.Find(doc) {
+		if arr, ok := v.([]interface{}); ok {
+			nodes = append(nodes, arr...)
+		} else {
+			nodes = append(nodes, v)
+		}
+	}
+	return nodes
+}
+
+/*
+ * get an item from the node
+ * a field of a definite path is a value or nil if nothing matches,
+ * a field of a path matching several values (such as @.tags[*]) is always a list, so that it has one type in all items.
+ * return nil if no json path field matches.
+ */
+func (rule *jsonRule) getItem(doc, node interface{}) data.Item {
+	item := data.Item{}
+	isNull := true
+	for _, f := range rule.fields {
+		if f.path == nil {
+			item[f.name] = f.value
+			continue
+		}
+		base := node
+		if strings.HasPrefix(f.value, "$") {
+			base = doc
+		}
+		values := f.path.Find(base)
+		switch {
+		case !f.path.Definite():
+			item[f.name] = values
+		case len(values) == 0:
+			item[f.name] = nil
+		default:
+			item[f.name] = values[0]
+		}
+		if len(values) > 0 {
+			isNull = false
+		}
+	}
+	if isNull {
+		return nil
+	}
+	return item
+}
+
+/*
+ * generate the request of the next page
+ * return nil if there is no next page.
+ */
+func (rule *jsonRule) nextPage(doc interface{}, resp *data.Response) (*data.Request, *constant.YiError) {
+	var nextURL string
+	if rule.nextURL != nil {
+		v, _ := rule.nextURL.First(doc)
+		nextURL = jsonpath.ToString(v)
+	} else if rule.nextCursor != nil {
+		v, _ := rule.nextCursor.First(doc)
+		cursor := jsonpath.ToString(v)
+		if cursor == "" || cursor == "false" {
+			return nil, nil
+		}
+		if rule.nextURLTemplate != "" {
+			nextURL = strings.Replace(rule.nextURLTemplate, CURSOR_PLACEHOLDER, url.QueryEscape(cursor), -1)
+		} else if rule.nextParam != "" {
+			u := *resp.HTTPRequest().URL
+			query := u.Query()
+			query.Set(rule.nextParam, cursor)
+			u.RawQuery = query.Encode()
+			nextURL = u.String()
+		}
+	}
+	if nextURL == "" {
+		return nil, nil
+	}
+	req, yierr := newRequest(resp, nextURL)
+	if yierr != nil {
+		return nil, yierr
+	}
+	// the same page again means the end
+	if req.HTTPReq().URL.String() == resp.HTTPRequest().URL.String() {
+		return nil, nil
+	}
+	return req, nil
+}
+
+/*
+ * create a GET request of the url, which may be relative to the response url
+ */
+func newRequest(resp *data.Response, u string) (*data.Request, *constant.YiError) {
+	u, err := utils.GetComplateUrl(resp.HTTPRequest().URL, u)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_CRAWL_GET_COMPLATE_URL, err)
+	}
+	httpReq, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_CRAWL_NEW_HTTP_REQUEST, err)
+	}
+	return data.NewRequest(httpReq), nil
+}
@@ -0,0 +1,109 @@
+package metaparser
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * extract the objects of the json-ld scripts, an item per object
+ * the objects of a @graph are items, the @context is removed and the types are normalized.
+ * the numbers are json.Number like the items of the json parsers.
+ */
+func extractJSONLD(doc *goquery.Document) ([]data.Item, []*constant.YiError) {
+	items := []data.Item{}
+	errorList := []*constant.YiError{}
+	doc.Find("script[type=\"application/ld+json\"]").Each(func(i int, script *goquery.Selection) {
+		text := strings.TrimSpace(script.Text())
+		for _, wrapper := range [][2]string{{"<![CDATA[", "]]>"}, {"<!--", "-->"}} {
+			text = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(text, wrapper[0]), wrapper[1]))
+		}
+		text = strings.TrimSuffix(text, ";")
+		if text == "" {
+			return
+		}
+		var value interface{}
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			errorList = append(errorList, constant.NewYiErrorf(constant.ERR_PARSE_JSON, "json-ld[%d]: %s", i, err))
+			return
+		}
+		for _, object := range jsonLDObjects(value) {
+			item := data.Item(normalizeJSONLD(object).(map[string]interface{}))
+			item[METADATA_SOURCE] = SOURCE_JSONLD
+			items = append(items, item)
+		}
+	})
+	return items, errorList
+}
+
+/*
+ * get the top objects of the json-ld value, expanding the lists and the @graph
+ */
+func jsonLDObjects(value interface{}) []map[string]interface{} {
+	objects := []map[string]interface{}{}
+	switch v := value.(type) {
+	case []interface{}:
+		for _, element := range v {
+			objects = append(objects, jsonLDObjects(element)...)
+		}
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			return jsonLDObjects(graph)
+		}
+		objects = append(objects, v)
+	}
+	return objects
+}
+
+/*
+ * remove the @context and normalize the types of the value and its nested objects
+ */
+func normalizeJSONLD(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, element := range v {
+			list[i] = normalizeJSONLD(element)
+		}
+		return list
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			switch key {
+			case "@context":
+			case METADATA_TYPE:
+				object[key] = normalizeTypes(element)
+			default:
+				object[key] = normalizeJSONLD(element)
+			}
+		}
+		return object
+	}
+	return value
+}
+
+/*
+ * normalize the type or the list of types, a list of one type is the type
+ */
+func normalizeTypes(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return normalizeType(v)
+	case []interface{}:
+		if len(v) == 1 {
+			return normalizeTypes(v[0])
+		}
+		list := make([]interface{}, len(v))
+		for i, element := range v {
+			list[i] = normalizeTypes(element)
+		}
+		return list
+	}
+	return value
+}
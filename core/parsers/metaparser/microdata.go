@@ -0,0 +1,115 @@
+package metaparser
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/lib/utils"
+)
+
+/*
+ * the attributes holding the values of the itemprop of the tags, the text for other tags
+ * the values of href, src and data are completed into urls.
+ */
+var propAttrs = map[string]string{
+	"meta":   "content",
+	"a":      "href",
+	"area":   "href",
+	"link":   "href",
+	"audio":  "src",
+	"embed":  "src",
+	"iframe": "src",
+	"img":    "src",
+	"source": "src",
+	"track":  "src",
+	"video":  "src",
+	"object": "data",
+	"time":   "datetime",
+	"data":   "value",
+	"meter":  "value",
+}
+
+/*
+ * extract the top microdata items, whose itemscope isn't the itemprop of another item
+ */
+func extractMicrodata(doc *goquery.Document, page *url.URL) []data.Item {
+	items := []data.Item{}
+	doc.Find("[itemscope]").Each(func(_ int, sel *goquery.Selection) {
+		if _, ok := sel.Attr("itemprop"); ok {
+			return
+		}
+		item := data.Item(readMicrodata(sel, page))
+		item[METADATA_SOURCE] = SOURCE_MICRODATA
+		items = append(items, item)
+	})
+	return items
+}
+
+/*
+ * read the microdata item of the itemscope
+ * the properties of nested items belong to the nested items, which are maps.
+ */
+func readMicrodata(scope *goquery.Selection, page *url.URL) map[string]interface{} {
+	item := map[string]interface{}{}
+	if types := strings.Fields(scope.AttrOr("itemtype", "")); len(types) == 1 {
+		item[METADATA_TYPE] = normalizeType(types[0])
+	} else if len(types) > 1 {
+		list := make([]interface{}, len(types))
+		for i, t := range types {
+			list[i] = normalizeType(t)
+		}
+		item[METADATA_TYPE] = list
+	}
+	if id := strings.TrimSpace(scope.AttrOr("itemid", "")); id != "" {
+		item[METADATA_ID] = id
+	}
+	var walk func(sel *goquery.Selection)
+	walk = func(sel *goquery.Selection) {
+		sel.Children().Each(func(_ int, child *goquery.Selection) {
+			_, isScope := child.Attr("itemscope")
+			if names := strings.Fields(child.AttrOr("itemprop", "")); len(names) > 0 {
+				var value interface{}
+				if isScope {
+					value = readMicrodata(child, page)
+				} else {
+					value = propValue(child, page)
+				}
+				for _, name := range names {
+					addProperty(item, name, value)
+				}
+			}
+			if !isScope {
+				walk(child)
+			}
+		})
+	}
+	walk(scope)
+	return item
+}
+
+/*
+ * get the value of the itemprop of the node
+ */
+func propValue(sel *goquery.Selection, page *url.URL) string {
+	tag := goquery.NodeName(sel)
+	attr, ok := propAttrs[tag]
+	if !ok {
+		return collapse(sel.Text())
+	}
+	value, ok := sel.Attr(attr)
+	if !ok {
+		if tag == "time" {
+			return collapse(sel.Text())
+		}
+		return ""
+	}
+	value = strings.TrimSpace(value)
+	if attr == "href" || attr == "src" || attr == "data" {
+		if u, err := utils.GetComplateUrl(page, value); err == nil {
+			return u
+		}
+	}
+	return value
+}
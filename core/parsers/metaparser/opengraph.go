@@ -0,0 +1,62 @@
+package metaparser
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+)
+
+// the prefix of opengraph properties
+const OG_PREFIX = "og:"
+
+// the namespaces of the object types of opengraph, whose properties keep their prefix
+var ogNamespaces = []string{"article:", "book:", "profile:", "music:", "video:", "product:"}
+
+/*
+ * extract the opengraph item of the page, nil if it has no opengraph tags
+ * the og: prefix is removed, such as "title" and "image:width",
+ * the properties of object types keep their namespace, such as "article:published_time".
+ */
+func extractOpenGraph(doc *goquery.Document) data.Item {
+	var item data.Item
+	doc.Find("meta[property], meta[name]").Each(func(_ int, meta *goquery.Selection) {
+		property, ok := meta.Attr("property")
+		if !ok {
+			property = meta.AttrOr("name", "")
+		}
+		property = strings.ToLower(strings.TrimSpace(property))
+		content, ok := meta.Attr("content")
+		if !ok {
+			return
+		}
+		name := ""
+		if strings.HasPrefix(property, OG_PREFIX) {
+			name = strings.TrimPrefix(property, OG_PREFIX)
+		} else {
+			for _, namespace := range ogNamespaces {
+				if strings.HasPrefix(property, namespace) {
+					name = property
+					break
+				}
+			}
+		}
+		if name == "" {
+			return
+		}
+		if item == nil {
+			item = data.Item{}
+		}
+		addProperty(item, name, strings.TrimSpace(content))
+	})
+	if item == nil {
+		return nil
+	}
+	item[METADATA_TYPE] = "website"
+	if t, ok := item["type"].(string); ok && t != "" {
+		item[METADATA_TYPE] = t
+	}
+	delete(item, "type")
+	item[METADATA_SOURCE] = SOURCE_OPENGRAPH
+	return item
+}
@@ -0,0 +1,278 @@
+package metaparser
+
+import (
+	"strings"
+
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/templateparser"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * sources of metadata
+ */
+const (
+	SOURCE_JSONLD    = "jsonld"    // <script type="application/ld+json">
+	SOURCE_MICRODATA = "microdata" // itemscope, itemtype and itemprop attributes
+	SOURCE_OPENGRAPH = "opengraph" // <meta property="og:..."> tags
+)
+
+/*
+ * reserved fields of the items of metadata models
+ * @type: the schema.org type without the schema.org prefix, such as "Product",
+ *        a list if the object has many types, the og:type ("website" by default) for opengraph
+ * @id: the @id of json-ld, or the itemid of microdata
+ * @source: one of SOURCE_*
+ * the other fields are the properties, a property of many values is a list,
+ * and nested objects are maps.
+ */
+const (
+	METADATA_TYPE   = "@type"
+	METADATA_ID     = "@id"
+	METADATA_SOURCE = "@source"
+)
+
+/*
+ * keys of the rule of metadata models, all are optional
+ * types: comma separated schema.org types of the kept items, such as "Product,Offer",
+ *        compared without case and the schema.org prefix, all items are kept if empty
+ * sources: comma separated SOURCE_* extracted, all sources if empty
+ * the fields of model.Schema, which takes the page as one item, are merged into every item
+ * and override its properties, except nil and empty values.
+ * with model.Schema, the links and AddQueue of the model are followed like template models.
+ */
+const (
+	RULE_TYPES   = "types"
+	RULE_SOURCES = "sources"
+)
+
+// the prefixes of schema.org types
+var typePrefixes = []string{"http://schema.org/", "https://schema.org/", "schema:"}
+
+/*
+ * compiled rule of a metadata model
+ * template is the parser of the fields of model.Schema, nil if it is not set.
+ */
+type metadataRule struct {
+	types    map[string]bool
+	sources  map[string]bool
+	template module.ParseResponse
+}
+
+/*
+ * compile the rule
+ */
+func compileRule(m *model.Model) (*metadataRule, *constant.YiError) {
+	rule := &metadataRule{
+		types:   map[string]bool{},
+		sources: map[string]bool{SOURCE_JSONLD: true, SOURCE_MICRODATA: true, SOURCE_OPENGRAPH: true},
+	}
+	for _, t := range splitList(m.Rule[RULE_TYPES]) {
+		rule.types[strings.ToLower(normalizeType(t))] = true
+	}
+	if sources := splitList(m.Rule[RULE_SOURCES]); len(sources) > 0 {
+		rule.sources = map[string]bool{}
+		for _, source := range sources {
+			switch source {
+			case SOURCE_JSONLD, SOURCE_MICRODATA, SOURCE_OPENGRAPH:
+				rule.sources[source] = true
+			default:
+				return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "%s: unknown source %q", RULE_SOURCES, source)
+			}
+		}
+	}
+	if m.Schema != nil {
+		if m.Schema.Node != "" {
+			return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "Schema.node: not allowed, the page is one item")
+		}
+		if m.Schema.CarryItem {
+			return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "Schema.carry_item: not supported by metadata models")
+		}
+		// the rule of the model is the metadata rule, not a legacy template rule
+		templateModel := *m
+		templateModel.Rule = nil
+		var yierr *constant.YiError
+		if rule.template, yierr = templateparser.GenTemplateParser(&templateModel); yierr != nil {
+			return nil, yierr
+		}
+	}
+	return rule, nil
+}
+
+/*
+ * generate a parser extracting the structured metadata of html pages
+ */
+func GenMetadataParser(model *model.Model) (module.ParseResponse, *constant.YiError) {
+	rule, yierr := compileRule(model)
+	if yierr != nil {
+		return nil, yierr
+	}
+	return func(resp *data.Response) ([]data.Data, []*constant.YiError) {
+		return MetadataRuleProcess(model, rule, resp)
+	}, nil
+}
+
+/*
+ * extract the metadata items of the page according to the rule
+ * the items are in the order of json-ld, microdata and opengraph.
+ * the fields of the schema are merged into every item,
+ * and they are the only item if the page has no metadata item of the rule but has any of them.
+ */
+func MetadataRuleProcess(m *model.Model, rule *metadataRule, resp *data.Response) (dataList []data.Data, errorList []*constant.YiError) {
+	dataList = []data.Data{}
+	errorList = []*constant.YiError{}
+
+	doc, err := resp.GetDom()
+	if err != nil {
+		errorList = append(errorList, constant.NewYiErrore(constant.ERR_CRAWL_GET_DOM, err))
+		return
+	}
+	page := resp.HTTPRequest().URL
+
+	items := []data.Item{}
+	if rule.sources[SOURCE_JSONLD] {
+		jsonldItems, jsonldErrors := extractJSONLD(doc)
+		items = append(items, jsonldItems...)
+		errorList = append(errorList, jsonldErrors...)
+	}
+	if rule.sources[SOURCE_MICRODATA] {
+		items = append(items, extractMicrodata(doc, page)...)
+	}
+	if rule.sources[SOURCE_OPENGRAPH] {
+		if item := extractOpenGraph(doc); item != nil {
+			items = append(items, item)
+		}
+	}
+
+	var fields data.Item
+	requests := []data.Data{}
+	if rule.template != nil {
+		templateData, templateErrors := rule.template(resp)
+		errorList = append(errorList, templateErrors...)
+		for _, d := range templateData {
+			if item, ok := d.(data.Item); ok {
+				if fields == nil {
+					fields = item
+				}
+				continue
+			}
+			requests = append(requests, d)
+		}
+	}
+
+	for _, item := range items {
+		if !rule.accept(item) {
+			continue
+		}
+		if fields != nil {
+			item = data.MergeItem(item, fields)
+		}
+		dataList = append(dataList, item)
+	}
+	if len(dataList) == 0 && !isEmptyItem(fields) {
+		dataList = append(dataList, fields)
+	}
+	dataList = append(dataList, requests...)
+	return
+}
+
+/*
+ * check whether every field of the item is nil, an empty string or an empty list
+ */
+func isEmptyItem(item data.Item) bool {
+	for _, value := range item {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			if v != "" {
+				return false
+			}
+		case []string:
+			if len(v) != 0 {
+				return false
+			}
+		case []interface{}:
+			if len(v) != 0 {
+				return false
+			}
+		case []map[string]interface{}:
+			if len(v) != 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+/*
+ * check whether the type of the item is one of the types of the rule
+ */
+func (rule *metadataRule) accept(item data.Item) bool {
+	if len(rule.types) == 0 {
+		return true
+	}
+	switch t := item[METADATA_TYPE].(type) {
+	case string:
+		return rule.types[strings.ToLower(t)]
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok && rule.types[strings.ToLower(s)] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+ * remove the schema.org prefix of the type
+ */
+func normalizeType(t string) string {
+	t = strings.TrimSpace(t)
+	for _, prefix := range typePrefixes {
+		if strings.HasPrefix(t, prefix) {
+			return strings.TrimPrefix(t, prefix)
+		}
+	}
+	return t
+}
+
+/*
+ * split the comma separated list, the empty elements are skipped
+ */
+func splitList(s string) []string {
+	list := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+/*
+ * add the value of the property, the values of a repeated property are a list
+ */
+func addProperty(item map[string]interface{}, name string, value interface{}) {
+	old, ok := item[name]
+	if !ok {
+		item[name] = value
+		return
+	}
+	if list, ok := old.([]interface{}); ok {
+		item[name] = append(list, value)
+		return
+	}
+	item[name] = []interface{}{old, value}
+}
+
+/*
+ * collapse the whitespaces into single spaces
+ */
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
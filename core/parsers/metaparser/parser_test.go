@@ -0,0 +1,219 @@
+package metaparser
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/parsertest"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+const jsonLDPage = `<html><head>
+<script type="application/ld+json">
+{"@context": "https://schema.org", "@graph": [
+  {"@type": "Product", "name": "Lamp", "offers": {"@type": "Offer", "price": 20}},
+  {"@type": "BreadcrumbList", "itemListElement": []},
+  {"@type": ["Product", "schema:Thing"], "@id": "urn:desk", "name": "Desk"}
+]}
+</script>
+<script type="application/ld+json"><!-- {"@type": "http://schema.org/Review", "author": "Tom"}; --></script>
+</head><body><h1>Lamps</h1></body></html>`
+
+const microdataPage = `<html><head>
+<meta property="og:title" content="Lamp">
+<meta property="og:type" content="product">
+<meta property="product:price:amount" content="20">
+</head><body>
+<div itemscope itemtype="https://schema.org/Product" itemid="urn:lamp">
+  <h1 itemprop="name">Lamp</h1>
+  <a itemprop="url" href="/lamp">more</a>
+  <div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+    <span itemprop="price">20</span>
+    <meta itemprop="priceCurrency" content="USD">
+    <div itemprop="seller" itemscope itemtype="https://schema.org/Organization"><span itemprop="name">Shop</span></div>
+  </div>
+  <span itemprop="color">red</span><span itemprop="color">blue</span>
+</div>
+<div itemscope itemtype="http://schema.org/Review"><span itemprop="author">Tom</span></div>
+</body></html>`
+
+func TestCompileRule(t *testing.T) {
+	compile := func(m *model.Model) *constant.YiError {
+		_, yierr := GenMetadataParser(m)
+		return yierr
+	}
+	parsertest.CheckCompile(t, compile, constant.ERR_RULE_SCHEMA, []parsertest.CompileTest{
+		{Name: "no rule", Model: &model.Model{}},
+		{Name: "types and sources", Model: &model.Model{Rule: map[string]string{"types": "Product, schema:Offer", "sources": "jsonld,microdata"}}},
+		{Name: "empty sources", Model: &model.Model{Rule: map[string]string{"sources": " , "}}},
+		{Name: "unknown source", Model: &model.Model{Rule: map[string]string{"sources": "jsonld,rdfa"}}, ErrMsg: `sources: unknown source "rdfa"`},
+		{Name: "node", Model: &model.Model{Schema: &model.Schema{Node: "div", Fields: []*model.FieldSchema{{Name: "a", Selector: "a"}}}}, ErrMsg: "Schema.node: "},
+		{Name: "carry item", Model: &model.Model{Schema: &model.Schema{CarryItem: true, Fields: []*model.FieldSchema{{Name: "a", Selector: "a"}}}}, ErrMsg: "Schema.carry_item: "},
+		{Name: "illegal css", Model: &model.Model{Schema: &model.Schema{Fields: []*model.FieldSchema{{Name: "a", Selector: "a["}}}}, ErrMsg: "fields[0].selector: "},
+		{Name: "illegal xpath", Model: &model.Model{Schema: &model.Schema{Fields: []*model.FieldSchema{{Name: "a", Selector: "xpath://a["}}}}, ErrMsg: "fields[0].selector: "},
+	})
+}
+
+func TestMetadataRuleProcess(t *testing.T) {
+	lamp := data.Item{
+		"@type":   "Product",
+		"name":    "Lamp",
+		"offers":  map[string]interface{}{"@type": "Offer", "price": json.Number("20")},
+		"@source": SOURCE_JSONLD,
+	}
+	desk := data.Item{
+		"@type":   []interface{}{"Product", "Thing"},
+		"@id":     "urn:desk",
+		"name":    "Desk",
+		"@source": SOURCE_JSONLD,
+	}
+	review := data.Item{"@type": "Review", "author": "Tom", "@source": SOURCE_JSONLD}
+	microLamp := data.Item{
+		"@type": "Product",
+		"@id":   "urn:lamp",
+		"name":  "Lamp",
+		"url":   "http://shop.example.com/lamp",
+		"offers": map[string]interface{}{
+			"@type":         "Offer",
+			"price":         "20",
+			"priceCurrency": "USD",
+			"seller":        map[string]interface{}{"@type": "Organization", "name": "Shop"},
+		},
+		"color":   []interface{}{"red", "blue"},
+		"@source": SOURCE_MICRODATA,
+	}
+	microReview := data.Item{"@type": "Review", "author": "Tom", "@source": SOURCE_MICRODATA}
+	og := data.Item{"@type": "product", "title": "Lamp", "product:price:amount": "20", "@source": SOURCE_OPENGRAPH}
+
+	tests := []struct {
+		name   string
+		model  *model.Model
+		body   string
+		items  []data.Item
+		errors int
+	}{
+		{
+			name:  "json-ld graph",
+			model: &model.Model{},
+			body:  jsonLDPage,
+			items: []data.Item{lamp, {"@type": "BreadcrumbList", "itemListElement": []interface{}{}, "@source": SOURCE_JSONLD}, desk, review},
+		},
+		{
+			name:  "json-ld graph of a type",
+			model: &model.Model{Rule: map[string]string{"types": "product"}},
+			body:  jsonLDPage,
+			items: []data.Item{lamp, desk},
+		},
+		{
+			name:  "json-ld type in a list",
+			model: &model.Model{Rule: map[string]string{"types": "https://schema.org/Thing"}},
+			body:  jsonLDPage,
+			items: []data.Item{desk},
+		},
+		{
+			name:  "json-ld nested type",
+			model: &model.Model{Rule: map[string]string{"types": "Offer"}},
+			body:  jsonLDPage,
+			items: []data.Item{},
+		},
+		{
+			name:  "microdata",
+			model: &model.Model{Rule: map[string]string{"sources": "microdata"}},
+			body:  microdataPage,
+			items: []data.Item{microLamp, microReview},
+		},
+		{
+			name:  "microdata of a type",
+			model: &model.Model{Rule: map[string]string{"types": "Review"}},
+			body:  microdataPage,
+			items: []data.Item{microReview},
+		},
+		{
+			name:  "microdata nested itemscope type",
+			model: &model.Model{Rule: map[string]string{"types": "Offer, Organization", "sources": "microdata"}},
+			body:  microdataPage,
+			items: []data.Item{},
+		},
+		{
+			name:  "opengraph",
+			model: &model.Model{Rule: map[string]string{"sources": "opengraph"}},
+			body:  microdataPage,
+			items: []data.Item{og},
+		},
+		{
+			name: "schema fields",
+			model: &model.Model{
+				Rule:   map[string]string{"types": "Product", "sources": "microdata"},
+				Schema: &model.Schema{Fields: []*model.FieldSchema{{Name: "heading", Selector: "h1"}, {Name: "name", Selector: "xpath://title"}}},
+			},
+			body:  microdataPage,
+			items: []data.Item{data.MergeItem(microLamp, data.Item{"heading": "Lamp"})},
+		},
+		{
+			name: "schema fields without metadata",
+			model: &model.Model{
+				Schema: &model.Schema{Fields: []*model.FieldSchema{{Name: "heading", Selector: "h1"}}},
+			},
+			body:  `<html><body><h1>Lamps</h1></body></html>`,
+			items: []data.Item{{"heading": "Lamps"}},
+		},
+		{
+			name: "schema fields without metadata of a type",
+			model: &model.Model{
+				Rule:   map[string]string{"types": "Event"},
+				Schema: &model.Schema{Fields: []*model.FieldSchema{{Name: "heading", Selector: "h1"}}},
+			},
+			body:  jsonLDPage,
+			items: []data.Item{{"heading": "Lamps"}},
+		},
+		{
+			name: "empty schema fields without metadata",
+			model: &model.Model{
+				Schema: &model.Schema{Fields: []*model.FieldSchema{{Name: "heading", Selector: "h2"}, {Name: "tags", Selector: "li", Extractor: model.EXTRACTOR_TEXTS}}},
+			},
+			body:  `<html><body><h1>Lamps</h1></body></html>`,
+			items: []data.Item{},
+		},
+		{
+			name:   "broken json-ld",
+			model:  &model.Model{Rule: map[string]string{"sources": "jsonld,opengraph"}},
+			body:   `<html><head><script type="application/ld+json">{"@type": "Product",</script><meta property="og:title" content="Lamp"></head></html>`,
+			items:  []data.Item{{"@type": "website", "title": "Lamp", "@source": SOURCE_OPENGRAPH}},
+			errors: 1,
+		},
+		{
+			name:  "empty document",
+			model: &model.Model{},
+			body:  "",
+			items: []data.Item{},
+		},
+		{
+			name:  "empty document with schema",
+			model: &model.Model{Schema: &model.Schema{Fields: []*model.FieldSchema{{Name: "name", Selector: "xpath://h1"}}}},
+			body:  "",
+			items: []data.Item{},
+		},
+	}
+	for _, test := range tests {
+		parser, yierr := GenMetadataParser(test.model)
+		if yierr != nil {
+			t.Fatalf("%s: GenMetadataParser fail: %s", test.name, yierr)
+		}
+		dataList, yierrs := parser(parsertest.NewResponse(t, "http://shop.example.com/lamp", parsertest.CONTENT_TYPE_HTML, []byte(test.body)))
+		if len(yierrs) != test.errors {
+			t.Errorf("%s: wrong errors: %v", test.name, yierrs)
+		}
+		items := []data.Item{}
+		for _, d := range dataList {
+			if item, ok := d.(data.Item); ok {
+				items = append(items, item)
+			}
+		}
+		if !reflect.DeepEqual(items, test.items) {
+			t.Errorf("%s: wrong items: %#v", test.name, items)
+		}
+	}
+}
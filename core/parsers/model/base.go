@@ -1,9 +1,25 @@
 package model
 
+/*
+ * parser model
+ * Name: name of the model, the requests tagged with a name are only handled by the model of the name
+ * Links: rules of link extraction of template models, WantedRegUrls is ignored if set
+ * AddQueueParser: name of the parser model handling the requests of AddQueue, and of the entries of sitemap and feed models
+ * Seeds: initial urls handled by the model, the name is required
+ * SeedForms: initial form submissions, see FormSpec, the name is required
+ * Forms: form submissions from every page handled by the model, see FormSpec
+ */
 type Model struct {
+	Name string
 	AcceptedRegUrls []string
 	WantedRegUrls []string
 	Type string
 	Rule map[string]string
+	Schema *Schema // structured rule of template models, Rule is ignored if set
 	AddQueue []string
+	Links []*LinkRule
+	AddQueueParser string
+	Seeds []string
+	SeedForms []*FormSpec
+	Forms []*FormSpec
 }
\ No newline at end of file
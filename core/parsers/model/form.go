@@ -0,0 +1,42 @@
+package model
+
+/*
+ * encodings of form submissions
+ */
+const (
+	FORM_ENCODING_URLENCODED = "urlencoded" // application/x-www-form-urlencoded, the query of GET submissions
+	FORM_ENCODING_MULTIPART  = "multipart"  // multipart/form-data
+	FORM_ENCODING_JSON       = "json"       // json object of the fields, a field of many values is a list
+)
+
+/*
+ * form submission, such as
+ *   {"method": "POST", "action": "http://example.com/search", "encoding": "json",
+ *    "fields": [{"name": "q", "values": ["apple", "pear"]}, {"name": "page", "values": ["{1-3,1}"]}]}
+ * which submits 6 requests, one per combination of the values of the fields.
+ * Method: GET or POST, the method of the form or GET by default
+ * Action: url of the submission relative to the page, the action of the form or the page by default
+ * Selector: selector of the <form> of the page, whose method, action, encoding and inputs are the defaults,
+ *           nothing is submitted if the page has no such form
+ * Fields: the fields set on the form, the values are expanded like the seeds,
+ *         such as "{1-10,1}" for 1 to 10 and "{a|b}" for a and b
+ * Encoding: one of FORM_ENCODING_*, the enctype of the form or FORM_ENCODING_URLENCODED by default
+ * Parser: name of the parser model handling the submissions,
+ *         the model of the form for SeedForms and AddQueueParser of the model for Forms by default
+ */
+type FormSpec struct {
+	Method   string       `json:"method,omitempty"`
+	Action   string       `json:"action,omitempty"`
+	Selector string       `json:"selector,omitempty"`
+	Fields   []*FormField `json:"fields,omitempty"`
+	Encoding string       `json:"encoding,omitempty"`
+	Parser   string       `json:"parser,omitempty"`
+}
+
+/*
+ * field of a form submission
+ */
+type FormField struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
@@ -0,0 +1,23 @@
+package model
+
+/*
+ * rule of link extraction, such as
+ *   {"allow": ["/item/\\d+"], "regions": ["div.list"], "parser": "item"}
+ * Allow: patterns of urls to follow, every url is allowed if empty
+ * Deny: patterns of urls to skip, checked before Allow
+ * the patterns are regexes, or globs with prefix "glob:"
+ * Regions: selectors of the regions to extract links from, the whole page if empty
+ * Selector: selector of link elements, the elements with any of Attributes by default
+ * Attributes: attributes holding the url, "href" by default, "srcset" is a list of candidates
+ * Nofollow: follow links with rel="nofollow" as well
+ * Parser: name of the parser model handling the extracted requests, any model if empty
+ */
+type LinkRule struct {
+	Allow      []string `json:"allow,omitempty"`
+	Deny       []string `json:"deny,omitempty"`
+	Regions    []string `json:"regions,omitempty"`
+	Selector   string   `json:"selector,omitempty"`
+	Attributes []string `json:"attributes,omitempty"`
+	Nofollow   bool     `json:"nofollow,omitempty"`
+	Parser     string   `json:"parser,omitempty"`
+}
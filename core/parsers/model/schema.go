@@ -0,0 +1,81 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+/*
+ * extractors of a field schema
+ */
+const (
+	EXTRACTOR_TEXT  = "text"  // text of the selected nodes
+	EXTRACTOR_HTML  = "html"  // inner html of the first selected node
+	EXTRACTOR_ATTR  = "attr"  // attribute of the first selected node
+	EXTRACTOR_TEXTS = "texts" // list of the text of every selected node
+	EXTRACTOR_HTMLS = "htmls" // list of the inner html of every selected node
+	EXTRACTOR_ATTRS = "attrs" // list of the attribute of every selected node
+	EXTRACTOR_REGEX = "regex" // regex on the text of the selected nodes, or the raw body without selector
+	EXTRACTOR_CONST = "const" // constant value
+)
+
+/*
+ * structured rule of a template model, such as
+ *   {"node": "div.item", "fields": [
+ *     {"name": "title", "selector": "h2", "filters": ["trim"]},
+ *     {"name": "link", "selector": "a", "extractor": "attr", "attribute": "href", "filters": ["url"]},
+ *     {"name": "seller", "selector": ".seller", "children": [{"name": "name", "selector": ".name"}]},
+ *     {"name": "variants", "selector": ".variant", "repeated": true, "children": [
+ *       {"name": "sku", "selector": ".sku"}, {"name": "price", "selector": ".price", "filters": ["float"]}]}
+ *   ]}
+ * Node: selector of item nodes, the page is one item if empty
+ * Links: selector of links followed when WantedRegUrls of the model is set, "a" by default
+ * CarryItem: the requests of AddQueue of the model carry the item as their parent item,
+ *            and the item isn't emitted by itself. the parent item is a part of the key
+ *            checking repeated requests, so a page shared by several items is crawled for each
+ * MergeParent: the items are merged into the parent item carried by the request (see data.MergeItem),
+ *              such as a detail model merging into the partial item of a list model with CarryItem
+ */
+type Schema struct {
+	Node        string         `json:"node,omitempty"`
+	Links       string         `json:"links,omitempty"`
+	Fields      []*FieldSchema `json:"fields"`
+	CarryItem   bool           `json:"carry_item,omitempty"`
+	MergeParent bool           `json:"merge_parent,omitempty"`
+}
+
+/*
+ * schema of an item field
+ * Extractor: one of EXTRACTOR_*, EXTRACTOR_TEXT by default
+ * Attribute: the attribute of EXTRACTOR_ATTR and EXTRACTOR_ATTRS
+ * Pattern: the regex of EXTRACTOR_REGEX
+ * Value: the value of EXTRACTOR_CONST
+ * Filters: field filters such as "trim" and "date:2006-01-02"
+ * Children: fields of a nested map extracted from the first selected node,
+ *           no extractor is allowed with children
+ * Repeated: the value is a list of nested maps, one per selected node, only allowed with children
+ */
+type FieldSchema struct {
+	Name      string         `json:"name"`
+	Selector  string         `json:"selector,omitempty"`
+	Extractor string         `json:"extractor,omitempty"`
+	Attribute string         `json:"attribute,omitempty"`
+	Pattern   string         `json:"pattern,omitempty"`
+	Value     string         `json:"value,omitempty"`
+	Filters   []string       `json:"filters,omitempty"`
+	Children  []*FieldSchema `json:"children,omitempty"`
+	Repeated  bool           `json:"repeated,omitempty"`
+}
+
+/*
+ * decode a schema from json, unknown keys are errors
+ */
+func ParseSchema(b []byte) (*Schema, error) {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	schema := &Schema{}
+	if err := decoder.Decode(schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
@@ -0,0 +1,68 @@
+package parsertest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * content types of the responses
+ */
+const (
+	CONTENT_TYPE_HTML = "text/html; charset=utf-8"
+	CONTENT_TYPE_JSON = "application/json; charset=utf-8"
+	CONTENT_TYPE_XML  = "application/xml"
+)
+
+/*
+ * new a 200 response of a GET request to the url
+ */
+func NewResponse(t testing.TB, u, contentType string, body []byte) *data.Response {
+	t.Helper()
+	httpReq, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpResp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {contentType}},
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Request:    httpReq,
+	}
+	return data.NewResponse(data.NewRequest(httpReq), httpResp)
+}
+
+/*
+ * a model to compile and the expected error message, the model compiles if ErrMsg is empty
+ */
+type CompileTest struct {
+	Name   string
+	Model  *model.Model
+	ErrMsg string
+}
+
+/*
+ * compile the model of each test, an error must have the error number and contain ErrMsg
+ */
+func CheckCompile(t *testing.T, gen func(*model.Model) *constant.YiError, errNo int, tests []CompileTest) {
+	t.Helper()
+	for _, test := range tests {
+		yierr := gen(test.Model)
+		if test.ErrMsg == "" {
+			if yierr != nil {
+				t.Errorf("%s: compile fail: %s", test.Name, yierr)
+			}
+			continue
+		}
+		if yierr == nil || yierr.ErrNo != errNo || !strings.Contains(yierr.Error(), test.ErrMsg) {
+			t.Errorf("%s: wrong error: %v", test.Name, yierr)
+		}
+	}
+}
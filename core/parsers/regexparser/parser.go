@@ -0,0 +1,22 @@
+package regexparser
+
+import (
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * generate a parser extracting items by a regex from the raw body or selected nodes
+ * the regex and the selectors in the rule are compiled once here.
+ */
+func GenRegexParser(model *model.Model) (module.ParseResponse, *constant.YiError) {
+	rule, yierr := compileRule(model.Rule)
+	if yierr != nil {
+		return nil, yierr
+	}
+	return func(resp *data.Response) ([]data.Data, []*constant.YiError) {
+		return RegexRuleProcess(model, rule, resp)
+	}, nil
+}
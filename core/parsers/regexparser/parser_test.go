@@ -0,0 +1,146 @@
+package regexparser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/parsertest"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+const listPage = `<html><body>
+<ul>
+  <li class="item">Lamp: $20 <a href="/item/1">#1</a></li>
+  <li class="item">Desk: $35 <a href="/item/2">#2</a></li>
+  <li class="other">Chair: $15</li>
+</ul>
+</body></html>`
+
+func TestCompileRule(t *testing.T) {
+	compile := func(m *model.Model) *constant.YiError {
+		_, yierr := GenRegexParser(m)
+		return yierr
+	}
+	parsertest.CheckCompile(t, compile, constant.ERR_REGEX_PATTERN, []parsertest.CompileTest{
+		{Name: "pattern", Model: &model.Model{Rule: map[string]string{"pattern": `(?P<name>\w+): \$(?P<price>\d+)`, "site": "shop"}}},
+		{Name: "no rule", Model: &model.Model{}, ErrMsg: "Missing pattern"},
+		{Name: "empty pattern", Model: &model.Model{Rule: map[string]string{"pattern": ""}}, ErrMsg: "Missing pattern"},
+		{Name: "illegal pattern", Model: &model.Model{Rule: map[string]string{"pattern": `(?P<name>\w+`}}, ErrMsg: "key: pattern"},
+		{Name: "no named group", Model: &model.Model{Rule: map[string]string{"pattern": `(\w+): \$(\d+)`}}, ErrMsg: "No named group"},
+	})
+	parsertest.CheckCompile(t, compile, constant.ERR_SELECTOR, []parsertest.CompileTest{
+		{Name: "text node", Model: &model.Model{Rule: map[string]string{"pattern": `(?P<name>\w+)`, "node": "text|li.item"}}},
+		{Name: "html node", Model: &model.Model{Rule: map[string]string{"pattern": `(?P<name>\w+)`, "node": "html|xpath://li"}}},
+		{Name: "no node type", Model: &model.Model{Rule: map[string]string{"pattern": `(?P<name>\w+)`, "node": "li"}}, ErrMsg: "Illegal node: li"},
+		{Name: "unknown node type", Model: &model.Model{Rule: map[string]string{"pattern": `(?P<name>\w+)`, "node": "attr|li"}}, ErrMsg: "Illegal node: attr|li"},
+		{Name: "illegal css", Model: &model.Model{Rule: map[string]string{"pattern": `(?P<name>\w+)`, "node": "text|li["}}, ErrMsg: "key: node"},
+		{Name: "illegal xpath", Model: &model.Model{Rule: map[string]string{"pattern": `(?P<name>\w+)`, "node": "text|xpath://li["}}, ErrMsg: "key: node"},
+	})
+}
+
+func TestRegexRuleProcess(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    *model.Model
+		items    []data.Item
+		requests []string
+	}{
+		{
+			name:  "named groups of the body",
+			model: &model.Model{Rule: map[string]string{"pattern": `(?P<name>\w+): \$(?P<price>\d+)`}},
+			items: []data.Item{
+				{"name": "Lamp", "price": "20"},
+				{"name": "Desk", "price": "35"},
+				{"name": "Chair", "price": "15"},
+			},
+			requests: []string{},
+		},
+		{
+			name:  "optional group",
+			model: &model.Model{Rule: map[string]string{"pattern": `(?P<name>\w+): \$(?P<price>\d+) ?(?:<a href="(?P<href>[^"]+)")?`}},
+			items: []data.Item{
+				{"name": "Lamp", "price": "20", "href": "/item/1"},
+				{"name": "Desk", "price": "35", "href": "/item/2"},
+				{"name": "Chair", "price": "15", "href": ""},
+			},
+			requests: []string{},
+		},
+		{
+			name:  "constants",
+			model: &model.Model{Rule: map[string]string{"pattern": `(?P<name>Lamp|Desk)`, "site": "shop", "name": "overridden"}},
+			items: []data.Item{
+				{"name": "Lamp", "site": "shop"},
+				{"name": "Desk", "site": "shop"},
+			},
+			requests: []string{},
+		},
+		{
+			name:  "text of nodes",
+			model: &model.Model{Rule: map[string]string{"pattern": `(?P<name>\w+): \$(?P<price>\d+)`, "node": "text|li.item"}},
+			items: []data.Item{
+				{"name": "Lamp", "price": "20"},
+				{"name": "Desk", "price": "35"},
+			},
+			requests: []string{},
+		},
+		{
+			name:     "html of nodes",
+			model:    &model.Model{Rule: map[string]string{"pattern": `href="(?P<href>[^"]+)"`, "node": "html|xpath://li[@class='item']"}},
+			items:    []data.Item{{"href": "/item/1"}, {"href": "/item/2"}},
+			requests: []string{},
+		},
+		{
+			name:     "no match",
+			model:    &model.Model{Rule: map[string]string{"pattern": `(?P<weight>\d+) ?kg`}},
+			items:    []data.Item{},
+			requests: []string{},
+		},
+		{
+			name: "add queue",
+			model: &model.Model{
+				Rule:           map[string]string{"pattern": `href="(?P<href>/item/(?P<id>\d+))"`},
+				AddQueue:       []string{"{$href}", "/review/{$id}"},
+				AddQueueParser: "item",
+			},
+			items: []data.Item{
+				{"href": "/item/1", "id": "1"},
+				{"href": "/item/2", "id": "2"},
+			},
+			requests: []string{
+				"http://shop.example.com/item/1", "http://shop.example.com/review/1",
+				"http://shop.example.com/item/2", "http://shop.example.com/review/2",
+			},
+		},
+	}
+	for _, test := range tests {
+		parser, yierr := GenRegexParser(test.model)
+		if yierr != nil {
+			t.Fatalf("%s: GenRegexParser fail: %s", test.name, yierr)
+		}
+		dataList, yierrs := parser(parsertest.NewResponse(t, "http://shop.example.com/list", parsertest.CONTENT_TYPE_HTML, []byte(listPage)))
+		if len(yierrs) != 0 {
+			t.Errorf("%s: errors: %v", test.name, yierrs)
+		}
+		items := []data.Item{}
+		requests := []string{}
+		for _, d := range dataList {
+			switch v := d.(type) {
+			case data.Item:
+				items = append(items, v)
+			case *data.Request:
+				requests = append(requests, v.HTTPReq().URL.String())
+				if v.Parser() != test.model.AddQueueParser {
+					t.Errorf("%s: wrong parser of the request: %q", test.name, v.Parser())
+				}
+			}
+		}
+		if !reflect.DeepEqual(items, test.items) {
+			t.Errorf("%s: wrong items: %v", test.name, items)
+		}
+		if !reflect.DeepEqual(requests, test.requests) {
+			t.Errorf("%s: wrong requests: %v", test.name, requests)
+		}
+	}
+}
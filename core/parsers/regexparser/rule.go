@@ -0,0 +1,158 @@
+package regexparser
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/templateparser"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+	"github.com/l-dandelion/yi-ants-go/lib/library/parseurl"
+	"github.com/l-dandelion/yi-ants-go/lib/library/pattern"
+	"github.com/l-dandelion/yi-ants-go/lib/utils"
+)
+
+/*
+ * reserved keys of the rule, other keys are constant item fields
+ * pattern: the regex, every match is an item whose fields are the named groups
+ * node: "text|selector" or "html|selector" to match the text or the html of every selected node,
+ *       the raw body is matched if not set
+ */
+const (
+	RULE_PATTERN = "pattern"
+	RULE_NODE    = "node"
+)
+
+/*
+ * compiled rule of a regex model
+ */
+type regexRule struct {
+	pattern   *pattern.Pattern
+	node      templateparser.Selector
+	nodeType  string
+	constants map[string]string
+}
+
+/*
+ * compile the regex and the node selector of the rule
+ */
+func compileRule(rule map[string]string) (*regexRule, *constant.YiError) {
+	expr, ok := rule[RULE_PATTERN]
+	if !ok || expr == "" {
+		return nil, constant.NewYiErrorf(constant.ERR_REGEX_PATTERN, "Missing %s in the rule.", RULE_PATTERN)
+	}
+	p, err := pattern.Compile(expr)
+	if err != nil {
+		return nil, constant.NewYiErrorf(constant.ERR_REGEX_PATTERN, "%s (key: %s)", err, RULE_PATTERN)
+	}
+	if !p.HasNames() {
+		return nil, constant.NewYiErrorf(constant.ERR_REGEX_PATTERN, "No named group in the pattern: %s", expr)
+	}
+	result := &regexRule{pattern: p, constants: map[string]string{}}
+
+	if v, ok := rule[RULE_NODE]; ok {
+		contentInfo := strings.SplitN(v, "|", 2)
+		if len(contentInfo) < 2 || (contentInfo[0] != "text" && contentInfo[0] != "html") {
+			return nil, constant.NewYiErrorf(constant.ERR_SELECTOR, "Illegal node: %s", v)
+		}
+		result.nodeType = contentInfo[0]
+		result.node, err = templateparser.CompileSelector(contentInfo[1])
+		if err != nil {
+			return nil, constant.NewYiErrorf(constant.ERR_SELECTOR, "%s (key: %s, selector: %s)", err, RULE_NODE, contentInfo[1])
+		}
+	}
+
+	for key, value := range rule {
+		if key != RULE_PATTERN && key != RULE_NODE {
+			result.constants[key] = value
+		}
+	}
+	return result, nil
+}
+
+/*
+ * parse the response according to the rule
+ * return an item per match and the follow-up requests of model.AddQueue
+ */
+func RegexRuleProcess(model *model.Model, rule *regexRule, resp *data.Response) (dataList []data.Data, errorList []*constant.YiError) {
+	dataList = []data.Data{}
+	errorList = []*constant.YiError{}
+
+	texts, yierr := rule.texts(resp)
+	if yierr != nil {
+		errorList = append(errorList, yierr)
+		return
+	}
+
+	for _, text := range texts {
+		for _, match := range rule.pattern.FindAll(text, -1) {
+			item := data.Item{}
+			for key, value := range rule.constants {
+				item[key] = value
+			}
+			for key, value := range match {
+				item[key] = value
+			}
+			dataList = append(dataList, item)
+			if len(model.AddQueue) == 0 {
+				continue
+			}
+			for _, u := range parseurl.ParseReqUrl(model.AddQueue, item) {
+				req, yierr := newRequest(resp, u)
+				if yierr != nil {
+					errorList = append(errorList, yierr)
+					continue
+				}
+				if model.AddQueueParser != "" {
+					req.SetParser(model.AddQueueParser)
+				}
+				dataList = append(dataList, req)
+			}
+		}
+	}
+	return
+}
+
+/*
+ * get the texts to match, the raw body or the text (or html) of every selected node
+ */
+func (rule *regexRule) texts(resp *data.Response) ([]string, *constant.YiError) {
+	if rule.node == nil {
+		text, err := resp.GetText()
+		if err != nil {
+			return nil, constant.NewYiErrore(constant.ERR_CRAWL_ANALYZER, err)
+		}
+		return []string{string(text)}, nil
+	}
+	doc, err := resp.GetDom()
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_CRAWL_GET_DOM, err)
+	}
+	texts := []string{}
+	rule.node.Find(doc.Selection).Each(func(i int, sel *goquery.Selection) {
+		if rule.nodeType == "html" {
+			html, _ := sel.Html()
+			texts = append(texts, html)
+		} else {
+			texts = append(texts, sel.Text())
+		}
+	})
+	return texts, nil
+}
+
+/*
+ * create a GET request of the url, which may be relative to the response url
+ */
+func newRequest(resp *data.Response, u string) (*data.Request, *constant.YiError) {
+	u, err := utils.GetComplateUrl(resp.HTTPRequest().URL, u)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_CRAWL_GET_COMPLATE_URL, err)
+	}
+	httpReq, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_CRAWL_NEW_HTTP_REQUEST, err)
+	}
+	return data.NewRequest(httpReq), nil
+}
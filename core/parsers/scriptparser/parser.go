@@ -0,0 +1,22 @@
+package scriptparser
+
+import (
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * generate a parser running the script of the model on every response
+ * the script is compiled once here, and runs in a sandbox without plugins or the go toolchain.
+ */
+func GenScriptParser(model *model.Model) (module.ParseResponse, *constant.YiError) {
+	rule, yierr := compileRule(model.Rule)
+	if yierr != nil {
+		return nil, yierr
+	}
+	return func(resp *data.Response) ([]data.Data, []*constant.YiError) {
+		return ScriptRuleProcess(model, rule, resp)
+	}, nil
+}
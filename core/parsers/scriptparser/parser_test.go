@@ -0,0 +1,250 @@
+package scriptparser
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/parsertest"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+	"github.com/l-dandelion/yi-ants-go/lib/library/script"
+)
+
+const listPage = `<html><body>
+<h1>Lamps</h1>
+<ul>
+  <li class="item" data-id="1"><a href="/item/1">Desk lamp</a> <span>$20</span></li>
+  <li class="item" data-id="2"><a href="/item/2">Floor lamp</a> <span>$35</span></li>
+</ul>
+</body></html>`
+
+func TestCompileRule(t *testing.T) {
+	compile := func(m *model.Model) *constant.YiError {
+		_, yierr := GenScriptParser(m)
+		return yierr
+	}
+	parsertest.CheckCompile(t, compile, constant.ERR_SCRIPT_COMPILE, []parsertest.CompileTest{
+		{Name: "script", Model: &model.Model{Rule: map[string]string{"script": `emit({"url": resp.url})`}}},
+		{Name: "limits", Model: &model.Model{Rule: map[string]string{"script": `emit({})`, "max_steps": "100", "timeout": "10ms", "max_len": "1024"}}},
+		{Name: "no script", Model: &model.Model{}, ErrMsg: `model.Rule["script"]`},
+		{Name: "syntax error", Model: &model.Model{Rule: map[string]string{"script": "let x = 1\nlet = 2"}}, ErrMsg: "line 2"},
+		{Name: "illegal max_steps", Model: &model.Model{Rule: map[string]string{"script": `emit({})`, "max_steps": "x"}}, ErrMsg: "max_steps: "},
+		{Name: "illegal timeout", Model: &model.Model{Rule: map[string]string{"script": `emit({})`, "timeout": "1"}}, ErrMsg: "timeout: "},
+		{Name: "illegal max_len", Model: &model.Model{Rule: map[string]string{"script": `emit({})`, "max_len": "1k"}}, ErrMsg: "max_len: "},
+	})
+}
+
+/*
+ * a followed request in the tests
+ */
+type followed struct {
+	method string
+	url    string
+	body   string
+	parser string
+}
+
+func TestScriptRuleProcess(t *testing.T) {
+	tests := []struct {
+		name        string
+		model       *model.Model
+		contentType string
+		body        string
+		items       []data.Item
+		requests    []followed
+		errMsg      string
+	}{
+		{
+			name: "response",
+			model: &model.Model{Rule: map[string]string{"script": `
+				emit({"url": resp.url, "status": resp.status, "depth": resp.depth, "parser": resp.parser,
+					"type": resp.header("Content-Type"), "missing": resp.header("X-Missing")})`}},
+			body:     listPage,
+			items:    []data.Item{{"url": "http://shop.example.com/list", "status": float64(200), "depth": float64(0), "parser": "", "type": parsertest.CONTENT_TYPE_HTML, "missing": ""}},
+			requests: []followed{},
+		},
+		{
+			name: "nodes",
+			model: &model.Model{Rule: map[string]string{"script": `
+				for n in resp.select("li.item") {
+					emit({"id": n.attr("data-id"), "name": n.text("a"), "href": n.attr("xpath:./a", "href"),
+						"price": trim(n.select("span")[0].text(), "$"), "none": n.text("em"), "html": n.html("span")})
+				}`}},
+			body: listPage,
+			items: []data.Item{
+				{"id": "1", "name": "Desk lamp", "href": "/item/1", "price": "20", "none": nil, "html": "<span>$20</span>"},
+				{"id": "2", "name": "Floor lamp", "href": "/item/2", "price": "35", "none": nil, "html": "<span>$35</span>"},
+			},
+			requests: []followed{},
+		},
+		{
+			name:     "text",
+			model:    &model.Model{Rule: map[string]string{"script": "emit({\"title\": re_find(`<h1>(.*)</h1>`, resp.text())})"}},
+			body:     listPage,
+			items:    []data.Item{{"title": "Lamps"}},
+			requests: []followed{},
+		},
+		{
+			name: "json",
+			model: &model.Model{Rule: map[string]string{"script": `
+				for x in resp.json().items { emit({"id": x.id, "tags": x.tags}) }`}},
+			contentType: parsertest.CONTENT_TYPE_JSON,
+			body:        `{"items": [{"id": 1, "tags": ["a"]}, {"id": 2, "tags": []}]}`,
+			items:       []data.Item{{"id": float64(1), "tags": []interface{}{"a"}}, {"id": float64(2), "tags": []interface{}{}}},
+			requests:    []followed{},
+		},
+		{
+			name: "follow",
+			model: &model.Model{AddQueueParser: "item", Rule: map[string]string{"script": `
+				for n in resp.select("li a") { follow(n.attr("href")) }
+				follow(" ?page=2 ", {"parser": "list"})
+				follow("/search", {"method": "post", "body": {"q": "lamp"}, "headers": {"X-Token": 1}})`}},
+			body:  listPage,
+			items: []data.Item{},
+			requests: []followed{
+				{"GET", "http://shop.example.com/item/1", "", "item"},
+				{"GET", "http://shop.example.com/item/2", "", "item"},
+				{"GET", "http://shop.example.com/list?page=2", "", "list"},
+				{"POST", "http://shop.example.com/search", "q=lamp", "item"},
+			},
+		},
+		{
+			name:     "follow without parser",
+			model:    &model.Model{Rule: map[string]string{"script": `follow("/item/1")`}},
+			body:     listPage,
+			items:    []data.Item{},
+			requests: []followed{{"GET", "http://shop.example.com/item/1", "", ""}},
+		},
+		{
+			name: "emitted item is copied",
+			model: &model.Model{Rule: map[string]string{"script": `
+				let item = {"tags": ["a"]}; emit(item); item.tags[0] = "b"; emit(item)`}},
+			body:     listPage,
+			items:    []data.Item{{"tags": []interface{}{"a"}}, {"tags": []interface{}{"b"}}},
+			requests: []followed{},
+		},
+		{
+			name:     "error after emit",
+			model:    &model.Model{Rule: map[string]string{"script": `emit({"a": 1}); follow("/next"); fail("bad page")`}},
+			body:     listPage,
+			items:    []data.Item{{"a": float64(1)}},
+			requests: []followed{{"GET", "http://shop.example.com/next", "", ""}},
+			errMsg:   "bad page",
+		},
+		{
+			name:     "emit not a map",
+			model:    &model.Model{Rule: map[string]string{"script": `emit(1)`}},
+			body:     listPage,
+			items:    []data.Item{},
+			requests: []followed{},
+			errMsg:   "item must be map",
+		},
+		{
+			name:     "illegal follow options",
+			model:    &model.Model{Rule: map[string]string{"script": `follow("/a", {"body": 1})`}},
+			body:     listPage,
+			items:    []data.Item{},
+			requests: []followed{},
+			errMsg:   "body must be string or map",
+		},
+		{
+			name:     "illegal selector",
+			model:    &model.Model{Rule: map[string]string{"script": `resp.select("li[")`}},
+			body:     listPage,
+			items:    []data.Item{},
+			requests: []followed{},
+			errMsg:   "line 1",
+		},
+		{
+			name:        "illegal json",
+			model:       &model.Model{Rule: map[string]string{"script": `resp.json()`}},
+			contentType: parsertest.CONTENT_TYPE_JSON,
+			body:        `{"items": `,
+			items:       []data.Item{},
+			requests:    []followed{},
+			errMsg:      "line 1",
+		},
+		{
+			name:     "step limit",
+			model:    &model.Model{Rule: map[string]string{"script": `emit({}); while true {}`, "max_steps": "1000"}},
+			body:     listPage,
+			items:    []data.Item{{}},
+			requests: []followed{},
+			errMsg:   script.ErrStepLimit.Error(),
+		},
+		{
+			name:     "timeout",
+			model:    &model.Model{Rule: map[string]string{"script": `while true {}`, "max_steps": "1000000000", "timeout": "10ms"}},
+			body:     listPage,
+			items:    []data.Item{},
+			requests: []followed{},
+			errMsg:   script.ErrTimeout.Error(),
+		},
+		{
+			name:     "text too long",
+			model:    &model.Model{Rule: map[string]string{"script": `resp.text()`, "max_len": "100"}},
+			body:     listPage,
+			items:    []data.Item{},
+			requests: []followed{},
+			errMsg:   script.ErrLenLimit.Error(),
+		},
+		{
+			name:        "json too long",
+			model:       &model.Model{Rule: map[string]string{"script": `resp.json()`, "max_len": "10"}},
+			contentType: parsertest.CONTENT_TYPE_JSON,
+			body:        `{"items": [1, 2, 3]}`,
+			items:       []data.Item{},
+			requests:    []followed{},
+			errMsg:      script.ErrLenLimit.Error(),
+		},
+		{
+			name:     "too many nodes",
+			model:    &model.Model{Rule: map[string]string{"script": `emit({"n": len(resp.select("li"))}); resp.select("*")`, "max_len": "2"}},
+			body:     listPage,
+			items:    []data.Item{{"n": float64(2)}},
+			requests: []followed{},
+			errMsg:   script.ErrLenLimit.Error(),
+		},
+	}
+	for _, test := range tests {
+		parser, yierr := GenScriptParser(test.model)
+		if yierr != nil {
+			t.Fatalf("%s: GenScriptParser fail: %s", test.name, yierr)
+		}
+		contentType := test.contentType
+		if contentType == "" {
+			contentType = parsertest.CONTENT_TYPE_HTML
+		}
+		dataList, yierrs := parser(parsertest.NewResponse(t, "http://shop.example.com/list", contentType, []byte(test.body)))
+		if test.errMsg == "" && len(yierrs) != 0 {
+			t.Errorf("%s: errors: %v", test.name, yierrs)
+		}
+		if test.errMsg != "" && (len(yierrs) != 1 || yierrs[0].ErrNo != constant.ERR_SCRIPT_RUN || !strings.Contains(yierrs[0].Error(), test.errMsg)) {
+			t.Errorf("%s: wrong errors: %v", test.name, yierrs)
+		}
+		items := []data.Item{}
+		requests := []followed{}
+		for _, d := range dataList {
+			switch v := d.(type) {
+			case data.Item:
+				items = append(items, v)
+			case *data.Request:
+				body, _ := ioutil.ReadAll(v.HTTPReq().Body)
+				requests = append(requests, followed{v.HTTPReq().Method, v.HTTPReq().URL.String(), string(body), v.Parser()})
+				if v.HTTPReq().Method == "POST" &&
+					(v.HTTPReq().Header.Get("Content-Type") != "application/x-www-form-urlencoded" || v.HTTPReq().Header.Get("X-Token") != "1") {
+					t.Errorf("%s: wrong headers: %v", test.name, v.HTTPReq().Header)
+				}
+			}
+		}
+		if !reflect.DeepEqual(items, test.items) {
+			t.Errorf("%s: wrong items: %#v", test.name, items)
+		}
+		if !reflect.DeepEqual(requests, test.requests) {
+			t.Errorf("%s: wrong requests: %v", test.name, requests)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package scriptparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/templateparser"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+	"github.com/l-dandelion/yi-ants-go/lib/library/script"
+	"github.com/l-dandelion/yi-ants-go/lib/utils"
+)
+
+/*
+ * keys of the rule of a script model
+ * script: the source, see package lib/library/script
+ * max_steps, timeout and max_len: the limits of a run, see script.ParseLimits
+ *
+ * the globals of the script:
+ *   resp.url resp.status resp.depth resp.parser
+ *   resp.header(name) resp.text() resp.json() resp.select(selector)
+ *   node.text([selector]) node.html([selector]) node.attr([selector, ]name) node.select(selector)
+ *   emit(item)               add an item (a map)
+ *   follow(url[, options])   add a request of the url relative to the response,
+ *                            options: {"method", "body" (string or map of form), "headers", "parser"}
+ * a selector is a css selector or a xpath selector with the prefix "xpath:".
+ * the work of resp.text(), resp.json() and the selects is done by the host out of the steps,
+ * so the body and the number of the selected nodes are checked against max_len before it.
+ * the parser of the followed requests is model.AddQueueParser if it is not in the options.
+ */
+const RULE_SCRIPT = "script"
+
+/*
+ * compiled rule of a script model
+ */
+type scriptRule struct {
+	program   *script.Program
+	limits    script.Limits
+	selectors sync.Map // expression => templateparser.Selector
+}
+
+/*
+ * compile the script and the limits of the rule
+ */
+func compileRule(rule map[string]string) (*scriptRule, *constant.YiError) {
+	src, ok := rule[RULE_SCRIPT]
+	if !ok {
+		return nil, constant.NewYiErrorf(constant.ERR_SCRIPT_COMPILE, `Can't get script from model.Rule["%s"]`, RULE_SCRIPT)
+	}
+	program, err := script.Compile(src)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_SCRIPT_COMPILE, err)
+	}
+	limits, err := script.ParseLimits(rule)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_SCRIPT_COMPILE, err)
+	}
+	return &scriptRule{program: program, limits: limits}, nil
+}
+
+/*
+ * check the length of a body or a list of nodes against the max_len of the rule
+ */
+func (rule *scriptRule) checkLen(n int) error {
+	maxLen := rule.limits.MaxLen
+	if maxLen <= 0 {
+		maxLen = script.DEFAULT_MAX_LEN
+	}
+	if n > maxLen {
+		return script.ErrLenLimit
+	}
+	return nil
+}
+
+/*
+ * get the compiled selector, which is cached by the rule
+ */
+func (rule *scriptRule) selector(expr string) (templateparser.Selector, error) {
+	if sel, ok := rule.selectors.Load(expr); ok {
+		return sel.(templateparser.Selector), nil
+	}
+	sel, err := templateparser.CompileSelector(expr)
+	if err != nil {
+		return nil, err
+	}
+	rule.selectors.Store(expr, sel)
+	return sel, nil
+}
+
+/*
+ * run the script on the response
+ * the items and requests added before an error are still returned.
+ */
+func ScriptRuleProcess(m *model.Model, rule *scriptRule, resp *data.Response) (dataList []data.Data, errorList []*constant.YiError) {
+	dataList = []data.Data{}
+	errorList = []*constant.YiError{}
+
+	emit := func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("emit needs one item")
+		}
+		item, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("item must be map")
+		}
+		copied, err := script.Copy(item)
+		if err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data.Item(copied.(map[string]interface{})))
+		return nil, nil
+	}
+	follow := func(args []interface{}) (interface{}, error) {
+		req, err := followRequest(m, resp, args)
+		if err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, req)
+		return nil, nil
+	}
+	globals := map[string]interface{}{
+		"resp":   rule.respObject(resp),
+		"emit":   script.Func(emit),
+		"follow": script.Func(follow),
+	}
+	if _, err := rule.program.Run(globals, rule.limits); err != nil {
+		errorList = append(errorList, constant.NewYiErrore(constant.ERR_SCRIPT_RUN, err))
+	}
+	return
+}
+
+/*
+ * the object of the response in the script
+ */
+func (rule *scriptRule) respObject(resp *data.Response) map[string]interface{} {
+	obj := map[string]interface{}{
+		"url":    resp.HTTPRequest().URL.String(),
+		"status": 0,
+		"depth":  resp.Depth(),
+		"parser": resp.Request().Parser(),
+		"header": script.Func(func(args []interface{}) (interface{}, error) {
+			name, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			if resp.HTTPResp() == nil {
+				return "", nil
+			}
+			return resp.HTTPResp().Header.Get(name), nil
+		}),
+		"text": script.Func(func(args []interface{}) (interface{}, error) {
+			text, err := resp.GetText()
+			if err != nil {
+				return nil, err
+			}
+			if err = rule.checkLen(len(text)); err != nil {
+				return nil, err
+			}
+			return string(text), nil
+		}),
+		"json": script.Func(func(args []interface{}) (interface{}, error) {
+			text, err := resp.GetText()
+			if err != nil {
+				return nil, err
+			}
+			// no string or list of the document is longer than the document
+			if err = rule.checkLen(len(text)); err != nil {
+				return nil, err
+			}
+			var result interface{}
+			if err = json.Unmarshal(text, &result); err != nil {
+				return nil, err
+			}
+			return result, nil
+		}),
+		"select": script.Func(func(args []interface{}) (interface{}, error) {
+			expr, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			dom, err := resp.GetDom()
+			if err != nil {
+				return nil, err
+			}
+			return rule.selectNodes(dom.Selection, expr)
+		}),
+	}
+	if resp.HTTPResp() != nil {
+		obj["status"] = resp.HTTPResp().StatusCode
+	}
+	return obj
+}
+
+/*
+ * select the nodes under the selection
+ */
+func (rule *scriptRule) selectNodes(sel *goquery.Selection, expr string) ([]interface{}, error) {
+	selector, err := rule.selector(expr)
+	if err != nil {
+		return nil, err
+	}
+	found := selector.Find(sel)
+	if err = rule.checkLen(found.Length()); err != nil {
+		return nil, err
+	}
+	nodes := []interface{}{}
+	found.Each(func(_ int, node *goquery.Selection) {
+		nodes = append(nodes, rule.nodeObject(node))
+	})
+	return nodes, nil
+}
+
+/*
+ * the object of a node in the script
+ * the optional selector of text, html and attr selects the first matched node under the node,
+ * and nil is returned if nothing is matched.
+ */
+func (rule *scriptRule) nodeObject(node *goquery.Selection) map[string]interface{} {
+	// the node itself, or the first node matched by the selector in args[0]
+	target := func(args []interface{}, withSelector bool) (*goquery.Selection, error) {
+		if !withSelector {
+			return node, nil
+		}
+		expr, err := stringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		selector, err := rule.selector(expr)
+		if err != nil {
+			return nil, err
+		}
+		sel := selector.Find(node)
+		if sel.Length() == 0 {
+			return nil, nil
+		}
+		return sel.First(), nil
+	}
+	return map[string]interface{}{
+		"text": script.Func(func(args []interface{}) (interface{}, error) {
+			sel, err := target(args, len(args) > 0)
+			if sel == nil || err != nil {
+				return nil, err
+			}
+			return sel.Text(), nil
+		}),
+		"html": script.Func(func(args []interface{}) (interface{}, error) {
+			sel, err := target(args, len(args) > 0)
+			if sel == nil || err != nil {
+				return nil, err
+			}
+			return goquery.OuterHtml(sel)
+		}),
+		"attr": script.Func(func(args []interface{}) (interface{}, error) {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("attr needs the name")
+			}
+			sel, err := target(args, len(args) > 1)
+			if sel == nil || err != nil {
+				return nil, err
+			}
+			name, err := stringArg(args, len(args)-1)
+			if err != nil {
+				return nil, err
+			}
+			if value, ok := templateparser.Attr(sel, name); ok {
+				return value, nil
+			}
+			return nil, nil
+		}),
+		"select": script.Func(func(args []interface{}) (interface{}, error) {
+			expr, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			return rule.selectNodes(node, expr)
+		}),
+	}
+}
+
+/*
+ * create the request of follow(url[, options])
+ */
+func followRequest(m *model.Model, resp *data.Response, args []interface{}) (*data.Request, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return nil, fmt.Errorf("follow needs the url and the optional options")
+	}
+	href, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	options := map[string]interface{}{}
+	if len(args) == 2 && args[1] != nil {
+		var ok bool
+		if options, ok = args[1].(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("options must be map")
+		}
+	}
+	u, err := utils.GetComplateUrl(resp.HTTPRequest().URL, strings.TrimSpace(href))
+	if err != nil {
+		return nil, err
+	}
+	method := "GET"
+	if v, ok := options["method"].(string); ok && v != "" {
+		method = strings.ToUpper(v)
+	}
+	body, contentType := "", ""
+	switch v := options["body"].(type) {
+	case nil:
+	case string:
+		body = v
+	case map[string]interface{}:
+		form := url.Values{}
+		for key, val := range v {
+			form.Set(key, fmt.Sprint(val))
+		}
+		body, contentType = form.Encode(), "application/x-www-form-urlencoded"
+	default:
+		return nil, fmt.Errorf("body must be string or map")
+	}
+	httpReq, err := http.NewRequest(method, u, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if headers, ok := options["headers"].(map[string]interface{}); ok {
+		for key, val := range headers {
+			httpReq.Header.Set(key, fmt.Sprint(val))
+		}
+	}
+	req := data.NewRequest(httpReq)
+	parser := m.AddQueueParser
+	if v, ok := options["parser"].(string); ok {
+		parser = v
+	}
+	if parser != "" {
+		req.SetParser(parser)
+	}
+	return req, nil
+}
+
+func stringArg(args []interface{}, i int) (string, error) {
+	if i >= len(args) {
+		return "", fmt.Errorf("not enough arguments")
+	}
+	s, ok := args[i].(string)
+	if !ok {
+		return "", fmt.Errorf("argument %d must be string", i+1)
+	}
+	return s, nil
+}
@@ -0,0 +1,294 @@
+package templateparser
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/l-dandelion/yi-ants-go/lib/utils"
+)
+
+/*
+ * filters of a field follow its value after FILTER_SEPARATOR, such as
+ *   "text|span.price||trim||replace:[^0-9.],||float||required"
+ * a filter is "name" or "name:args", args are separated by "," and "\," is a literal comma.
+ * built-in filters:
+ *   trim[:cutset]         trim spaces, or the characters of cutset
+ *   replace:regex,repl    replace the matches of regex with repl ($1 refers to a group)
+ *   split[:sep]           split the value into a list, sep is "," by default
+ *   join[:sep]            join the list into a string, sep is "," by default
+ *   int float bool        convert the value, an empty string becomes nil
+ *   date:layout,...       parse the value with the first matching go layout (or "unix"),
+ *                         the result is in RFC3339
+ *   url                   resolve the value against the response url
+ *   default:value         use value if the field is empty
+ *   required              fail if the field is empty
+ * filters are applied to every element of a list.
+ */
+const FILTER_SEPARATOR = "||"
+
+/*
+ * context of filters
+ */
+type filterContext struct {
+	baseURL *url.URL
+}
+
+/*
+ * compiled field filter
+ */
+type fieldFilter struct {
+	name  string
+	apply func(value interface{}, ctx *filterContext) (interface{}, error)
+}
+
+/*
+ * compile the filters of a field
+ */
+func compileFilters(specs []string) ([]fieldFilter, error) {
+	filters := []fieldFilter{}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		name, arg := spec, ""
+		hasArg := false
+		if i := strings.Index(spec, ":"); i >= 0 {
+			name, arg, hasArg = spec[:i], spec[i+1:], true
+		}
+		filter, err := newFilter(name, arg, hasArg)
+		if err != nil {
+			return nil, fmt.Errorf("illegal filter %q: %s", spec, err)
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+/*
+ * create a built-in filter
+ */
+func newFilter(name, arg string, hasArg bool) (fieldFilter, error) {
+	args := splitArgs(arg)
+	filter := fieldFilter{name: name}
+	switch name {
+	case "trim":
+		filter.apply = eachString(func(s string, ctx *filterContext) (interface{}, error) {
+			if hasArg {
+				return strings.Trim(s, args[0]), nil
+			}
+			return strings.TrimSpace(s), nil
+		})
+	case "replace":
+		if len(args) != 2 {
+			return filter, fmt.Errorf("replace requires a regex and a replacement")
+		}
+		re, err := regexp.Compile(args[0])
+		if err != nil {
+			return filter, err
+		}
+		filter.apply = eachString(func(s string, ctx *filterContext) (interface{}, error) {
+			return re.ReplaceAllString(s, args[1]), nil
+		})
+	case "split":
+		sep := ","
+		if hasArg {
+			sep = args[0]
+		}
+		filter.apply = func(value interface{}, ctx *filterContext) (interface{}, error) {
+			if s, ok := value.(string); ok {
+				if s == "" {
+					return []string{}, nil
+				}
+				return strings.Split(s, sep), nil
+			}
+			return value, nil
+		}
+	case "join":
+		sep := ","
+		if hasArg {
+			sep = args[0]
+		}
+		filter.apply = func(value interface{}, ctx *filterContext) (interface{}, error) {
+			switch list := value.(type) {
+			case []string:
+				return strings.Join(list, sep), nil
+			case []interface{}:
+				strs := make([]string, len(list))
+				for i, v := range list {
+					strs[i] = fmt.Sprint(v)
+				}
+				return strings.Join(strs, sep), nil
+			}
+			return value, nil
+		}
+	case "int":
+		filter.apply = eachString(func(s string, ctx *filterContext) (interface{}, error) {
+			if s = strings.TrimSpace(s); s == "" {
+				return nil, nil
+			}
+			return strconv.ParseInt(s, 10, 64)
+		})
+	case "float":
+		filter.apply = eachString(func(s string, ctx *filterContext) (interface{}, error) {
+			if s = strings.TrimSpace(s); s == "" {
+				return nil, nil
+			}
+			return strconv.ParseFloat(s, 64)
+		})
+	case "bool":
+		filter.apply = eachString(func(s string, ctx *filterContext) (interface{}, error) {
+			if s = strings.TrimSpace(s); s == "" {
+				return nil, nil
+			}
+			return strconv.ParseBool(s)
+		})
+	case "date":
+		if !hasArg {
+			return filter, fmt.Errorf("date requires layouts")
+		}
+		filter.apply = eachString(func(s string, ctx *filterContext) (interface{}, error) {
+			if s = strings.TrimSpace(s); s == "" {
+				return nil, nil
+			}
+			return parseDate(s, args)
+		})
+	case "url":
+		filter.apply = eachString(func(s string, ctx *filterContext) (interface{}, error) {
+			if s = strings.TrimSpace(s); s == "" || ctx.baseURL == nil {
+				return s, nil
+			}
+			return utils.GetComplateUrl(ctx.baseURL, s)
+		})
+	case "default":
+		filter.apply = func(value interface{}, ctx *filterContext) (interface{}, error) {
+			if isEmptyValue(value) {
+				return strings.Join(args, ","), nil
+			}
+			return value, nil
+		}
+	case "required":
+		filter.apply = func(value interface{}, ctx *filterContext) (interface{}, error) {
+			if isEmptyValue(value) {
+				return nil, fmt.Errorf("required field is empty")
+			}
+			return value, nil
+		}
+	default:
+		return filter, fmt.Errorf("unknown filter")
+	}
+	return filter, nil
+}
+
+/*
+ * apply the filters to the value in order
+ */
+func applyFilters(filters []fieldFilter, value interface{}, ctx *filterContext) (interface{}, error) {
+	var err error
+	for _, filter := range filters {
+		if value, err = filter.apply(value, ctx); err != nil {
+			return nil, fmt.Errorf("%s: %s", filter.name, err)
+		}
+	}
+	return value, nil
+}
+
+/*
+ * make a filter applied to a string or every string of a list
+ */
+func eachString(f func(s string, ctx *filterContext) (interface{}, error)) func(interface{}, *filterContext) (interface{}, error) {
+	return func(value interface{}, ctx *filterContext) (interface{}, error) {
+		switch v := value.(type) {
+		case string:
+			return f(v, ctx)
+		case []string:
+			result := make([]interface{}, len(v))
+			for i, s := range v {
+				r, err := f(s, ctx)
+				if err != nil {
+					return nil, err
+				}
+				result[i] = r
+			}
+			return result, nil
+		case []interface{}:
+			result := make([]interface{}, len(v))
+			for i, e := range v {
+				s, ok := e.(string)
+				if !ok {
+					result[i] = e
+					continue
+				}
+				r, err := f(s, ctx)
+				if err != nil {
+					return nil, err
+				}
+				result[i] = r
+			}
+			return result, nil
+		}
+		return value, nil
+	}
+}
+
+/*
+ * parse a date with the layouts, "unix" is a timestamp in seconds
+ */
+func parseDate(s string, layouts []string) (string, error) {
+	for _, layout := range layouts {
+		if layout == "unix" {
+			if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return time.Unix(sec, 0).UTC().Format(time.RFC3339), nil
+			}
+			continue
+		}
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(time.RFC3339), nil
+		}
+	}
+	return "", fmt.Errorf("%q does not match the layouts", s)
+}
+
+/*
+ * check whether the value is nil, an empty string, an empty list or an empty map
+ */
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	case []map[string]interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+/*
+ * split the arguments by commas, "\," is a literal comma
+ */
+func splitArgs(s string) []string {
+	args := []string{}
+	var current strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && s[i+1] == ',' {
+			current.WriteByte(',')
+			i++
+			continue
+		}
+		if s[i] == ',' {
+			args = append(args, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteByte(s[i])
+	}
+	return append(args, current.String())
+}
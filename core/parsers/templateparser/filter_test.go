@@ -0,0 +1,134 @@
+package templateparser
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+func TestApplyFilters(t *testing.T) {
+	base, _ := url.Parse("http://shop.example.com/list/lamp")
+	ctx := &filterContext{baseURL: base}
+	tests := []struct {
+		specs  []string
+		value  interface{}
+		result interface{}
+		ok     bool
+	}{
+		{[]string{"trim"}, "  lamp \n", "lamp", true},
+		{[]string{"trim:$"}, "$20$", "20", true},
+		{[]string{"replace:[^0-9.],"}, "$ 1,020.50", "1020.50", true},
+		{[]string{"replace:(\\w+)@(\\w+),$2 at $1"}, "lamp@shop", "shop at lamp", true},
+		{[]string{"replace:a\\,b,c"}, "a,b", "c", true},
+		{[]string{"split"}, "a,b,c", []string{"a", "b", "c"}, true},
+		{[]string{"split:/"}, "a/b", []string{"a", "b"}, true},
+		{[]string{"split"}, "", []string{}, true},
+		{[]string{"split", "trim"}, "a, b", []interface{}{"a", "b"}, true},
+		{[]string{"join:-"}, []string{"a", "b"}, "a-b", true},
+		{[]string{"join"}, []interface{}{"a", int64(1)}, "a,1", true},
+		{[]string{"int"}, " 42 ", int64(42), true},
+		{[]string{"int"}, "", nil, true},
+		{[]string{"int"}, "4.2", nil, false},
+		{[]string{"float"}, "20.5", 20.5, true},
+		{[]string{"float"}, []string{"1", "2.5"}, []interface{}{1.0, 2.5}, true},
+		{[]string{"bool"}, "true", true, true},
+		{[]string{"bool"}, "yes", nil, false},
+		{[]string{"date:2006-01-02"}, "2018-03-04", "2018-03-04T00:00:00Z", true},
+		{[]string{"date:2006-01-02,Jan 2 2006"}, "Mar 4 2018", "2018-03-04T00:00:00Z", true},
+		{[]string{"date:unix"}, "1520121600", "2018-03-04T00:00:00Z", true},
+		{[]string{"date:2006-01-02"}, "04/03/2018", nil, false},
+		{[]string{"url"}, "../desk?a=1", "http://shop.example.com/desk?a=1", true},
+		{[]string{"url"}, []string{"/a", "b"}, []interface{}{"http://shop.example.com/a", "http://shop.example.com/list/b"}, true},
+		{[]string{"url"}, "", "", true},
+		{[]string{"default:none"}, "", "none", true},
+		{[]string{"default:a,b"}, nil, "a,b", true},
+		{[]string{"default:none"}, "lamp", "lamp", true},
+		{[]string{"required"}, "lamp", "lamp", true},
+		{[]string{"required"}, "", nil, false},
+		{[]string{"required"}, []string{}, nil, false},
+		{[]string{"trim", "required"}, "  ", nil, false},
+		{[]string{"replace:[^0-9]+,", "int", "default:0"}, "n/a", "0", true},
+		{[]string{"trim"}, int64(1), int64(1), true},
+	}
+	for _, test := range tests {
+		filters, err := compileFilters(test.specs)
+		if err != nil {
+			t.Errorf("%v: compile fail: %s", test.specs, err)
+			continue
+		}
+		result, err := applyFilters(filters, test.value, ctx)
+		if !test.ok {
+			if err == nil {
+				t.Errorf("%v: no error of %#v", test.specs, test.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: apply fail: %s", test.specs, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, test.result) {
+			t.Errorf("%v: wrong result of %#v: %#v", test.specs, test.value, result)
+		}
+	}
+}
+
+func TestIllegalFilters(t *testing.T) {
+	tests := [][]string{
+		{"unknown"},
+		{"trim", "lower"},
+		{"replace"},
+		{"replace:a"},
+		{"replace:a,b,c"},
+		{"replace:[a-,b"},
+		{"date"},
+	}
+	for _, specs := range tests {
+		if _, err := compileFilters(specs); err == nil {
+			t.Errorf("%v: illegal filters compiled", specs)
+		}
+	}
+
+	// the error of a rule has the path of the field
+	_, yierr := GenTemplateParser(&model.Model{Rule: map[string]string{"title": "text|h1", "price": "text|.price||float:x||upper"}})
+	if yierr == nil || yierr.ErrNo != constant.ERR_RULE_SCHEMA || !strings.Contains(yierr.Error(), "fields[0].filters: ") {
+		t.Errorf("Wrong error: %v", yierr)
+	}
+}
+
+func TestFilterRule(t *testing.T) {
+	page := `<html><body>
+<div class="item"><span class="name"> Lamp </span><span class="price">$20.50</span><a href="/lamp">more</a></div>
+<div class="item"><span class="name">Desk</span><span class="price">n/a</span></div>
+<div class="item"><span class="price">$5</span></div>
+</body></html>`
+	m := &model.Model{Rule: map[string]string{
+		"node":  "array|div.item",
+		"name":  "text|.name||trim||required",
+		"price": "text|.price||replace:[^0-9.],||float",
+		"url":   "attr.href|a||url||default:none",
+	}}
+	items, _, yierrs := parse(t, m, "http://shop.example.com/list", page)
+	expected := []data.Item{
+		{"name": "Lamp", "price": 20.5, "url": "http://shop.example.com/lamp"},
+		{"name": "Desk", "price": nil, "url": "none"},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("Wrong items: %#v", items)
+	}
+	// the item without a name fails
+	if len(yierrs) != 1 || yierrs[0].ErrNo != constant.ERR_FIELD_FILTER || !strings.Contains(yierrs[0].Error(), "field: name") {
+		t.Errorf("Wrong errors: %v", yierrs)
+	}
+
+	// a failing filter of an empty document
+	items, _, yierrs = parse(t, &model.Model{Rule: map[string]string{"title": "text|h1||required"}}, "http://shop.example.com/list", "")
+	if len(items) != 0 || len(yierrs) != 1 {
+		t.Errorf("Wrong result of an empty document: %v %v", items, yierrs)
+	}
+}
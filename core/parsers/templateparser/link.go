@@ -0,0 +1,209 @@
+package templateparser
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/filter"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+	"github.com/l-dandelion/yi-ants-go/lib/utils"
+)
+
+// the default attribute of links
+const DEFAULT_LINK_ATTRIBUTE = "href"
+
+// schemes of urls never followed
+var unfollowedSchemes = []string{"javascript:", "mailto:", "tel:", "data:"}
+
+/*
+ * compiled rule of link extraction
+ */
+type linkRule struct {
+	urlFilter  *filter.URLFilter
+	regions    []Selector
+	sel        Selector
+	attributes []string
+	nofollow   bool
+	parser     string
+}
+
+/*
+ * get the link rules of the model
+ * model.WantedRegUrls is converted into a rule following every link matching them
+ * with the links selector of the schema, as it was before the link rules.
+ */
+func linkRulesOf(m *model.Model, schema *model.Schema) []*model.LinkRule {
+	if len(m.Links) > 0 || len(m.WantedRegUrls) == 0 {
+		return m.Links
+	}
+	selector := DEFAULT_LINKS_SELECTOR
+	if schema.Links != "" {
+		selector = schema.Links
+	}
+	return []*model.LinkRule{{Allow: m.WantedRegUrls, Selector: selector, Nofollow: true}}
+}
+
+/*
+ * compile the link rules
+ * an error is reported with the path of the wrong value, such as "links[0].allow[1]".
+ */
+func compileLinkRules(rules []*model.LinkRule) ([]*linkRule, *constant.YiError) {
+	result := []*linkRule{}
+	for i, rule := range rules {
+		path := fmt.Sprintf("links[%d]", i)
+		if rule == nil {
+			return nil, schemaError(path, fmt.Errorf("null link rule"))
+		}
+		compiled := &linkRule{
+			attributes: rule.Attributes,
+			nofollow:   rule.Nofollow,
+			parser:     rule.Parser,
+		}
+		if len(compiled.attributes) == 0 {
+			compiled.attributes = []string{DEFAULT_LINK_ATTRIBUTE}
+		}
+		var err error
+		if compiled.urlFilter, err = filter.Compile(rule.Allow, rule.Deny); err != nil {
+			return nil, constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "%s.%s", path, err)
+		}
+		for j, region := range rule.Regions {
+			sel, err := CompileSelector(region)
+			if err != nil {
+				return nil, schemaError(fmt.Sprintf("%s.regions[%d]", path, j), err)
+			}
+			compiled.regions = append(compiled.regions, sel)
+		}
+		selector := rule.Selector
+		if selector == "" {
+			attrs := make([]string, len(compiled.attributes))
+			for j, attr := range compiled.attributes {
+				attrs[j] = "[" + attr + "]"
+			}
+			selector = strings.Join(attrs, ",")
+		}
+		if compiled.sel, err = CompileSelector(selector); err != nil {
+			return nil, schemaError(path+".selector", err)
+		}
+		result = append(result, compiled)
+	}
+	return result, nil
+}
+
+/*
+ * extract the requests of the links on the page
+ * the fragments are removed, and a url is extracted once even if it matches several rules.
+ */
+func extractLinks(rules []*linkRule, doc *goquery.Document, resp *data.Response) (dataList []data.Data, errorList []*constant.YiError) {
+	dataList = []data.Data{}
+	errorList = []*constant.YiError{}
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		for _, href := range rule.hrefs(doc) {
+			u, err := utils.GetComplateUrl(resp.HTTPRequest().URL, href)
+			if err != nil {
+				errorList = append(errorList, constant.NewYiErrore(constant.ERR_CRAWL_GET_COMPLATE_URL, err))
+				continue
+			}
+			if i := strings.Index(u, "#"); i >= 0 {
+				u = u[:i]
+			}
+			if seen[u] || !rule.urlFilter.Allowed(u) {
+				continue
+			}
+			seen[u] = true
+			httpReq, err := http.NewRequest("GET", u, nil)
+			if err != nil {
+				errorList = append(errorList, constant.NewYiErrore(constant.ERR_CRAWL_NEW_HTTP_REQUEST, err))
+				continue
+			}
+			req := data.NewRequest(httpReq)
+			if rule.parser != "" {
+				req.SetParser(rule.parser)
+			}
+			dataList = append(dataList, req)
+		}
+	}
+	return
+}
+
+/*
+ * get the urls in the link elements of the regions
+ */
+func (rule *linkRule) hrefs(doc *goquery.Document) []string {
+	regions := doc.Selection
+	if len(rule.regions) > 0 {
+		// the nodes of an empty slice share the array of the document, which must not be overwritten
+		regions = doc.Selection.Slice(0, 0)
+		regions.Nodes = nil
+		for _, region := range rule.regions {
+			regions = regions.AddSelection(region.Find(doc.Selection))
+		}
+	}
+	hrefs := []string{}
+	rule.sel.Find(regions).Each(func(i int, sel *goquery.Selection) {
+		if !rule.nofollow && isNofollow(sel) {
+			return
+		}
+		for _, attr := range rule.attributes {
+			value, ok := attrOf(sel, attr)
+			if !ok {
+				continue
+			}
+			if attr == "srcset" {
+				hrefs = append(hrefs, parseSrcset(value)...)
+				continue
+			}
+			if value = strings.TrimSpace(value); isFollowed(value) {
+				hrefs = append(hrefs, value)
+			}
+		}
+	})
+	return hrefs
+}
+
+/*
+ * check whether the link element has rel="nofollow"
+ */
+func isNofollow(sel *goquery.Selection) bool {
+	rel, _ := sel.Attr("rel")
+	for _, token := range strings.Fields(strings.ToLower(rel)) {
+		if token == "nofollow" {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ * check whether the href is worth following
+ */
+func isFollowed(href string) bool {
+	if href == "" || strings.HasPrefix(href, "#") {
+		return false
+	}
+	lower := strings.ToLower(href)
+	for _, scheme := range unfollowedSchemes {
+		if strings.HasPrefix(lower, scheme) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+ * get the urls of a srcset, such as "a.jpg 1x, b.jpg 2x"
+ */
+func parseSrcset(srcset string) []string {
+	urls := []string{}
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 && isFollowed(fields[0]) {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
@@ -0,0 +1,160 @@
+package templateparser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/parsertest"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+const listPage = `<html><body>
+<div class="nav"><a href="/">Home</a><a href="/login">Login</a></div>
+<div class="list">
+  <a href="/item/1">One</a>
+  <a href="/item/1#reviews">One again</a>
+  <a href="item/2?ref=list">Two</a>
+  <a href="/item/3" rel="nofollow">Three</a>
+  <a href="/ad/1">Ad</a>
+  <span data-href="/item/4">Four</span>
+  <img src="/img/1.jpg" srcset="/img/1-1x.jpg 1x, /img/1-2x.jpg 2x">
+  <a href="javascript:void(0)">Script</a>
+  <a href="mailto:shop@example.com">Mail</a>
+  <a href="#top">Top</a>
+</div>
+<div class="more"><a href="/item/5">Five</a></div>
+</body></html>`
+
+func TestLinkRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		links []*model.LinkRule
+		urls  []string
+	}{
+		{
+			name:  "all",
+			links: []*model.LinkRule{{}},
+			urls: []string{
+				"http://shop.example.com/", "http://shop.example.com/login",
+				"http://shop.example.com/item/1", "http://shop.example.com/list/item/2?ref=list",
+				"http://shop.example.com/ad/1", "http://shop.example.com/item/5",
+			},
+		},
+		{
+			name:  "allow and deny",
+			links: []*model.LinkRule{{Allow: []string{"/item/"}, Deny: []string{`ref=`}}},
+			urls:  []string{"http://shop.example.com/item/1", "http://shop.example.com/item/5"},
+		},
+		{
+			name:  "glob",
+			links: []*model.LinkRule{{Allow: []string{"glob:http://shop.example.com/item/*"}}},
+			urls:  []string{"http://shop.example.com/item/1", "http://shop.example.com/item/5"},
+		},
+		{
+			name:  "regions",
+			links: []*model.LinkRule{{Regions: []string{"div.list", "xpath://div[@class='nav']"}, Allow: []string{"/item/", "/login"}}},
+			urls:  []string{"http://shop.example.com/item/1", "http://shop.example.com/list/item/2?ref=list", "http://shop.example.com/login"},
+		},
+		{
+			name:  "nofollow",
+			links: []*model.LinkRule{{Regions: []string{"div.list"}, Allow: []string{"/item/3"}, Nofollow: true}},
+			urls:  []string{"http://shop.example.com/item/3"},
+		},
+		{
+			name:  "attributes",
+			links: []*model.LinkRule{{Regions: []string{"div.list"}, Attributes: []string{"data-href", "srcset"}}},
+			urls: []string{
+				"http://shop.example.com/item/4",
+				"http://shop.example.com/img/1-1x.jpg", "http://shop.example.com/img/1-2x.jpg",
+			},
+		},
+		{
+			name:  "selector",
+			links: []*model.LinkRule{{Selector: "xpath://div[@class='more']/a"}},
+			urls:  []string{"http://shop.example.com/item/5"},
+		},
+		{
+			name: "once",
+			links: []*model.LinkRule{
+				{Allow: []string{"/item/1"}},
+				{Regions: []string{"div.list"}, Allow: []string{"/item/"}},
+			},
+			urls: []string{"http://shop.example.com/item/1", "http://shop.example.com/list/item/2?ref=list"},
+		},
+		{
+			name:  "no region",
+			links: []*model.LinkRule{{Regions: []string{"div.missing"}}},
+			urls:  []string{},
+		},
+	}
+	for _, test := range tests {
+		m := &model.Model{Links: test.links, Rule: map[string]string{"title": "text|h1"}}
+		_, urls, yierrs := parse(t, m, "http://shop.example.com/list/", listPage)
+		if len(yierrs) != 0 {
+			t.Errorf("%s: errors: %v", test.name, yierrs)
+		}
+		if !reflect.DeepEqual(urls, test.urls) {
+			t.Errorf("%s: wrong urls: %v", test.name, urls)
+		}
+	}
+}
+
+func TestLinkParser(t *testing.T) {
+	m := &model.Model{
+		Links: []*model.LinkRule{
+			{Regions: []string{"div.more"}, Parser: "item"},
+			{Regions: []string{"div.nav"}},
+		},
+		Rule: map[string]string{"title": "text|h1"},
+	}
+	parser, yierr := GenTemplateParser(m)
+	if yierr != nil {
+		t.Fatalf("GenTemplateParser fail: %s", yierr)
+	}
+	dataList, _ := parser(parsertest.NewResponse(t, "http://shop.example.com/list/", parsertest.CONTENT_TYPE_HTML, []byte(listPage)))
+	parsers := map[string]string{}
+	for _, d := range dataList {
+		if req, ok := d.(*data.Request); ok {
+			parsers[req.HTTPReq().URL.String()] = req.Parser()
+		}
+	}
+	expected := map[string]string{
+		"http://shop.example.com/item/5": "item",
+		"http://shop.example.com/":       "",
+		"http://shop.example.com/login":  "",
+	}
+	if !reflect.DeepEqual(parsers, expected) {
+		t.Errorf("Wrong parsers: %v", parsers)
+	}
+}
+
+func TestWantedRegUrls(t *testing.T) {
+	m := &model.Model{WantedRegUrls: []string{"/item/"}, Rule: map[string]string{"links": "div.more a", "title": "text|h1"}}
+	_, urls, _ := parse(t, m, "http://shop.example.com/list/", listPage)
+	if !reflect.DeepEqual(urls, []string{"http://shop.example.com/item/5"}) {
+		t.Errorf("Wrong urls: %v", urls)
+	}
+
+	// no link of an empty document
+	_, urls, yierrs := parse(t, &model.Model{Links: []*model.LinkRule{{}}, Rule: map[string]string{"title": "text|h1"}}, "http://shop.example.com/", "")
+	if len(urls) != 0 || len(yierrs) != 0 {
+		t.Errorf("Wrong result of an empty document: %v %v", urls, yierrs)
+	}
+}
+
+func TestIllegalLinkRules(t *testing.T) {
+	compile := func(m *model.Model) *constant.YiError {
+		_, yierr := GenTemplateParser(m)
+		return yierr
+	}
+	rule := map[string]string{"title": "text|h1"}
+	parsertest.CheckCompile(t, compile, constant.ERR_RULE_SCHEMA, []parsertest.CompileTest{
+		{Name: "nil", Model: &model.Model{Links: []*model.LinkRule{nil}, Rule: rule}, ErrMsg: "links[0]: "},
+		{Name: "allow", Model: &model.Model{Links: []*model.LinkRule{{}, {Allow: []string{"/item/", "(["}}}, Rule: rule}, ErrMsg: "links[1].allow[1]: "},
+		{Name: "deny", Model: &model.Model{Links: []*model.LinkRule{{Deny: []string{"regex:a(b"}}}, Rule: rule}, ErrMsg: "links[0].deny[0]: "},
+		{Name: "regions", Model: &model.Model{Links: []*model.LinkRule{{Regions: []string{"div", "xpath://div["}}}, Rule: rule}, ErrMsg: "links[0].regions[1]: "},
+		{Name: "selector", Model: &model.Model{Links: []*model.LinkRule{{Selector: "xpath://a[@href"}}, Rule: rule}, ErrMsg: "links[0].selector: "},
+	})
+}
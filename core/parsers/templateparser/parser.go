@@ -3,16 +3,30 @@ package templateparser
 import (
 	"github.com/l-dandelion/yi-ants-go/core/module"
 	"github.com/l-dandelion/yi-ants-go/core/module/data"
-	"github.com/l-dandelion/yi-ants-go/core/parsers/filter"
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
 	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
 )
 
-func GenTemplateParser(model *model.Model) module.ParseResponse {
-	return func(resp *data.Response) ([]data.Data, []*constant.YiError) {
-		if len(model.AcceptedRegUrls) > 0 && !filter.Filter(resp.HTTPRequest().URL.String(), model.AcceptedRegUrls) {
-			return nil, nil
+/*
+ * generate a parser for html responses
+ * the rule (or the legacy rule of strings) is validated and compiled once here.
+ */
+func GenTemplateParser(model *model.Model) (module.ParseResponse, *constant.YiError) {
+	schema := model.Schema
+	if schema == nil {
+		var err error
+		if schema, err = ConvertRule(model.Rule); err != nil {
+			return nil, constant.NewYiErrore(constant.ERR_RULE_SCHEMA, err)
 		}
-		return TemplateRuleProcess(model, resp)
 	}
-}
\ No newline at end of file
+	rule, yierr := compileSchema(schema)
+	if yierr != nil {
+		return nil, yierr
+	}
+	if rule.links, yierr = compileLinkRules(linkRulesOf(model, schema)); yierr != nil {
+		return nil, yierr
+	}
+	return func(resp *data.Response) ([]data.Data, []*constant.YiError) {
+		return TemplateRuleProcess(model, rule, resp)
+	}, nil
+}
@@ -1,169 +1,326 @@
 package templateparser
 
 import (
-	"encoding/json"
 	"github.com/PuerkitoBio/goquery"
-	"strings"
 	"github.com/l-dandelion/yi-ants-go/core/module/data"
-	"github.com/l-dandelion/yi-ants-go/lib/utils"
 	"net/http"
 	"github.com/l-dandelion/yi-ants-go/lib/library/parseurl"
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
 	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
-	"fmt"
+	"github.com/l-dandelion/yi-ants-go/lib/library/pattern"
 )
 
-func TemplateRuleProcess(model *model.Model, resp *data.Response) (dataList []data.Data, errorList []*constant.YiError) {
+/*
+ * reserved keys of the rule, other keys are item fields
+ * node: "array|selector" for an item per matched node, or "map" for an item of the page
+ * links: the selector of links followed when model.WantedRegUrls is set, "a" by default,
+ *        model.Links is used instead if set
+ */
+const (
+	RULE_NODE  = "node"
+	RULE_LINKS = "links"
+)
+
+// the default selector of links
+const DEFAULT_LINKS_SELECTOR = "a"
+
+/*
+ * a compiled item field
+ * sel is nil if the field is a constant or a regex field matching the raw body.
+ * pattern is the regex of a regex field.
+ * children are the fields of a nested map, or a list of nested maps if repeated.
+ */
+type templateField struct {
+	name      string
+	extractor string
+	attribute string
+	sel       Selector
+	pattern   *pattern.Pattern
+	filters   []fieldFilter
+	value     string
+	children  []templateField
+	repeated  bool
+}
+
+/*
+ * compiled rule of a template model
+ */
+type templateRule struct {
+	resultType  string
+	root        Selector
+	links       []*linkRule
+	fields      []templateField
+	needBody    bool
+	carryItem   bool
+	mergeParent bool
+}
+
+func TemplateRuleProcess(model *model.Model, rule *templateRule, resp *data.Response) (dataList []data.Data, errorList []*constant.YiError) {
 	dataList = []data.Data{}
 	errorList = []*constant.YiError{}
-	rule := model.Rule
 
 	doc, err := resp.GetDom()
 	if err != nil {
 		errorList = append(errorList, constant.NewYiErrore(constant.ERR_CRAWL_GET_DOM, err))
 		return
 	}
+	body := ""
+	if rule.needBody {
+		text, err := resp.GetText()
+		if err != nil {
+			errorList = append(errorList, constant.NewYiErrore(constant.ERR_CRAWL_ANALYZER, err))
+			return
+		}
+		body = string(text)
+	}
+	ctx := &filterContext{baseURL: resp.HTTPRequest().URL}
 
-	if len(model.WantedRegUrls) > 0 {
-		doc.Find("a").Each(func(i int, sel *goquery.Selection) {
-			href, _ := sel.Attr("href")
-			href, err = utils.GetComplateUrl(resp.HTTPRequest().URL, href)
-			if err != nil {
-				errorList = append(errorList, constant.NewYiErrore(constant.ERR_CRAWL_GET_COMPLATE_URL, err))
+	if len(rule.links) > 0 {
+		links, linkErrors := extractLinks(rule.links, doc, resp)
+		dataList = append(dataList, links...)
+		errorList = append(errorList, linkErrors...)
+	}
+
+	if rule.resultType == "array" {
+		rule.root.Find(doc.Selection).Each(func(i int, s *goquery.Selection) {
+			mdata, yierr := getMapFromDom(rule, s, body, ctx)
+			if yierr != nil {
+				errorList = append(errorList, yierr)
 				return
 			}
-			
-			httpReq, err := http.NewRequest("GET", href, nil)
-			if err != nil {
-				errorList = append(errorList, constant.NewYiErrore(constant.ERR_CRAWL_NEW_HTTP_REQUEST, err))
+			if mdata == nil {
 				return
 			}
-			dataList = append(dataList, data.NewRequest(httpReq))
-
+			itemData, yierr := emitItem(model, rule, resp, mdata)
+			dataList = append(dataList, itemData...)
+			if yierr != nil {
+				errorList = append(errorList, yierr)
+			}
 		})
 	}
 
-	resultType := "map"
-	rootSel := ""
-
-	v, ok := rule["node"]
-	if ok {
-		contentInfo := strings.Split(v, "|")
-		resultType = contentInfo[0]
-		rootSel = contentInfo[1]
+	if rule.resultType == "map" {
+		mdata, yierr := getMapFromDom(rule, doc.Selection, body, ctx)
+		if yierr != nil {
+			errorList = append(errorList, yierr)
+			return
+		}
+		if mdata == nil {
+			return
+		}
+		itemData, yierr := emitItem(model, rule, resp, mdata)
+		dataList = append(dataList, itemData...)
+		if yierr != nil {
+			errorList = append(errorList, yierr)
+		}
 	}
 
-	if resultType == "array" {
-		doc.Find(rootSel).Each(func(i int, s *goquery.Selection) {
-			mdata := getMapFromDom(rule, s)
-			if mdata == nil {
-				return
-			}
-			dataList = append(dataList, data.Item(mdata))
-			if len(model.AddQueue) > 0 {
-				urls := parseurl.ParseReqUrl(model.AddQueue, mdata)
-				fmt.Println(urls, " ", model.AddQueue, " ", mdata)
-				for _, u := range urls {
-					httpReq, err := http.NewRequest("GET", u, nil)
-					if err != nil {
-						errorList = append(errorList, constant.NewYiErrore(constant.ERR_CRAWL_ANALYZER, err))
-						return
-					}
-					dataList = append(dataList, data.NewRequest(httpReq))
-				}
-			}
+	return
+}
 
-		})
+/*
+ * get the item and the requests of AddQueue of the item
+ * the item is merged into the parent item of the request if rule.mergeParent is set,
+ * and carried by the requests instead of being emitted if rule.carryItem is set.
+ */
+func emitItem(model *model.Model, rule *templateRule, resp *data.Response, mdata map[string]interface{}) ([]data.Data, *constant.YiError) {
+	item := data.Item(mdata)
+	if rule.mergeParent {
+		if parent := resp.Request().ParentItem(); parent != nil {
+			item = data.MergeItem(parent, item)
+		}
 	}
-
-	if resultType == "map" {
-		mdata := getMapFromDom(rule, doc.Selection)
-		dataList = append(dataList, data.Item(mdata))
-		if len(model.AddQueue) > 0 {
-			urls := parseurl.ParseReqUrl(model.AddQueue, mdata)
-			for _, u := range urls {
-				httpReq, err := http.NewRequest("GET", u, nil)
-				if err != nil {
-					errorList = append(errorList, constant.NewYiErrore(constant.ERR_CRAWL_ANALYZER, err))
-					return
-				}
-				dataList = append(dataList, data.NewRequest(httpReq))
+	dataList := []data.Data{}
+	if !rule.carryItem || len(model.AddQueue) == 0 {
+		dataList = append(dataList, item)
+	}
+	if len(model.AddQueue) > 0 {
+		urls := parseurl.ParseReqUrl(model.AddQueue, item)
+		for _, u := range urls {
+			httpReq, err := http.NewRequest("GET", u, nil)
+			if err != nil {
+				return dataList, constant.NewYiErrore(constant.ERR_CRAWL_ANALYZER, err)
 			}
+			req := addQueueRequest(model, httpReq)
+			if rule.carryItem {
+				req.SetParentItem(data.MergeItem(nil, item))
+			}
+			dataList = append(dataList, req)
 		}
 	}
+	return dataList, nil
+}
 
-	return
+/*
+ * create the request of AddQueue, carrying model.AddQueueParser
+ */
+func addQueueRequest(model *model.Model, httpReq *http.Request) *data.Request {
+	req := data.NewRequest(httpReq)
+	if model.AddQueueParser != "" {
+		req.SetParser(model.AddQueueParser)
+	}
+	return req
 }
 
-func getMapFromDom(rule map[string]string, node *goquery.Selection) map[string]interface{} {
+/*
+ * get the item of the node
+ * return nil if no field is found.
+ */
+func getMapFromDom(rule *templateRule, node *goquery.Selection, body string, ctx *filterContext) (map[string]interface{}, *constant.YiError) {
+	result, isNull, yierr := getFields(rule.fields, node, body, ctx)
+	if yierr != nil || isNull {
+		return nil, yierr
+	}
+	return result, nil
+}
+
+/*
+ * get the fields of the node
+ * the named groups of the first match of a regex field are item fields,
+ * and the field itself is the first group (or the whole match) if there is no named group.
+ * the filters of a field are applied to its value (or the values of its named groups),
+ * and a failing filter fails the item.
+ * isNull is true if no field is found.
+ */
+func getFields(fields []templateField, node *goquery.Selection, body string, ctx *filterContext) (result map[string]interface{}, isNull bool, yierr *constant.YiError) {
 
-	result := make(map[string]interface{})
+	result = make(map[string]interface{})
 
-	isNull := true
+	isNull = true
 
-	for key, value := range rule {
+	for _, field := range fields {
+		key := field.name
 
-		if key == "node" {
+		if field.children != nil {
+			var child interface{}
+			s := field.sel.Find(node)
+			if !field.repeated {
+				s = s.First()
+			}
+			children := []map[string]interface{}{}
+			var childErr *constant.YiError
+			s.EachWithBreak(func(i int, sel *goquery.Selection) bool {
+				m, childNull, yierr := getFields(field.children, sel, body, ctx)
+				if yierr != nil {
+					childErr = yierr
+					return false
+				}
+				if !childNull {
+					children = append(children, m)
+				}
+				return true
+			})
+			if childErr != nil {
+				return nil, false, childErr
+			}
+			if field.repeated {
+				child = children
+			} else if len(children) > 0 {
+				child = children[0]
+			}
+			if len(children) > 0 {
+				isNull = false
+			}
+			value, err := applyFilters(field.filters, child, ctx)
+			if err != nil {
+				return nil, false, filterError(key, err)
+			}
+			result[key] = value
 			continue
 		}
 
-		rules := strings.Split(value, "|")
-		ValueType := strings.Split(rules[0], ".")
+		if field.pattern != nil {
+			text := body
+			if field.sel != nil {
+				text = field.sel.Find(node).Text()
+			}
+			if !field.pattern.HasNames() {
+				value, ok := field.pattern.FindValue(text)
+				result[key] = value
+				if ok {
+					isNull = false
+				}
+			} else {
+				for _, match := range field.pattern.FindAll(text, 1) {
+					for name, value := range match {
+						result[name] = value
+					}
+					isNull = false
+				}
+			}
+			if len(field.filters) == 0 {
+				continue
+			}
+			names := field.pattern.Names()
+			if len(names) == 0 {
+				names = []string{key}
+			}
+			for _, name := range names {
+				value, err := applyFilters(field.filters, result[name], ctx)
+				if err != nil {
+					return nil, false, filterError(name, err)
+				}
+				result[name] = value
+			}
+			continue
+		}
 
-		if len(rules) < 2 {
+		if field.sel == nil {
+			value, err := applyFilters(field.filters, field.value, ctx)
+			if err != nil {
+				return nil, false, filterError(key, err)
+			}
 			result[key] = value
 			continue
 		}
 
-		s := node.Find(rules[1])
-		switch ValueType[0] {
-		case "text":
+		s := field.sel.Find(node)
+		switch field.extractor {
+		case model.EXTRACTOR_TEXT:
 			result[key] = s.Text()
-		case "html":
+		case model.EXTRACTOR_HTML:
 			result[key], _ = s.Html()
-		case "attr":
-			if len(ValueType) < 2 {
-				continue
-			}
-			result[key], _ = s.Attr(ValueType[1])
-		case "texts":
+		case model.EXTRACTOR_ATTR:
+			result[key], _ = attrOf(s, field.attribute)
+		case model.EXTRACTOR_TEXTS:
 			arr := []string{}
 			s.Each(func(i int, sel *goquery.Selection) {
 				text := sel.Text()
 				arr = append(arr, text)
 			})
-			j, _ := json.Marshal(arr)
-			result[key] = string(j)
-		case "htmls":
+			result[key] = arr
+		case model.EXTRACTOR_HTMLS:
 			arr := []string{}
 			s.Each(func(i int, sel *goquery.Selection) {
-				html, _ := s.Html()
+				html, _ := sel.Html()
 				arr = append(arr, html)
 			})
-			j, _ := json.Marshal(arr)
-			result[key] = string(j)
-		case "attrs":
+			result[key] = arr
+		case model.EXTRACTOR_ATTRS:
 			arr := []string{}
-			attr := ""
 			s.Each(func(i int, sel *goquery.Selection) {
-				if len(ValueType) >= 2 {
-					attr, _ = sel.Attr(ValueType[1])
-					arr = append(arr, attr)
-				}
+				attr, _ := attrOf(sel, field.attribute)
+				arr = append(arr, attr)
 			})
 			result[key] = arr
-		default:
-			result[key] = value
 		}
-		res, ok := result[key].(string)
-		if ok || len(res) != 0 {
+		if field.extractor != model.EXTRACTOR_ATTRS {
 			isNull = false
 		}
-	}
 
-	if isNull == true {
-		return nil
+		value, err := applyFilters(field.filters, result[key], ctx)
+		if err != nil {
+			return nil, false, filterError(key, err)
+		}
+		result[key] = value
 	}
 
-	return result
-}
\ No newline at end of file
+	return result, isNull, nil
+}
+
+/*
+ * error of a failing filter of the field
+ */
+func filterError(name string, err error) *constant.YiError {
+	return constant.NewYiErrorf(constant.ERR_FIELD_FILTER, "%s (field: %s)", err, name)
+}
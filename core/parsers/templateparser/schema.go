@@ -0,0 +1,256 @@
+package templateparser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+	"github.com/l-dandelion/yi-ants-go/lib/library/pattern"
+)
+
+// the value type of a legacy field, such as "text" and "attr.href"
+var extractorPattern = regexp.MustCompile(`^[a-z]+(\.[\w:-]+)?$`)
+
+/*
+ * convert a legacy rule of strings into a schema
+ * node: "array|selector" or "map", links: selector, other keys are fields such as
+ *   "text|selector", "attr.href|selector", "regex.<pattern>|selector" and constants,
+ * each followed by filters after FILTER_SEPARATOR.
+ * the value type is before the first "|", and the selector is the rest,
+ * so that a xpath union such as "text|xpath://h1 | //h2" is kept.
+ * a value type like an extractor but unknown, such as "txt|h1", is an error instead of a constant,
+ * which is reported with the key, such as Rule["title"].
+ */
+func ConvertRule(rule map[string]string) (*model.Schema, error) {
+	schema := &model.Schema{Fields: []*model.FieldSchema{}}
+	if v, ok := rule[RULE_NODE]; ok {
+		contentInfo := strings.SplitN(v, "|", 2)
+		if contentInfo[0] == "array" {
+			if len(contentInfo) < 2 || strings.TrimSpace(contentInfo[1]) == "" {
+				return nil, fmt.Errorf("Rule[%q]: empty root selector", RULE_NODE)
+			}
+			schema.Node = contentInfo[1]
+		}
+	}
+	if v, ok := rule[RULE_LINKS]; ok {
+		schema.Links = v
+	}
+
+	keys := make([]string, 0, len(rule))
+	for key := range rule {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == RULE_NODE || key == RULE_LINKS {
+			continue
+		}
+		parts := strings.Split(rule[key], FILTER_SEPARATOR)
+		value := parts[0]
+		field := &model.FieldSchema{Name: key, Filters: parts[1:]}
+		schema.Fields = append(schema.Fields, field)
+
+		if strings.HasPrefix(value, model.EXTRACTOR_REGEX+".") {
+			// the selector starts at the first "|" outside the groups and classes of the pattern,
+			// so an alternation of the pattern must be grouped, such as "regex.(?:a|b)".
+			expr := strings.TrimPrefix(value, model.EXTRACTOR_REGEX+".")
+			field.Extractor = model.EXTRACTOR_REGEX
+			field.Pattern = expr
+			if i := patternEnd(expr); i < len(expr) {
+				field.Pattern = expr[:i]
+				field.Selector = strings.TrimSpace(expr[i+1:])
+				if _, err := CompileSelector(field.Selector); field.Selector != "" && err != nil {
+					return nil, fmt.Errorf("Rule[%q]: illegal selector %q, an alternation of the pattern must be grouped, such as \"regex.(?:a|b)\"", key, field.Selector)
+				}
+			}
+			continue
+		}
+
+		rules := strings.SplitN(value, "|", 2)
+		if len(rules) == 2 {
+			valueType := strings.SplitN(rules[0], ".", 2)
+			switch valueType[0] {
+			case model.EXTRACTOR_TEXT, model.EXTRACTOR_HTML, model.EXTRACTOR_ATTR,
+				model.EXTRACTOR_TEXTS, model.EXTRACTOR_HTMLS, model.EXTRACTOR_ATTRS:
+				field.Extractor = valueType[0]
+				field.Selector = rules[1]
+				if len(valueType) == 2 {
+					field.Attribute = valueType[1]
+				}
+				continue
+			}
+			if extractorPattern.MatchString(rules[0]) {
+				return nil, fmt.Errorf("Rule[%q]: unknown extractor %q", key, valueType[0])
+			}
+		}
+		field.Extractor = model.EXTRACTOR_CONST
+		field.Value = value
+	}
+	return schema, nil
+}
+
+/*
+ * compile the schema
+ * an error is reported with the path of the wrong value, such as "fields[1].children[0].selector".
+ */
+func compileSchema(schema *model.Schema) (*templateRule, *constant.YiError) {
+	result := &templateRule{resultType: "map", carryItem: schema.CarryItem, mergeParent: schema.MergeParent}
+	var err error
+	if schema.Node != "" {
+		result.resultType = "array"
+		if result.root, err = CompileSelector(schema.Node); err != nil {
+			return nil, schemaError("node", err)
+		}
+	}
+	result.fields, err = compileFields(schema.Fields, "fields", &result.needBody)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_RULE_SCHEMA, err)
+	}
+	return result, nil
+}
+
+/*
+ * compile the field schemas under the path
+ * needBody is set if any regex field matches the raw body.
+ */
+func compileFields(schemas []*model.FieldSchema, path string, needBody *bool) ([]templateField, error) {
+	fields := []templateField{}
+	names := map[string]bool{}
+	for i, schema := range schemas {
+		fieldPath := fmt.Sprintf("%s[%d]", path, i)
+		if schema == nil {
+			return nil, fmt.Errorf("%s: null field", fieldPath)
+		}
+		if schema.Name == "" {
+			return nil, fmt.Errorf("%s.name: empty name", fieldPath)
+		}
+		if names[schema.Name] {
+			return nil, fmt.Errorf("%s.name: duplicate name %q", fieldPath, schema.Name)
+		}
+		names[schema.Name] = true
+		field, err := compileField(schema, fieldPath, needBody)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, *field)
+	}
+	return fields, nil
+}
+
+/*
+ * compile a field schema
+ */
+func compileField(schema *model.FieldSchema, path string, needBody *bool) (*templateField, error) {
+	field := &templateField{
+		name:      schema.Name,
+		extractor: schema.Extractor,
+		attribute: schema.Attribute,
+		value:     schema.Value,
+	}
+	var err error
+	if field.filters, err = compileFilters(schema.Filters); err != nil {
+		return nil, fmt.Errorf("%s.filters: %s", path, err)
+	}
+	if schema.Selector != "" {
+		if field.sel, err = CompileSelector(schema.Selector); err != nil {
+			return nil, fmt.Errorf("%s.selector: %s", path, err)
+		}
+	}
+
+	if schema.Repeated && len(schema.Children) == 0 {
+		return nil, fmt.Errorf("%s.repeated: only allowed with children", path)
+	}
+	if len(schema.Children) > 0 {
+		field.repeated = schema.Repeated
+		if field.extractor != "" {
+			return nil, fmt.Errorf("%s.extractor: no extractor is allowed with children", path)
+		}
+		if field.sel == nil {
+			return nil, fmt.Errorf("%s.selector: required by children", path)
+		}
+		field.children, err = compileFields(schema.Children, path+".children", needBody)
+		if err != nil {
+			return nil, err
+		}
+		return field, nil
+	}
+
+	if field.extractor == "" {
+		field.extractor = model.EXTRACTOR_TEXT
+	}
+	switch field.extractor {
+	case model.EXTRACTOR_TEXT, model.EXTRACTOR_HTML, model.EXTRACTOR_TEXTS, model.EXTRACTOR_HTMLS:
+		if field.sel == nil {
+			return nil, fmt.Errorf("%s.selector: required by extractor %s", path, field.extractor)
+		}
+	case model.EXTRACTOR_ATTR, model.EXTRACTOR_ATTRS:
+		if field.sel == nil {
+			return nil, fmt.Errorf("%s.selector: required by extractor %s", path, field.extractor)
+		}
+		if field.attribute == "" {
+			return nil, fmt.Errorf("%s.attribute: required by extractor %s", path, field.extractor)
+		}
+	case model.EXTRACTOR_REGEX:
+		if schema.Pattern == "" {
+			return nil, fmt.Errorf("%s.pattern: required by extractor %s", path, field.extractor)
+		}
+		if field.pattern, err = pattern.Compile(schema.Pattern); err != nil {
+			return nil, fmt.Errorf("%s.pattern: %s", path, err)
+		}
+		if field.sel == nil {
+			*needBody = true
+		}
+	case model.EXTRACTOR_CONST:
+		if field.sel != nil {
+			return nil, fmt.Errorf("%s.selector: not allowed by extractor %s", path, field.extractor)
+		}
+	default:
+		return nil, fmt.Errorf("%s.extractor: unknown extractor %q", path, field.extractor)
+	}
+	return field, nil
+}
+
+/*
+ * get the index of the first "|" outside the groups and classes of the pattern,
+ * or the length of the pattern if there is no such "|"
+ */
+func patternEnd(expr string) int {
+	depth := 0
+	inClass := false
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+		case c == '[':
+			inClass = true
+			// a "]" right after "[" or "[^" is a literal
+			if strings.HasPrefix(expr[i+1:], "^") {
+				i++
+			}
+			if strings.HasPrefix(expr[i+1:], "]") {
+				i++
+			}
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '|' && depth <= 0:
+			return i
+		}
+	}
+	return len(expr)
+}
+
+/*
+ * error of the schema at the path
+ */
+func schemaError(path string, err error) *constant.YiError {
+	return constant.NewYiErrorf(constant.ERR_RULE_SCHEMA, "%s: %s", path, err)
+}
@@ -0,0 +1,140 @@
+package templateparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+func TestConvertRule(t *testing.T) {
+	tests := []struct {
+		value     string
+		extractor string
+		selector  string
+		attribute string
+		constant  string
+	}{
+		{"text|h1", model.EXTRACTOR_TEXT, "h1", "", ""},
+		{"attr.href|a.next", model.EXTRACTOR_ATTR, "a.next", "href", ""},
+		{"attrs.data-id|li", model.EXTRACTOR_ATTRS, "li", "data-id", ""},
+		{"text|xpath://h1 | //h2", model.EXTRACTOR_TEXT, "xpath://h1 | //h2", "", ""},
+		{"example", model.EXTRACTOR_CONST, "", "", "example"},
+		{"Tom | Jerry", model.EXTRACTOR_CONST, "", "", "Tom | Jerry"},
+		{"http://example.com/?a=1|2", model.EXTRACTOR_CONST, "", "", "http://example.com/?a=1|2"},
+	}
+	for _, test := range tests {
+		schema, err := ConvertRule(map[string]string{"field": test.value})
+		if err != nil {
+			t.Errorf("%s: convert fail: %s", test.value, err)
+			continue
+		}
+		field := schema.Fields[0]
+		if field.Extractor != test.extractor || field.Selector != test.selector ||
+			field.Attribute != test.attribute || field.Value != test.constant {
+			t.Errorf("%s: wrong field: %+v", test.value, field)
+		}
+	}
+}
+
+func TestIllegalRule(t *testing.T) {
+	tests := []struct {
+		rule   map[string]string
+		errMsg string
+	}{
+		{map[string]string{"title": "txt|h1"}, `Rule["title"]: unknown extractor "txt"`},
+		{map[string]string{"href": "atr.href|a"}, `Rule["href"]: unknown extractor "atr"`},
+		{map[string]string{"node": "array|", "title": "text|h1"}, `Rule["node"]: empty root selector`},
+		{map[string]string{"title": "text|h1[", "name": "text|h2"}, "fields[1].selector: "},
+		{map[string]string{"node": "array|li:unknown", "title": "text|h1"}, "node: "},
+		{map[string]string{"links": "a[href", "title": "text|h1"}, "links[0].selector: "},
+		{map[string]string{"price": "regex.(?P<a>x)|(?P<b>y)"}, `Rule["price"]: illegal selector "(?P<b>y)"`},
+		{map[string]string{"price": "regex.(?P<price>\\d+"}, "fields[0].pattern: "},
+		{map[string]string{"title": "text|h1"}, ""},
+	}
+	for _, test := range tests {
+		_, yierr := GenTemplateParser(&model.Model{Rule: test.rule, WantedRegUrls: []string{"/item/"}})
+		if test.errMsg == "" {
+			if yierr != nil {
+				t.Errorf("%v: compile fail: %s", test.rule, yierr)
+			}
+			continue
+		}
+		if yierr == nil || yierr.ErrNo != constant.ERR_RULE_SCHEMA || !strings.Contains(yierr.Error(), test.errMsg) {
+			t.Errorf("%v: wrong error: %v", test.rule, yierr)
+		}
+	}
+}
+
+func TestConvertRegexRule(t *testing.T) {
+	tests := []struct {
+		value    string
+		pattern  string
+		selector string
+	}{
+		{`regex.(?P<price>\d+)`, `(?P<price>\d+)`, ""},
+		{`regex.(?P<price>\d+)|span.price`, `(?P<price>\d+)`, "span.price"},
+		{`regex.(?:kg|g)`, `(?:kg|g)`, ""},
+		{`regex.(?P<unit>kg|g)|xpath://dd | //dt`, `(?P<unit>kg|g)`, "xpath://dd | //dt"},
+		{`regex.[|]x`, `[|]x`, ""},
+		{`regex.[]|]x|p`, `[]|]x`, "p"},
+		{`regex.\|(\d+)|p`, `\|(\d+)`, "p"},
+	}
+	for _, test := range tests {
+		schema, err := ConvertRule(map[string]string{"field": test.value})
+		if err != nil {
+			t.Errorf("%s: convert fail: %s", test.value, err)
+			continue
+		}
+		field := schema.Fields[0]
+		if field.Extractor != model.EXTRACTOR_REGEX || field.Pattern != test.pattern || field.Selector != test.selector {
+			t.Errorf("%s: wrong field: %+v", test.value, field)
+		}
+	}
+}
+
+func TestRegexRule(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  map[string]string
+		items []data.Item
+	}{
+		{
+			name:  "named groups of the body",
+			rule:  map[string]string{"price": `regex.<dd> (?P<price>\d+)\.(?P<cents>\d+) </dd>`},
+			items: []data.Item{{"price": "20", "cents": "5"}},
+		},
+		{
+			name:  "first group of a selector",
+			rule:  map[string]string{"stock": `regex.(\d+)|xpath://dt[text()='Stock']/following-sibling::dd[1]`},
+			items: []data.Item{{"stock": "3"}},
+		},
+		{
+			name:  "grouped alternation",
+			rule:  map[string]string{"kind": `regex.(?P<kind>Desk|Floor) lamp|h2`},
+			items: []data.Item{{"kind": "Desk"}},
+		},
+		{
+			name:  "filters of named groups",
+			rule:  map[string]string{"price": `regex.(?P<price> \d+\.\d+ )|dd||trim`},
+			items: []data.Item{{"price": "20.5"}},
+		},
+		{
+			name:  "no match",
+			rule:  map[string]string{"weight": `regex.(?P<weight>\d+) ?kg`},
+			items: []data.Item{},
+		},
+	}
+	for _, test := range tests {
+		items, _, yierrs := parse(t, &model.Model{Rule: test.rule}, "http://shop.example.com/lamp", productPage)
+		if len(yierrs) != 0 {
+			t.Errorf("%s: errors: %v", test.name, yierrs)
+		}
+		if !reflect.DeepEqual(items, test.items) {
+			t.Errorf("%s: wrong items: %#v", test.name, items)
+		}
+	}
+}
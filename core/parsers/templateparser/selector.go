@@ -0,0 +1,114 @@
+package templateparser
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+)
+
+// the prefix of a xpath selector, such as xpath://div[@id='main']/p[1]
+const XPATH_PREFIX = "xpath:"
+
+/*
+ * selector finding the matched nodes under a selection
+ */
+type Selector interface {
+	Find(sel *goquery.Selection) *goquery.Selection
+	String() string
+}
+
+/*
+ * compile a selector, a css selector or a xpath selector with XPATH_PREFIX
+ * both are compiled here, so an illegal selector fails the model instead of matching nothing.
+ */
+func CompileSelector(expr string) (Selector, error) {
+	if !strings.HasPrefix(expr, XPATH_PREFIX) {
+		compiled, err := cascadia.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		return &cssSelector{expr: expr, compiled: compiled}, nil
+	}
+	xpathExpr := strings.TrimSpace(strings.TrimPrefix(expr, XPATH_PREFIX))
+	compiled, err := xpath.Compile(xpathExpr)
+	if err != nil {
+		return nil, err
+	}
+	return &xpathSelector{expr: expr, compiled: compiled}, nil
+}
+
+/*
+ * css selector by goquery
+ */
+type cssSelector struct {
+	expr     string
+	compiled cascadia.Selector
+}
+
+func (s *cssSelector) Find(sel *goquery.Selection) *goquery.Selection {
+	return sel.FindMatcher(s.compiled)
+}
+
+func (s *cssSelector) String() string {
+	return s.expr
+}
+
+/*
+ * xpath selector
+ * the expression is evaluated with every node of the selection as the context node,
+ * text nodes and attributes could be selected, such as //h1/text() and //a/@href.
+ */
+type xpathSelector struct {
+	expr     string
+	compiled *xpath.Expr
+}
+
+func (s *xpathSelector) Find(sel *goquery.Selection) *goquery.Selection {
+	nodes := []*html.Node{}
+	for _, node := range sel.Nodes {
+		nodes = append(nodes, htmlquery.QuerySelectorAll(node, s.compiled)...)
+	}
+	// the nodes of an empty slice share the array of sel, which must not be overwritten
+	result := sel.Slice(0, 0)
+	result.Nodes = nil
+	return result.AddNodes(nodes...)
+}
+
+func (s *xpathSelector) String() string {
+	return s.expr
+}
+
+/*
+ * get the value of the attribute of the first node
+ * a selected attribute node (such as //a/@href) returns its own value for any name.
+ */
+func attrOf(sel *goquery.Selection, name string) (string, bool) {
+	if len(sel.Nodes) == 0 {
+		return "", false
+	}
+	if isAttrNode(sel.Nodes[0]) {
+		return sel.Nodes[0].FirstChild.Data, true
+	}
+	return sel.Attr(name)
+}
+
+/*
+ * get the value of the attribute of the first node for other parsers, see attrOf
+ */
+func Attr(sel *goquery.Selection, name string) (string, bool) {
+	return attrOf(sel, name)
+}
+
+/*
+ * check whether the node is an attribute selected by xpath
+ * htmlquery returns an element without parent whose only child is the value.
+ */
+func isAttrNode(node *html.Node) bool {
+	return node.Type == html.ElementNode && node.Parent == nil &&
+		node.FirstChild != nil && node.FirstChild == node.LastChild &&
+		node.FirstChild.Type == html.TextNode
+}
@@ -0,0 +1,186 @@
+package templateparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/model"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/parsertest"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+const productPage = `<html><body>
+<h1>Lamp</h1>
+<h2>Desk lamp</h2>
+<dl><dt>Price</dt><dd> 20.5 </dd><dt>Stock</dt><dd>3</dd></dl>
+<ul class="list">
+  <li><a href="/item/1">One</a></li>
+  <li><a href="/item/2">Two</a></li>
+  <li>None</li>
+</ul>
+<a class="next" href="?page=2">Next</a>
+</body></html>`
+
+/*
+ * parse the page by the model, return the items, the urls of the requests and the errors
+ */
+func parse(t *testing.T, m *model.Model, u, body string) ([]data.Item, []string, []*constant.YiError) {
+	parser, yierr := GenTemplateParser(m)
+	if yierr != nil {
+		t.Fatalf("GenTemplateParser fail: %s", yierr)
+	}
+	dataList, yierrs := parser(parsertest.NewResponse(t, u, parsertest.CONTENT_TYPE_HTML, []byte(body)))
+	items := []data.Item{}
+	urls := []string{}
+	for _, d := range dataList {
+		switch v := d.(type) {
+		case data.Item:
+			items = append(items, v)
+		case *data.Request:
+			urls = append(urls, v.HTTPReq().URL.String())
+		}
+	}
+	return items, urls, yierrs
+}
+
+func TestCompileSelector(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(productPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr   string
+		ok     bool
+		length int
+	}{
+		{"li a", true, 2},
+		{"xpath://li/a", true, 2},
+		{"xpath: //li/a/@href", true, 2},
+		{"xpath://dt[text()='Price']/following-sibling::dd[1]", true, 1},
+		{"xpath://h1 | //h2", true, 2},
+		{"xpath://table", true, 0},
+		{"xpath://li[", false, 0},
+		{"xpath://li/a[@href", false, 0},
+		{"xpath:", false, 0},
+		{"xpath://li/unknown::a", false, 0},
+	}
+	for _, test := range tests {
+		sel, err := CompileSelector(test.expr)
+		if !test.ok {
+			if err == nil {
+				t.Errorf("%s: illegal selector compiled", test.expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: compile fail: %s", test.expr, err)
+			continue
+		}
+		if sel.String() != test.expr {
+			t.Errorf("%s: wrong string: %s", test.expr, sel)
+		}
+		if n := sel.Find(doc.Selection).Length(); n != test.length {
+			t.Errorf("%s: wrong number of nodes: %d", test.expr, n)
+		}
+	}
+}
+
+func TestXPathRule(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  map[string]string
+		body  string
+		items []data.Item
+	}{
+		{
+			name: "map",
+			rule: map[string]string{
+				"node":  "map",
+				"title": "text|xpath://h1/text()",
+				"price": "text|xpath://dt[text()='Price']/following-sibling::dd[1]||trim",
+				"next":  "attr.href|xpath://a[@class='next']/@href",
+				"names": "texts|xpath://h1 | //h2",
+			},
+			body: productPage,
+			items: []data.Item{{
+				"title": "Lamp", "price": "20.5", "next": "?page=2", "names": []string{"Lamp", "Desk lamp"},
+			}},
+		},
+		{
+			name: "array",
+			rule: map[string]string{
+				"node": "array|xpath://ul[@class='list']/li",
+				"name": "text|xpath:./a",
+				"href": "attrs.href|xpath:./a",
+			},
+			body: productPage,
+			items: []data.Item{
+				{"name": "One", "href": []string{"/item/1"}},
+				{"name": "Two", "href": []string{"/item/2"}},
+				{"name": "", "href": []string{}},
+			},
+		},
+		{
+			name:  "array of empty document",
+			rule:  map[string]string{"node": "array|xpath://li", "name": "text|xpath:./a"},
+			body:  "",
+			items: []data.Item{},
+		},
+		{
+			name:  "map of empty document",
+			rule:  map[string]string{"title": "text|xpath://h1", "hrefs": "attrs.href|xpath://a"},
+			body:  "",
+			items: []data.Item{{"title": "", "hrefs": []string{}}},
+		},
+		{
+			name:  "only attrs of empty document",
+			rule:  map[string]string{"hrefs": "attrs.href|xpath://a"},
+			body:  "",
+			items: []data.Item{},
+		},
+	}
+	for _, test := range tests {
+		items, _, yierrs := parse(t, &model.Model{Rule: test.rule}, "http://shop.example.com/lamp", test.body)
+		if len(yierrs) != 0 {
+			t.Errorf("%s: errors: %v", test.name, yierrs)
+		}
+		if !reflect.DeepEqual(items, test.items) {
+			t.Errorf("%s: wrong items: %#v", test.name, items)
+		}
+	}
+}
+
+func TestIllegalXPathRule(t *testing.T) {
+	compile := func(m *model.Model) *constant.YiError {
+		_, yierr := GenTemplateParser(m)
+		return yierr
+	}
+	parsertest.CheckCompile(t, compile, constant.ERR_RULE_SCHEMA, []parsertest.CompileTest{
+		{
+			Name:   "node",
+			Model:  &model.Model{Rule: map[string]string{"node": "array|xpath://li[", "name": "text|a"}},
+			ErrMsg: "node: ",
+		},
+		{
+			Name:   "field",
+			Model:  &model.Model{Rule: map[string]string{"name": "text|xpath://a[@href"}},
+			ErrMsg: "fields[0].selector: ",
+		},
+		{
+			Name: "child",
+			Model: &model.Model{Schema: &model.Schema{Fields: []*model.FieldSchema{{
+				Name: "offers", Selector: "xpath://ul", Repeated: true,
+				Children: []*model.FieldSchema{{Name: "price", Selector: "xpath:./li["}},
+			}}}},
+			ErrMsg: "fields[0].children[0].selector: ",
+		},
+		{
+			Name:   "links",
+			Model:  &model.Model{WantedRegUrls: []string{".*"}, Rule: map[string]string{"links": "xpath://a[", "name": "text|a"}},
+			ErrMsg: "links[0].selector: ",
+		},
+	})
+}
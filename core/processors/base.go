@@ -2,15 +2,58 @@ package processors
 
 import (
 	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
 	"github.com/l-dandelion/yi-ants-go/core/processors/mysqlprocessor"
 	"github.com/l-dandelion/yi-ants-go/core/processors/sourceprocessor"
 	"github.com/l-dandelion/yi-ants-go/core/processors/model"
 	"github.com/l-dandelion/yi-ants-go/core/processors/consoleprocessor"
+	"github.com/l-dandelion/yi-ants-go/core/processors/scriptprocessor"
 )
 
 
-func GenProcessorsByModel(model *model.Model) ([]module.ProcessItem, *constant.YiError){
+func GenProcessorsByModel(m *model.Model) ([]module.ProcessItem, *constant.YiError){
+	processors, yierr := genProcessorsByModel(m)
+	if yierr != nil {
+		return nil, yierr
+	}
+	switch m.Provenance {
+	case "", model.PROVENANCE_KEEP:
+		return processors, nil
+	case model.PROVENANCE_STRIP:
+		for i, processor := range processors {
+			processors[i] = stripProvenance(processor)
+		}
+		return processors, nil
+	default:
+		return nil, constant.NewYiErrorf(constant.ERR_GET_PROCESSORS, "Unsupported provenance.(provenance: %s)", m.Provenance)
+	}
+}
+
+/*
+ * wrap the processor so it gets the item without the provenance
+ * the provenance is attached again to the result for the next processors.
+ */
+func stripProvenance(processor module.ProcessItem) module.ProcessItem {
+	return func(item data.Item) (data.Item, *constant.YiError) {
+		provenance, ok := item[data.PROVENANCE_KEY]
+		if !ok {
+			return processor(item)
+		}
+		stripped := item.WithoutProvenance()
+		result, yierr := processor(stripped)
+		if result == nil {
+			// the processor may modify the item in place
+			result = stripped
+		}
+		if _, exists := result[data.PROVENANCE_KEY]; !exists {
+			result[data.PROVENANCE_KEY] = provenance
+		}
+		return result, yierr
+	}
+}
+
+func genProcessorsByModel(model *model.Model) ([]module.ProcessItem, *constant.YiError){
 	switch model.Type {
 	case "mysql":
 		return []module.ProcessItem{mysqlprocessor.DefaultMysqlProcessor}, nil
@@ -18,6 +61,12 @@ func GenProcessorsByModel(model *model.Model) ([]module.ProcessItem, *constant.Y
 		return []module.ProcessItem{consoleprocessor.DefaultConsoleProcessor}, nil
 	case "source":
 		return sourceprocessor.GetSourceProcessorsFromModel(model)
+	case "script":
+		processor, yierr := scriptprocessor.GenScriptProcessor(model)
+		if yierr != nil {
+			return nil, yierr
+		}
+		return []module.ProcessItem{processor}, nil
 	default:
 		return nil, constant.NewYiErrorf(constant.ERR_UNSUPPORTED_MODEL_TYPE, "Unsupported model type.(modelType: %s)", model.Type)
 	}
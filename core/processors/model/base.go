@@ -1,6 +1,22 @@
 package model
 
+/*
+ * values of Provenance
+ * keep: the processor gets the item with the provenance (data.PROVENANCE_KEY)
+ * strip: the processor gets the item without the provenance,
+ *        which is still passed to the next processors
+ */
+const (
+	PROVENANCE_KEEP  = "keep"
+	PROVENANCE_STRIP = "strip"
+)
+
+/*
+ * processor model
+ * Provenance: PROVENANCE_KEEP by default
+ */
 type Model struct {
-	Type string
-	Rule map[string]string
-}
\ No newline at end of file
+	Type       string
+	Rule       map[string]string
+	Provenance string
+}
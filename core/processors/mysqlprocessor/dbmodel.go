@@ -5,6 +5,7 @@ import (
 	"strings"
 	"reflect"
 	"encoding/json"
+	"sort"
 )
 
 type Field struct {
@@ -28,6 +29,8 @@ func (f *Field) Sql() string{
 		sql = fmt.Sprintf("\n `%s` float NULL DEFAULT 0.0 ",f.Name)
 	case float32:
 		sql = fmt.Sprintf("\n `%s` float NULL DEFAULT 0.0 ",f.Name)
+	case bool:
+		sql = fmt.Sprintf("\n `%s` tinyint(1) NULL DEFAULT 0 ",f.Name)
 	default:
 		sql = fmt.Sprintf("\n `%s` Text",f.Name)
 	}
@@ -68,27 +71,46 @@ func (d *DBModel) InsertSql() string{
 
 
 
+/*
+ * get the values of the fields except the primary key
+ * lists and nested maps are stored as json, and other values are stored as they are.
+ */
 func (d *DBModel) InsertArgs() []interface{}{
 	args := []interface{}{}
 	for i:= 1;i< len(d.Fields);i++{
-		rv := reflect.ValueOf(d.Fields[i].Value)
+		value := d.Fields[i].Value
+		if value == nil {
+			args = append(args, nil)
+			continue
+		}
+		rv := reflect.ValueOf(value)
 		switch rv.Kind(){
-		case reflect.Array:
-		case reflect.Slice:
-			bytes,_ := json.Marshal(d.Fields[i].Value)
+		case reflect.Array, reflect.Slice, reflect.Map, reflect.Struct, reflect.Ptr:
+			bytes,_ := json.Marshal(value)
 			args = append(args,string(bytes))
-		default:
+		case reflect.String:
 			args = append(args,rv.String())
+		default:
+			args = append(args,value)
 		}
 	}
 	return args
 }
 
+/*
+ * create a model of the item, the fields are sorted by name
+ * so that items of the same fields share the insert sql.
+ */
 func NewDBModel(name string,m map[string]interface{}) *DBModel{
 	dbModel := &DBModel{Name:name,Fields:[]Field{}}
 	dbModel.Fields = append(dbModel.Fields,Field{Name:strings.ToLower("FffId"),Pk:true,Value:1})
-	for k,v := range m{
-		dbModel.Fields = append(dbModel.Fields,Field{Name:strings.ToLower(k),Pk:false,Value:v})
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		dbModel.Fields = append(dbModel.Fields,Field{Name:strings.ToLower(k),Pk:false,Value:m[k]})
 	}
 	return dbModel
 }
@@ -3,6 +3,7 @@ package mysqlprocessor
 import (
 	"testing"
 	"fmt"
+	"reflect"
 )
 
 func TestGensql(t *testing.T) {
@@ -18,3 +19,22 @@ func TestGensql(t *testing.T) {
 	fmt.Println(GenInsertModelsSql(models))
 	fmt.Println(GenInsertModelsArgs(models))
 }
+
+func TestInsertArgs(t *testing.T) {
+	model := NewDBModel("test", map[string]interface{}{
+		"title":    "t",
+		"price":    12.5,
+		"tags":     []string{"a", "b"},
+		"seller":   map[string]interface{}{"name": "bob"},
+		"variants": []map[string]interface{}{{"sku": "1"}, {"sku": "2"}},
+		"empty":    nil,
+	})
+	expected := []interface{}{nil, 12.5, `{"name":"bob"}`, `["a","b"]`, "t", `[{"sku":"1"},{"sku":"2"}]`}
+	args := model.InsertArgs()
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("Wrong args: %v", args)
+	}
+	if sql := model.InsertSql(); sql != "INSERT `test` SET `empty`=?,`price`=?,`seller`=?,`tags`=?,`title`=?,`variants`=?" {
+		t.Fatalf("Wrong sql: %s", sql)
+	}
+}
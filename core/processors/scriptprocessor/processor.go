@@ -0,0 +1,100 @@
+package scriptprocessor
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/processors/model"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+	"github.com/l-dandelion/yi-ants-go/lib/library/script"
+)
+
+/*
+ * key of the script in the rule, max_steps, timeout and max_len are the limits (see script.ParseLimits)
+ * the global "item" of the script is a copy of the item, the item itself is not changed.
+ * the result is the returned map, or "item" if nothing is returned,
+ * and fail(msg) fails the item.
+ * the numbers of the script are float64, the fields not assigned by the script keep their types.
+ */
+const RULE_SCRIPT = "script"
+
+/*
+ * generate a processor running the script of the model on every item
+ */
+func GenScriptProcessor(model *model.Model) (module.ProcessItem, *constant.YiError) {
+	src, ok := model.Rule[RULE_SCRIPT]
+	if !ok {
+		return nil, constant.NewYiErrorf(constant.ERR_SCRIPT_COMPILE, `Can't get script from model.Rule["%s"]`, RULE_SCRIPT)
+	}
+	program, err := script.Compile(src)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_SCRIPT_COMPILE, err)
+	}
+	limits, err := script.ParseLimits(model.Rule)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_SCRIPT_COMPILE, err)
+	}
+	return func(item data.Item) (data.Item, *constant.YiError) {
+		// the item of the caller is not converted, only its copies
+		original, err := script.Copy(map[string]interface{}(item))
+		if err != nil {
+			return nil, constant.NewYiErrore(constant.ERR_SCRIPT_RUN, err)
+		}
+		copied, err := script.Copy(original)
+		if err != nil {
+			return nil, constant.NewYiErrore(constant.ERR_SCRIPT_RUN, err)
+		}
+		value := script.Value(copied)
+		baseline, err := script.Copy(value)
+		if err != nil {
+			return nil, constant.NewYiErrore(constant.ERR_SCRIPT_RUN, err)
+		}
+		globals := map[string]interface{}{"item": value}
+		result, err := program.Run(globals, limits)
+		if err != nil {
+			return nil, constant.NewYiErrore(constant.ERR_SCRIPT_RUN, err)
+		}
+		if result == nil {
+			result = value
+		}
+		m, ok := result.(map[string]interface{})
+		if !ok {
+			return nil, constant.NewYiErrore(constant.ERR_SCRIPT_RUN, fmt.Errorf("result must be map"))
+		}
+		if result, err = script.Copy(m); err != nil {
+			return nil, constant.NewYiErrore(constant.ERR_SCRIPT_RUN, err)
+		}
+		restored := restore(original, baseline, result)
+		return data.Item(restored.(map[string]interface{})), nil
+	}, nil
+}
+
+/*
+ * restore the values not assigned by the script
+ * original is the copy of the item before the conversion, baseline is its converted value,
+ * and result is the value after the script. an unchanged value is the original one,
+ * so its type is kept, such as an int64 above 2^53 which is a float64 in the script.
+ */
+func restore(original, baseline, result interface{}) interface{} {
+	if reflect.DeepEqual(baseline, result) {
+		return original
+	}
+	originalMap, ok1 := original.(map[string]interface{})
+	baselineMap, ok2 := baseline.(map[string]interface{})
+	resultMap, ok3 := result.(map[string]interface{})
+	if !ok1 || !ok2 || !ok3 {
+		return result
+	}
+	restored := make(map[string]interface{}, len(resultMap))
+	for key, value := range resultMap {
+		originalValue, ok1 := originalMap[key]
+		baselineValue, ok2 := baselineMap[key]
+		if ok1 && ok2 {
+			value = restore(originalValue, baselineValue, value)
+		}
+		restored[key] = value
+	}
+	return restored
+}
@@ -0,0 +1,60 @@
+package scriptprocessor
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/processors/model"
+)
+
+func TestScriptProcessor(t *testing.T) {
+	processor, yierr := GenScriptProcessor(&model.Model{Type: "script", Rule: map[string]string{
+		"script": `item.total = item.count * 2
+item.tags[0] = "new"
+item.meta.seen = true`,
+	}})
+	if yierr != nil {
+		t.Fatalf("GenScriptProcessor fail: %s", yierr)
+	}
+	item := data.Item{
+		"id":    int64(9007199254740993),
+		"count": 3,
+		"price": json.Number("20"),
+		"tags":  []interface{}{"a", 1},
+		"meta":  map[string]interface{}{"rank": int64(1)},
+		"names": []string{"x"},
+	}
+	result, yierr := processor(item)
+	if yierr != nil {
+		t.Fatalf("Process fail: %s", yierr)
+	}
+
+	// the item of the caller is not changed
+	expected := data.Item{
+		"id":    int64(9007199254740993),
+		"count": 3,
+		"price": json.Number("20"),
+		"tags":  []interface{}{"a", 1},
+		"meta":  map[string]interface{}{"rank": int64(1)},
+		"names": []string{"x"},
+	}
+	if !reflect.DeepEqual(item, expected) {
+		t.Fatalf("The item is changed: %#v", item)
+	}
+
+	// the fields not assigned keep their types
+	expected = data.Item{
+		"id":    int64(9007199254740993),
+		"count": 3,
+		"price": json.Number("20"),
+		"total": 6.0,
+		"tags":  []interface{}{"new", 1.0},
+		"meta":  map[string]interface{}{"rank": int64(1), "seen": true},
+		"names": []string{"x"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("Wrong result: %#v", result)
+	}
+}
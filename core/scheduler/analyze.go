@@ -54,7 +54,11 @@ func (sched *myScheduler) analyzeOne(resp *data.Response) {
 	if sched.canceled() {
 		return
 	}
-	analyzer := sched.analyzer
+	analyzer, yierr := sched.getAnalyzer()
+	if yierr != nil {
+		sched.sendError(yierr)
+		return
+	}
 	dataList, yierrs := analyzer.Analyze(resp)
 	if dataList != nil {
 		for _, mdata := range dataList {
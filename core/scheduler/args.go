@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/filter"
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
 )
 
@@ -18,6 +19,9 @@ type Args interface {
 type RequestArgs struct {
 	AcceptedDomains []string `json:"accepted_primary_domains"` //accepted domains
 	MaxDepth        uint32   `json:"max_depth"`                //max crawl depth
+	AllowedUrls     []string `json:"allowed_urls"`             //patterns of urls to crawl, every url if empty
+	DeniedUrls      []string `json:"denied_urls"`              //patterns of urls not to crawl, prior to AllowedUrls
+	HostlessSchemes []string `json:"hostless_schemes"`         //schemes whose urls without host are crawled, such as "file"
 }
 
 /*
@@ -27,9 +31,23 @@ func (args *RequestArgs) Check() *constant.YiError {
 	if args.AcceptedDomains == nil {
 		return constant.NewYiErrorf(constant.ERR_ARGS, "Nil accepted domains")
 	}
+	if _, yierr := args.URLFilter(); yierr != nil {
+		return yierr
+	}
 	return nil
 }
 
+/*
+ * compile the url filter of AllowedUrls and DeniedUrls
+ */
+func (args *RequestArgs) URLFilter() (*filter.URLFilter, *constant.YiError) {
+	urlFilter, err := filter.Compile(args.AllowedUrls, args.DeniedUrls)
+	if err != nil {
+		return nil, constant.NewYiErrorf(constant.ERR_ARGS, "Illegal url pattern: %s", err)
+	}
+	return urlFilter, nil
+}
+
 /*
  * check whether it is same as anthor
  */
@@ -43,6 +61,10 @@ func (args *RequestArgs) Same(anthor *RequestArgs) bool {
 	if len(args.AcceptedDomains) != len(anthor.AcceptedDomains) {
 		return false
 	}
+	if !sameStrings(args.AllowedUrls, anthor.AllowedUrls) || !sameStrings(args.DeniedUrls, anthor.DeniedUrls) ||
+		!sameStrings(args.HostlessSchemes, anthor.HostlessSchemes) {
+		return false
+	}
 	if anthor.AcceptedDomains != nil {
 		for i, acceptedDomain := range anthor.AcceptedDomains {
 			if args.AcceptedDomains[i] != acceptedDomain {
@@ -53,6 +75,21 @@ func (args *RequestArgs) Same(anthor *RequestArgs) bool {
 	return true
 }
 
+/*
+ * check whether the string lists are the same
+ */
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 /*
  * implementation of interface Args
  */
@@ -100,25 +137,90 @@ func (args *DataArgs) Check() *constant.YiError {
 
 /*
  * implementation of interface Args
+ * Downloader, Analyzer and Pipeline are single instances,
+ * Downloaders, Analyzers and Pipelines are additional instances,
+ * all of them are registered into the registrar of the scheduler,
+ * and one instance is picked by score for every task.
  */
 type ModuleArgs struct {
-	Downloader module.Downloader //downloader
-	Analyzer   module.Analyzer   //analyzer
-	Pipeline   module.Pipeline   //pipeline
+	Downloader  module.Downloader   //downloader
+	Analyzer    module.Analyzer     //analyzer
+	Pipeline    module.Pipeline     //pipeline
+	Downloaders []module.Downloader //additional downloaders
+	Analyzers   []module.Analyzer   //additional analyzers
+	Pipelines   []module.Pipeline   //additional pipelines
 }
 
 /*
  * check whether the module args is vaild.
  */
 func (args *ModuleArgs) Check() *constant.YiError {
-	if args.Downloader == nil {
+	for _, d := range args.Downloaders {
+		if d == nil {
+			return constant.NewYiErrorf(constant.ERR_SCHEDULER_ARGS, "Nil downloader in downloaders.")
+		}
+	}
+	for _, a := range args.Analyzers {
+		if a == nil {
+			return constant.NewYiErrorf(constant.ERR_SCHEDULER_ARGS, "Nil analyzer in analyzers.")
+		}
+	}
+	for _, p := range args.Pipelines {
+		if p == nil {
+			return constant.NewYiErrorf(constant.ERR_SCHEDULER_ARGS, "Nil pipeline in pipelines.")
+		}
+	}
+	if args.Downloader == nil && len(args.Downloaders) == 0 {
 		return constant.NewYiErrorf(constant.ERR_SCHEDULER_ARGS, "Nil downloader.")
 	}
-	if args.Analyzer == nil {
+	if args.Analyzer == nil && len(args.Analyzers) == 0 {
 		return constant.NewYiErrorf(constant.ERR_SCHEDULER_ARGS, "Nil analyzer.")
 	}
-	if args.Pipeline == nil {
+	if args.Pipeline == nil && len(args.Pipelines) == 0 {
 		return constant.NewYiErrorf(constant.ERR_SCHEDULER_ARGS, "Nil pipeline.")
 	}
 	return nil
 }
+
+/*
+ * get all module instances of the args
+ */
+func (args *ModuleArgs) Modules() []module.Module {
+	modules := []module.Module{}
+	if args.Downloader != nil {
+		modules = append(modules, args.Downloader)
+	}
+	for _, d := range args.Downloaders {
+		modules = append(modules, d)
+	}
+	if args.Analyzer != nil {
+		modules = append(modules, args.Analyzer)
+	}
+	for _, a := range args.Analyzers {
+		modules = append(modules, a)
+	}
+	if args.Pipeline != nil {
+		modules = append(modules, args.Pipeline)
+	}
+	for _, p := range args.Pipelines {
+		modules = append(modules, p)
+	}
+	return modules
+}
+
+/*
+ * create a registrar and register all module instances of the args
+ */
+func (args *ModuleArgs) Registrar() (module.Registrar, *constant.YiError) {
+	registrar := module.NewRegistrar()
+	for _, m := range args.Modules() {
+		if _, ok := registrar.GetAll()[m.ID()]; ok {
+			return nil, constant.NewYiErrorf(constant.ERR_SCHEDULER_ARGS,
+				"Repeated module instance.(mid: %s)", m.ID())
+		}
+		if yierr := registrar.Register(m); yierr != nil {
+			return nil, yierr
+		}
+	}
+	return registrar, nil
+}
@@ -15,7 +15,7 @@ import (
 	"github.com/l-dandelion/yi-ants-go/core/module/local/downloader"
 	"github.com/l-dandelion/yi-ants-go/core/module/local/pipeline"
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
-	"github.com/l-dandelion/yi-ants-go/lib/library/log"
+	log "github.com/sirupsen/logrus"
 )
 
 func TestArgsRequest(t *testing.T) {
@@ -158,7 +158,7 @@ func genSimpleDownloaders(number int8, reuseMID bool, snGen module.SNGenerator,
 	} else if number == -1 { // 不合规的MID。
 		mid := module.MID(fmt.Sprintf("A%d", snGen.Get()))
 		httpClient := &http.Client{}
-		d, err := downloader.New(mid, httpClient, nil)
+		d, err := downloader.New(mid, httpClient, nil, 1)
 		if err != nil {
 			t.Fatalf("An error occurs when creating a downloader: %s (mid: %s, httpClient: %#v)",
 				err, mid, httpClient)
@@ -172,7 +172,7 @@ func genSimpleDownloaders(number int8, reuseMID bool, snGen module.SNGenerator,
 			mid = module.MID(fmt.Sprintf("D%d", snGen.Get()))
 		}
 		httpClient := &http.Client{}
-		d, err := downloader.New(mid, httpClient, nil)
+		d, err := downloader.New(mid, httpClient, nil, 1)
 		if err != nil {
 			t.Fatalf("An error occurs when creating a downloader: %s (mid: %s, httpClient: %#v)",
 				err, mid, httpClient)
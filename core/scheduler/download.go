@@ -4,6 +4,7 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/l-dandelion/yi-ants-go/core/module"
 	"github.com/l-dandelion/yi-ants-go/core/module/data"
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
 )
@@ -28,10 +29,15 @@ func (sched *myScheduler) download() {
 				log.Warnln("The request buffer pool was closed. Break request reception.")
 				return
 			}
-			sched.downloader.Add()
+			downloader, yierr := sched.getDownloader()
+			if yierr != nil {
+				sched.sendError(yierr)
+				continue
+			}
+			downloader.Add()
 			downloaderPool.Add()
-			go func(datum interface{}) {
-				defer sched.downloader.Done()
+			go func(downloader module.Downloader, datum interface{}) {
+				defer downloader.Done()
 				defer downloaderPool.Done()
 				req, ok := datum.(*data.Request)
 				if !ok {
@@ -40,8 +46,8 @@ func (sched *myScheduler) download() {
 					sched.sendError(yierr)
 					return
 				}
-				sched.downloadOne(req)
-			}(datum)
+				sched.downloadOne(downloader, req)
+			}(downloader, datum)
 		}
 		downloaderPool.Wait()
 	}()
@@ -50,14 +56,13 @@ func (sched *myScheduler) download() {
 /*
  * download one
  */
-func (sched *myScheduler) downloadOne(req *data.Request) {
+func (sched *myScheduler) downloadOne(downloader module.Downloader, req *data.Request) {
 	if req == nil {
 		return
 	}
 	if sched.canceled() {
 		return
 	}
-	downloader := sched.downloader
 	resp, yierr := downloader.Download(req)
 	if resp != nil {
 		sched.sendResp(resp)
@@ -50,7 +50,11 @@ func (sched *myScheduler) pickOne(item data.Item) {
 	if sched.canceled() {
 		return
 	}
-	pipeline := sched.pipeline
+	pipeline, yierr := sched.getPipeline()
+	if yierr != nil {
+		sched.sendError(yierr)
+		return
+	}
 	errs := pipeline.Send(item)
 	if errs != nil {
 		for _, err := range errs {
@@ -3,10 +3,12 @@ package scheduler
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/l-dandelion/yi-ants-go/core/module"
 	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/filter"
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
 	"github.com/l-dandelion/yi-ants-go/lib/library/buffer"
 	"github.com/l-dandelion/yi-ants-go/lib/library/cmap"
@@ -52,6 +54,8 @@ type myScheduler struct {
 	name              string
 	maxDepth          uint32             // the max crawl depth
 	acceptedDomainMap cmap.ConcurrentMap // accepted domain
+	hostlessSchemes   map[string]bool    // schemes whose urls without host are accepted
+	urlFilter         *filter.URLFilter  // filter of urls to crawl
 	reqBufferPool     buffer.Pool        // request buffer pool
 	respBufferPool    buffer.Pool        // response buffer pool
 	itemBufferPool    buffer.Pool        // item buffer pool
@@ -62,9 +66,7 @@ type myScheduler struct {
 	status            int8               // running status
 	statusLock        sync.RWMutex       // status lock
 	summary           SchedSummary       // sched summary
-	downloader        module.Downloader  // downloader
-	analyzer          module.Analyzer    // analyzer
-	pipeline          module.Pipeline    // pipeline
+	registrar         module.Registrar   // registrar of downloaders, analyzers and pipelines
 	distributeQeueu   buffer.Pool
 }
 
@@ -128,13 +130,28 @@ func (sched *myScheduler) Init(
 	}
 	log.Infof("-- Accepted primay domains: %v", requestArgs.AcceptedDomains)
 
+	sched.hostlessSchemes = map[string]bool{}
+	for _, scheme := range requestArgs.HostlessSchemes {
+		sched.hostlessSchemes[strings.ToLower(scheme)] = true
+	}
+	log.Infof("-- Schemes without host: %v", requestArgs.HostlessSchemes)
+
+	if sched.urlFilter, yierr = requestArgs.URLFilter(); yierr != nil {
+		return
+	}
+	log.Infof("-- Allowed urls: %v, denied urls: %v", requestArgs.AllowedUrls, requestArgs.DeniedUrls)
+
 	sched.urlMap, _ = cmap.NewConcurrentMap(16, nil)
 	log.Infof("-- URL map: length: %d, concurrency: %d", sched.urlMap.Len(), sched.urlMap.Concurrency())
 
 	//initialize modules
-	sched.downloader = moduleArgs.Downloader
-	sched.analyzer = moduleArgs.Analyzer
-	sched.pipeline = moduleArgs.Pipeline
+	log.Info("Register modules...")
+	if sched.registrar, yierr = moduleArgs.Registrar(); yierr != nil {
+		return
+	}
+	for mid := range sched.registrar.GetAll() {
+		log.Infof("-- Module: %s", mid)
+	}
 
 	sched.initBufferPool(dataArgs)
 	sched.resetContext()
@@ -183,6 +200,9 @@ func (sched *myScheduler) Start(initialReqs []*data.Request) (yierr *constant.Yi
 
 	for _, req := range initialReqs {
 		httpReq := req.HTTPReq()
+		if httpReq.Host == "" {
+			continue
+		}
 		log.Infof("-- Host: %s", httpReq.Host)
 		var primaryDomain string
 		primaryDomain, yierr = getPrimaryDomain(httpReq.Host)
@@ -332,10 +352,10 @@ func (sched *myScheduler) ErrorChan() <-chan *constant.YiError {
  * check whether all are finished.
  */
 func (sched *myScheduler) Idle() bool {
-	if sched.downloader.HandlingNumber() > 0 ||
-		sched.analyzer.HandlingNumber() > 0 ||
-		sched.pipeline.HandlingNumber() > 0 {
-		return false
+	for _, m := range sched.registrar.GetAll() {
+		if m.HandlingNumber() > 0 {
+			return false
+		}
 	}
 	if sched.reqBufferPool.Total() > 0 ||
 		sched.respBufferPool.Total() > 0 ||
@@ -460,6 +480,54 @@ func (sched *myScheduler) Status() int8 {
 	return sched.status
 }
 
+/*
+ * get a downloader with minimum score
+ */
+func (sched *myScheduler) getDownloader() (module.Downloader, *constant.YiError) {
+	m, yierr := sched.registrar.Get(module.TYPE_DOWNLOADER)
+	if yierr != nil {
+		return nil, yierr
+	}
+	downloader, ok := m.(module.Downloader)
+	if !ok {
+		return nil, constant.NewYiErrorf(constant.ERR_CRAWL_SCHEDULER,
+			"Incorrect downloader type: %T (mid: %s)", m, m.ID())
+	}
+	return downloader, nil
+}
+
+/*
+ * get an analyzer with minimum score
+ */
+func (sched *myScheduler) getAnalyzer() (module.Analyzer, *constant.YiError) {
+	m, yierr := sched.registrar.Get(module.TYPE_ANALYZER)
+	if yierr != nil {
+		return nil, yierr
+	}
+	analyzer, ok := m.(module.Analyzer)
+	if !ok {
+		return nil, constant.NewYiErrorf(constant.ERR_CRAWL_SCHEDULER,
+			"Incorrect analyzer type: %T (mid: %s)", m, m.ID())
+	}
+	return analyzer, nil
+}
+
+/*
+ * get a pipeline with minimum score
+ */
+func (sched *myScheduler) getPipeline() (module.Pipeline, *constant.YiError) {
+	m, yierr := sched.registrar.Get(module.TYPE_PIPELINE)
+	if yierr != nil {
+		return nil, yierr
+	}
+	pipeline, ok := m.(module.Pipeline)
+	if !ok {
+		return nil, constant.NewYiErrorf(constant.ERR_CRAWL_SCHEDULER,
+			"Incorrect pipeline type: %T (mid: %s)", m, m.ID())
+	}
+	return pipeline, nil
+}
+
 /*
  * set distribute queue
  */
@@ -471,12 +539,12 @@ func (sched *myScheduler) SetDistributeQueue(pool buffer.Pool) {
  * sign request
  */
 func (sched *myScheduler) SignRequest(req *data.Request) {
-	sched.urlMap.Put(req.HTTPReq().URL.String(), struct{}{})
+	sched.urlMap.Put(req.UniqueKey(), struct{}{})
 }
 
 /*
  * check whether it has request
  */
 func (sched *myScheduler) HasRequest(req *data.Request) bool {
-	return sched.urlMap.Get(req.HTTPReq().URL.String()) != nil
+	return sched.urlMap.Get(req.UniqueKey()) != nil
 }
\ No newline at end of file
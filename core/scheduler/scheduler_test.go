@@ -3,13 +3,16 @@ package scheduler
 import (
 	"net/http"
 	"runtime"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/l-dandelion/yi-ants-go/core/module"
 	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/module/local/downloader"
+	"github.com/l-dandelion/yi-ants-go/core/module/local/fetcher"
 	"github.com/l-dandelion/yi-ants-go/lib/library/cmap"
-	"github.com/l-dandelion/yi-ants-go/lib/library/log"
+	log "github.com/sirupsen/logrus"
 )
 
 // snGen 代表序列号生成器。
@@ -428,3 +431,62 @@ func TestSendItem(t *testing.T) {
 		t.Fatalf("It still can send item with closed buffer!")
 	}
 }
+
+func TestSchedPick(t *testing.T) {
+	// the downloader with minimum score is picked
+	scores := map[module.MID]uint64{"D1": 3, "D2": 1, "D3": 2}
+	downloaders := []module.Downloader{}
+	for _, mid := range []module.MID{"D1", "D2", "D3"} {
+		score := scores[mid]
+		d, yierr := downloader.New(mid, &http.Client{}, func(module.Counts) uint64 { return score }, 1)
+		if yierr != nil {
+			t.Fatalf("An error occurs when creating a downloader: %s", yierr)
+		}
+		downloaders = append(downloaders, d)
+	}
+	moduleArgs := genSimpleModuleArgs(t)
+	moduleArgs.Downloader = nil
+	moduleArgs.Downloaders = downloaders
+	sched := &myScheduler{}
+	if yierr := sched.Init(genRequestArgs([]string{}, 0), genDataArgs(10, 2, 1), moduleArgs); yierr != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", yierr)
+	}
+	d, yierr := sched.getDownloader()
+	if yierr != nil {
+		t.Fatalf("An error occurs when getting a downloader: %s", yierr)
+	}
+	if d.ID() != "D2" {
+		t.Fatalf("Inconsistent downloader: expected: %s, actual: %s", "D2", d.ID())
+	}
+
+	// a downloader handling a request has a higher score, so the next task goes to another one
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	fetcher.Register("block", fetcher.FetcherFunc(func(httpReq *http.Request) (*http.Response, error) {
+		started <- struct{}{}
+		<-release
+		return fetcher.NewResponse(httpReq, http.StatusOK, nil, nil), nil
+	}))
+	defer fetcher.Default().Unregister("block")
+	moduleArgs = genSimpleModuleArgs(t)
+	moduleArgs.Downloaders = genSimpleDownloaders(1, false, module.NewSNGenerator(2, 0), t)
+	sched = &myScheduler{}
+	if yierr := sched.Init(genRequestArgs([]string{}, 0), genDataArgs(10, 2, 1), moduleArgs); yierr != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", yierr)
+	}
+	picked := map[module.MID]bool{}
+	for i := 0; i < 2; i++ {
+		d, yierr := sched.getDownloader()
+		if yierr != nil {
+			t.Fatalf("An error occurs when getting a downloader: %s", yierr)
+		}
+		picked[d.ID()] = true
+		httpReq, _ := http.NewRequest("GET", "block://example.com/"+strconv.Itoa(i), nil)
+		go d.Download(data.NewRequest(httpReq))
+		<-started
+	}
+	close(release)
+	if len(picked) != 2 {
+		t.Fatalf("The tasks are not split among the downloaders: %v", picked)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/l-dandelion/yi-ants-go/core/module/data"
+	"github.com/l-dandelion/yi-ants-go/core/module/local/fetcher"
 	log "github.com/sirupsen/logrus"
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
 )
@@ -29,19 +30,22 @@ func (sched *myScheduler) sendReq(req *data.Request) bool {
 		return false
 	}
 	scheme := strings.ToLower(reqURL.Scheme)
-	if scheme != "http" && scheme != "https" {
-		//log.Warnf("Ignore the request! Its URL scheme is %q, but should be %q or %q. (URL: %s)\n", scheme, "http", "https", reqURL)
+	if !fetcher.Supported(scheme) {
+		//log.Warnf("Ignore the request! Its URL scheme %q has no registered fetcher. (URL: %s)\n", scheme, reqURL)
 		return false
 	}
-	if v := sched.urlMap.Get(reqURL.String()); v != nil {
+	if v := sched.urlMap.Get(req.UniqueKey()); v != nil {
 		//log.Warnf("Ignore the request! Its URL is repeated. (URL: %s)\n", reqURL)
 		return false
 	}
-	pd, _ := getPrimaryDomain(httpReq.Host)
-	if sched.acceptedDomainMap.Get(pd) == nil {
+	if !sched.acceptedHost(httpReq.Host, scheme) {
 		//log.Warnf("Ignore the request! Its host %q is not in accepted primary domain map. (URL: %s)\n", httpReq.Host, reqURL)
 		return false
 	}
+	if sched.urlFilter != nil && !sched.urlFilter.Allowed(reqURL.String()) {
+		//log.Warnf("Ignore the request! Its URL is not allowed. (URL: %s)\n", reqURL)
+		return false
+	}
 	if req.Depth() > sched.maxDepth {
 		//log.Warnf("Ignore the request! Its depth %d is greater than %d. (URL: %s)\n", req.Depth(), sched.maxDepth, reqURL)
 		return false
@@ -56,14 +60,14 @@ func (sched *myScheduler) sendReq(req *data.Request) bool {
 				log.Infof("Send req distribute, %v Size: %d", req, sched.distributeQeueu.Total())
 			}
 		}(req)
-		sched.urlMap.Put(reqURL.String(), struct{}{})
+		sched.urlMap.Put(req.UniqueKey(), struct{}{})
 	} else {
 		go func(req *data.Request) {
 			if err := sched.reqBufferPool.Put(req); err != nil {
 				log.Warnln("The request buffer pool was closed. Ignore request sending.")
 			}
 		}(req)
-		sched.urlMap.Put(reqURL.String(), struct{}{})
+		sched.urlMap.Put(req.UniqueKey(), struct{}{})
 	}
 	return true
 }
@@ -102,19 +106,22 @@ func (sched *myScheduler) SendReq(req *data.Request) bool {
 		return false
 	}
 	scheme := strings.ToLower(reqURL.Scheme)
-	if scheme != "http" && scheme != "https" {
-		//log.Warnf("Ignore the request! Its URL scheme is %q, but should be %q or %q. (URL: %s)\n", scheme, "http", "https", reqURL)
+	if !fetcher.Supported(scheme) {
+		//log.Warnf("Ignore the request! Its URL scheme %q has no registered fetcher. (URL: %s)\n", scheme, reqURL)
 		return false
 	}
-	if v := sched.urlMap.Get(reqURL.String()); v != nil {
+	if v := sched.urlMap.Get(req.UniqueKey()); v != nil {
 		//log.Warnf("Ignore the request! Its URL is repeated. (URL: %s)\n", reqURL)
 		return false
 	}
-	pd, _ := getPrimaryDomain(httpReq.Host)
-	if sched.acceptedDomainMap.Get(pd) == nil {
+	if !sched.acceptedHost(httpReq.Host, scheme) {
 		//log.Warnf("Ignore the request! Its host %q is not in accepted primary domain map. (URL: %s)\n", httpReq.Host, reqURL)
 		return false
 	}
+	if sched.urlFilter != nil && !sched.urlFilter.Allowed(reqURL.String()) {
+		//log.Warnf("Ignore the request! Its URL is not allowed. (URL: %s)\n", reqURL)
+		return false
+	}
 	if req.Depth() > sched.maxDepth {
 		//log.Warnf("Ignore the request! Its depth %d is greater than %d. (URL: %s)\n", req.Depth(), sched.maxDepth, reqURL)
 		return false
@@ -127,7 +134,7 @@ func (sched *myScheduler) SendReq(req *data.Request) bool {
 			log.Infof("Accept request: %v Size: %d", req, sched.distributeQeueu.Total())
 		}
 	}(req)
-	sched.urlMap.Put(reqURL.String(), struct{}{})
+	sched.urlMap.Put(req.UniqueKey(), struct{}{})
 	return true
 }
 
@@ -184,3 +191,15 @@ func (sched *myScheduler)sendError(yierr *constant.YiError) bool {
 	}(yierr)
 	return true
 }
+
+/*
+ * check whether the host is in the accepted primary domains
+ * requests without host (such as file://) are only accepted if their scheme is in hostless schemes.
+ */
+func (sched *myScheduler) acceptedHost(host string, scheme string) bool {
+	if host == "" {
+		return sched.hostlessSchemes[scheme]
+	}
+	pd, _ := getPrimaryDomain(host)
+	return sched.acceptedDomainMap.Get(pd) != nil
+}
@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"encoding/json"
+	"sort"
 
 	"github.com/l-dandelion/yi-ants-go/core/module"
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
@@ -28,13 +29,19 @@ type mySchedSummary struct {
  * get scheduler summary struct
  */
 func (ss *mySchedSummary) Struct() SummaryStruct {
+	downloaders := getModuleSummaries(ss.sched.registrar, module.TYPE_DOWNLOADER)
+	analyzers := getModuleSummaries(ss.sched.registrar, module.TYPE_ANALYZER)
+	pipelines := getModuleSummaries(ss.sched.registrar, module.TYPE_PIPELINE)
 	return SummaryStruct{
 		RequestArgs:     ss.requestArgs,
 		DataArgs:        ss.dataArgs,
 		Status:          GetStatusDescription(ss.sched.Status()),
-		Downloader:      ss.sched.downloader.Summary(),
-		Analyzer:        ss.sched.analyzer.Summary(),
-		Pipeline:        ss.sched.pipeline.Summary(),
+		Downloader:      sumModuleSummaries(downloaders),
+		Analyzer:        sumModuleSummaries(analyzers),
+		Pipeline:        sumModuleSummaries(pipelines),
+		Downloaders:     downloaders,
+		Analyzers:       analyzers,
+		Pipelines:       pipelines,
 		ReqBufferPool:   getBufferPoolSummary(ss.sched.reqBufferPool),
 		RespBufferPool:  getBufferPoolSummary(ss.sched.respBufferPool),
 		ItemBufferPool:  getBufferPoolSummary(ss.sched.itemBufferPool),
@@ -80,6 +87,9 @@ type SummaryStruct struct {
 	Downloader      module.SummaryStruct    `json:"downloader"`
 	Analyzer        module.SummaryStruct    `json:"analyzer"`
 	Pipeline        module.SummaryStruct    `json:"pipeline"`
+	Downloaders     []module.SummaryStruct  `json:"downloaders"`
+	Analyzers       []module.SummaryStruct  `json:"analyzers"`
+	Pipelines       []module.SummaryStruct  `json:"pipelines"`
 	ReqBufferPool   BufferPoolSummaryStruct `json:"request_buffer_pool"`
 	RespBufferPool  BufferPoolSummaryStruct `json:"response_buffer_pool"`
 	ItemBufferPool  BufferPoolSummaryStruct `json:"item_buffer_pool"`
@@ -107,6 +117,12 @@ func (one *SummaryStruct) Same(anthor SummaryStruct) bool {
 		return false
 	}
 
+	if !sameModuleSummaries(one.Downloaders, anthor.Downloaders) ||
+		!sameModuleSummaries(one.Analyzers, anthor.Analyzers) ||
+		!sameModuleSummaries(one.Pipelines, anthor.Pipelines) {
+		return false
+	}
+
 	if one.ReqBufferPool != anthor.ReqBufferPool ||
 		one.RespBufferPool != anthor.RespBufferPool ||
 		one.ItemBufferPool != anthor.ItemBufferPool ||
@@ -142,3 +158,54 @@ func getBufferPoolSummary(bufferPool buffer.Pool) BufferPoolSummaryStruct {
 		Total:           bufferPool.Total(),
 	}
 }
+
+/*
+ * get the summaries of all module instances of the type, sorted by mid
+ */
+func getModuleSummaries(registrar module.Registrar, mtype int8) []module.SummaryStruct {
+	summaries := []module.SummaryStruct{}
+	modules, yierr := registrar.GetAllByType(mtype)
+	if yierr != nil {
+		return summaries
+	}
+	for _, m := range modules {
+		summaries = append(summaries, m.Summary())
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].ID < summaries[j].ID
+	})
+	return summaries
+}
+
+/*
+ * sum the counts of module summaries
+ * the id is kept only if there is exactly one instance.
+ */
+func sumModuleSummaries(summaries []module.SummaryStruct) module.SummaryStruct {
+	if len(summaries) == 1 {
+		return summaries[0]
+	}
+	sum := module.SummaryStruct{}
+	for _, summary := range summaries {
+		sum.Called += summary.Called
+		sum.Accepted += summary.Accepted
+		sum.Completed += summary.Completed
+		sum.Handling += summary.Handling
+	}
+	return sum
+}
+
+/*
+ * compare two lists of module summaries
+ */
+func sameModuleSummaries(one, anthor []module.SummaryStruct) bool {
+	if len(one) != len(anthor) {
+		return false
+	}
+	for i := range one {
+		if one[i] != anthor[i] {
+			return false
+		}
+	}
+	return true
+}
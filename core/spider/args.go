@@ -0,0 +1,139 @@
+package spider
+
+import (
+	"net/url"
+
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+	"github.com/l-dandelion/yi-ants-go/lib/library/warc"
+)
+
+//constant of download mode
+const (
+	DOWNLOAD_MODE_LIVE   = ""       // download from the network
+	DOWNLOAD_MODE_RECORD = "record" // download from the network and store every response
+	DOWNLOAD_MODE_REPLAY = "replay" // serve only from stored responses, fail on a miss
+)
+
+/*
+ * args for the downloaders of a spider
+ * Warc: write every fetched request/response pair into warc files if not nil
+ * Mode: download mode, live, record or replay
+ * StoreDir: the directory of stored responses for record and replay mode
+ * Clients: one downloader is created for every client, a default one is used if empty
+ */
+type DownloaderArgs struct {
+	Warc     *warc.Args   `json:"warc,omitempty"`
+	Mode     string       `json:"mode"`
+	StoreDir string       `json:"store_dir"`
+	Clients  []ClientArgs `json:"clients,omitempty"`
+}
+
+/*
+ * check whether the downloader args is valid
+ */
+func (args *DownloaderArgs) Check() *constant.YiError {
+	switch args.Mode {
+	case DOWNLOAD_MODE_LIVE:
+	case DOWNLOAD_MODE_RECORD, DOWNLOAD_MODE_REPLAY:
+		if args.StoreDir == "" {
+			return constant.NewYiErrorf(constant.ERR_ARGS, "Empty store dir for download mode %q.", args.Mode)
+		}
+	default:
+		return constant.NewYiErrorf(constant.ERR_ARGS, "Unsupported download mode: %q", args.Mode)
+	}
+	for _, client := range args.Clients {
+		if yierr := client.Check(); yierr != nil {
+			return yierr
+		}
+	}
+	if args.Warc != nil {
+		if err := args.Warc.Check(); err != nil {
+			return constant.NewYiErrore(constant.ERR_ARGS, err)
+		}
+	}
+	return nil
+}
+
+/*
+ * args for the http client of a downloader
+ * Proxy: the proxy url, such as http://127.0.0.1:8080
+ * Timeout: the dial timeout in seconds, DEFAULT_CLIENT_TIMEOUT if zero
+ */
+type ClientArgs struct {
+	Proxy   string `json:"proxy"`
+	Timeout int    `json:"timeout"`
+}
+
+/*
+ * check whether the client args is valid
+ */
+func (args *ClientArgs) Check() *constant.YiError {
+	if args.Timeout < 0 {
+		return constant.NewYiErrorf(constant.ERR_ARGS, "Negative client timeout: %d", args.Timeout)
+	}
+	if args.Proxy != "" {
+		proxyURL, err := url.Parse(args.Proxy)
+		if err != nil {
+			return constant.NewYiErrore(constant.ERR_ARGS, err)
+		}
+		if proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return constant.NewYiErrorf(constant.ERR_ARGS, "Illegal proxy url: %s", args.Proxy)
+		}
+	}
+	return nil
+}
+
+/*
+ * args for the analyzers and pipelines of a spider
+ * Analyzers: the number of analyzers, one if zero
+ * Pipelines: the number of local pipelines, one if zero, ignored if there are remote pipelines
+ */
+type ModuleArgs struct {
+	Analyzers int `json:"analyzers"`
+	Pipelines int `json:"pipelines"`
+}
+
+/*
+ * check whether the module args is valid
+ */
+func (args *ModuleArgs) Check() *constant.YiError {
+	if args.Analyzers < 0 {
+		return constant.NewYiErrorf(constant.ERR_ARGS, "Negative analyzer number: %d", args.Analyzers)
+	}
+	if args.Pipelines < 0 {
+		return constant.NewYiErrorf(constant.ERR_ARGS, "Negative pipeline number: %d", args.Pipelines)
+	}
+	return nil
+}
+
+/*
+ * get the numbers of analyzers and pipelines, zero means one
+ */
+func (args *ModuleArgs) numbers() (analyzers, pipelines int) {
+	analyzers, pipelines = args.Analyzers, args.Pipelines
+	if analyzers == 0 {
+		analyzers = 1
+	}
+	if pipelines == 0 {
+		pipelines = 1
+	}
+	return
+}
+
+/*
+ * split the threads among n modules, the first ones get the remainder
+ * every module gets one thread at least.
+ */
+func splitThreads(threads, n, i int) int {
+	if n <= 0 {
+		return threads
+	}
+	share := threads / n
+	if i < threads%n {
+		share++
+	}
+	if share < 1 {
+		share = 1
+	}
+	return share
+}
@@ -0,0 +1,57 @@
+package spider
+
+import (
+	"testing"
+)
+
+func TestSplitThreads(t *testing.T) {
+	tests := []struct {
+		threads int
+		n       int
+		shares  []int
+	}{
+		{10, 1, []int{10}},
+		{10, 3, []int{4, 3, 3}},
+		{10, 5, []int{2, 2, 2, 2, 2}},
+		{2, 3, []int{1, 1, 1}},
+		{0, 2, []int{1, 1}},
+	}
+	for _, test := range tests {
+		for i, expected := range test.shares {
+			if share := splitThreads(test.threads, test.n, i); share != expected {
+				t.Fatalf("Inconsistent share of %d threads among %d modules[%d]: expected: %d, actual: %d",
+					test.threads, test.n, i, expected, share)
+			}
+		}
+	}
+	if share := splitThreads(10, 0, 0); share != 10 {
+		t.Fatalf("Inconsistent share without module: expected: %d, actual: %d", 10, share)
+	}
+}
+
+func TestModuleArgs(t *testing.T) {
+	tests := []struct {
+		args      ModuleArgs
+		analyzers int
+		pipelines int
+	}{
+		{ModuleArgs{}, 1, 1},
+		{ModuleArgs{Analyzers: 3}, 3, 1},
+		{ModuleArgs{Analyzers: 2, Pipelines: 4}, 2, 4},
+	}
+	for _, test := range tests {
+		if yierr := test.args.Check(); yierr != nil {
+			t.Fatalf("An error occurs when checking module args %+v: %s", test.args, yierr)
+		}
+		analyzers, pipelines := test.args.numbers()
+		if analyzers != test.analyzers || pipelines != test.pipelines {
+			t.Fatalf("Inconsistent numbers of %+v: expected: %d %d, actual: %d %d",
+				test.args, test.analyzers, test.pipelines, analyzers, pipelines)
+		}
+	}
+	for _, args := range []ModuleArgs{{Analyzers: -1}, {Pipelines: -1}} {
+		if yierr := args.Check(); yierr == nil {
+			t.Fatalf("No error when checking module args %+v!", args)
+		}
+	}
+}
@@ -3,21 +3,53 @@ package spider
 import (
 	"net"
 	"net/http"
+	"net/url"
 	"time"
+
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
 )
 
+// default dial timeout of the http client
+const DEFAULT_CLIENT_TIMEOUT = 15
+
 func genHTTPClient() *http.Client {
-	return &http.Client{
-		Transport: &http.Transport{
-			Dial: func(netw, addr string) (net.Conn, error) {
-				deadline := time.Now().Add(15 * time.Second)
-				c, err := net.DialTimeout(netw, addr, time.Second*15)
-				if err != nil {
-					return nil, err
-				}
-				c.SetDeadline(deadline)
-				return c, nil
-			},
+	client, _ := genHTTPClientByArgs(ClientArgs{})
+	return client
+}
+
+/*
+ * create a http client according to the client args like the downloaders of spiders
+ * it is used by the modules hosted for other nodes.
+ */
+func NewHTTPClient(args ClientArgs) (*http.Client, *constant.YiError) {
+	return genHTTPClientByArgs(args)
+}
+
+/*
+ * generate a http client according to the client args
+ */
+func genHTTPClientByArgs(args ClientArgs) (*http.Client, *constant.YiError) {
+	if yierr := args.Check(); yierr != nil {
+		return nil, yierr
+	}
+	timeout := time.Duration(args.Timeout) * time.Second
+	if timeout == 0 {
+		timeout = DEFAULT_CLIENT_TIMEOUT * time.Second
+	}
+	transport := &http.Transport{
+		Dial: func(netw, addr string) (net.Conn, error) {
+			deadline := time.Now().Add(timeout)
+			c, err := net.DialTimeout(netw, addr, timeout)
+			if err != nil {
+				return nil, err
+			}
+			c.SetDeadline(deadline)
+			return c, nil
 		},
 	}
+	if args.Proxy != "" {
+		proxyURL, _ := url.Parse(args.Proxy)
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Transport: transport}, nil
 }
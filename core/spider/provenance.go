@@ -0,0 +1,49 @@
+package spider
+
+import (
+	"sync"
+
+	"github.com/l-dandelion/yi-ants-go/core/module/data"
+)
+
+var (
+	// name of the node recorded in the provenance of items
+	nodeName     string
+	nodeNameLock sync.RWMutex
+)
+
+/*
+ * set the name of the node recorded in the provenance of items
+ */
+func SetNodeName(name string) {
+	nodeNameLock.Lock()
+	defer nodeNameLock.Unlock()
+	nodeName = name
+}
+
+/*
+ * get the name of the node recorded in the provenance of items
+ */
+func NodeName() string {
+	nodeNameLock.RLock()
+	defer nodeNameLock.RUnlock()
+	return nodeName
+}
+
+/*
+ * enable or disable the provenance of items, it takes effect when the scheduler is initialized
+ * see data.PROVENANCE_KEY for the fields.
+ */
+func (spider *mySpider) SetProvenance(enable bool) {
+	spider.Provenance = enable
+}
+
+/*
+ * get the source of the provenance of the analyzer, nil if disabled
+ */
+func (spider *mySpider) provenanceSource() *data.ProvenanceSource {
+	if !spider.Provenance {
+		return nil
+	}
+	return &data.ProvenanceSource{Spider: spider.Name, Node: NodeName()}
+}
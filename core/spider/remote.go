@@ -0,0 +1,109 @@
+package spider
+
+import (
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/remote"
+	"github.com/l-dandelion/yi-ants-go/lib/constant"
+)
+
+/*
+ * check the mids of remote modules
+ * only downloaders and pipelines with network address could be remote.
+ */
+func checkRemoteModules(mids []string) *constant.YiError {
+	for _, midStr := range mids {
+		mid := module.MID(midStr)
+		parts, yierr := module.SplitMID(mid)
+		if yierr != nil {
+			return yierr
+		}
+		if parts[2] == "" {
+			return constant.NewYiErrorf(constant.ERR_REMOTE_MODULE,
+				"Empty network address in mid: %s", mid)
+		}
+		mtype, _ := module.GetType(mid)
+		if mtype != module.TYPE_DOWNLOADER && mtype != module.TYPE_PIPELINE {
+			return constant.NewYiErrorf(constant.ERR_REMOTE_MODULE,
+				"Unsupported remote module type: %d (mid: %s)", mtype, mid)
+		}
+	}
+	return nil
+}
+
+/*
+ * set the mids of remote modules, such as D1|192.168.1.2:8300
+ * remote modules of a type replace the local ones when the scheduler is initialized.
+ */
+func (spider *mySpider) SetRemoteModules(mids []string) *constant.YiError {
+	if yierr := checkRemoteModules(mids); yierr != nil {
+		return yierr
+	}
+	spider.RemoteModules = mids
+	return nil
+}
+
+/*
+ * create the remote downloaders and pipelines
+ */
+func (spider *mySpider) genRemoteModules() ([]module.Downloader, []module.Pipeline, *constant.YiError) {
+	if yierr := checkRemoteModules(spider.RemoteModules); yierr != nil {
+		return nil, nil, yierr
+	}
+	spider.closeRemoteModules()
+	downloaders := []module.Downloader{}
+	pipelines := []module.Pipeline{}
+	remoteDownloaders := 0
+	for _, midStr := range spider.RemoteModules {
+		if mtype, _ := module.GetType(module.MID(midStr)); mtype == module.TYPE_DOWNLOADER {
+			remoteDownloaders++
+		}
+	}
+	for _, midStr := range spider.RemoteModules {
+		mid := module.MID(midStr)
+		mtype, _ := module.GetType(mid)
+		var (
+			m     remote.Module
+			yierr *constant.YiError
+		)
+		if mtype == module.TYPE_DOWNLOADER {
+			var d module.Downloader
+			maxThread := splitThreads(spider.MaxThread, remoteDownloaders, len(downloaders))
+			d, yierr = remote.NewDownloader(mid, module.CalculateScoreSimple, maxThread, nil)
+			if yierr == nil {
+				downloaders = append(downloaders, d)
+				m = d.(remote.Module)
+			}
+		} else {
+			var p module.Pipeline
+			p, yierr = remote.NewPipeline(mid, module.CalculateScoreSimple, nil)
+			if yierr == nil {
+				pipelines = append(pipelines, p)
+				m = p.(remote.Module)
+			}
+		}
+		if yierr != nil {
+			spider.closeRemoteModules()
+			return nil, nil, yierr
+		}
+		spider.remoteModules = append(spider.remoteModules, m)
+	}
+	if len(spider.remoteModules) > 0 {
+		spider.stopPinging = make(chan struct{})
+		go remote.KeepPinging(spider.remoteModules, remote.PING_INTERVAL, spider.stopPinging)
+	}
+	return downloaders, pipelines, nil
+}
+
+/*
+ * stop pinging and close the connections of remote modules
+ */
+func (spider *mySpider) closeRemoteModules() {
+	if spider.stopPinging != nil {
+		close(spider.stopPinging)
+		spider.stopPinging = nil
+	}
+	for _, m := range spider.remoteModules {
+		m.Close()
+	}
+	spider.remoteModules = nil
+}
@@ -2,6 +2,7 @@ package spider
 
 import (
 	"net/http"
+	"net/url"
 	"time"
 
 	"encoding/gob"
@@ -10,7 +11,10 @@ import (
 	"github.com/l-dandelion/yi-ants-go/core/module/local/analyzer"
 	"github.com/l-dandelion/yi-ants-go/core/module/local/downloader"
 	"github.com/l-dandelion/yi-ants-go/core/module/local/pipeline"
+	"github.com/l-dandelion/yi-ants-go/core/module/local/replay"
+	"github.com/l-dandelion/yi-ants-go/core/module/remote"
 	"github.com/l-dandelion/yi-ants-go/core/parsers"
+	"github.com/l-dandelion/yi-ants-go/core/parsers/form"
 	parsermodel "github.com/l-dandelion/yi-ants-go/core/parsers/model"
 	"github.com/l-dandelion/yi-ants-go/core/processors"
 	processormodel "github.com/l-dandelion/yi-ants-go/core/processors/model"
@@ -18,6 +22,7 @@ import (
 	"github.com/l-dandelion/yi-ants-go/lib/constant"
 	"github.com/l-dandelion/yi-ants-go/lib/library/buffer"
 	"github.com/l-dandelion/yi-ants-go/lib/library/parseurl"
+	"github.com/l-dandelion/yi-ants-go/lib/library/warc"
 	"strings"
 	"sync"
 )
@@ -34,6 +39,7 @@ type SpiderStatus struct {
 	EndTime         time.Time
 	ComplilingError *constant.YiError
 	CreatedAt       time.Time
+	Modules         map[string]module.SummaryStruct // summaries of the modules by mid
 }
 
 type Spider interface {
@@ -50,6 +56,11 @@ type Spider interface {
 	Copy() Spider
 	Complile() *constant.YiError
 	CanStart() bool
+	SetDownloaderArgs(args DownloaderArgs) *constant.YiError
+	SetModuleArgs(args ModuleArgs) *constant.YiError
+	SetRemoteModules(mids []string) *constant.YiError
+	SetItemSchema(schema *data.ItemSchema) *constant.YiError
+	SetProvenance(enable bool)
 }
 
 func (spider *mySpider) GetInitReqs() []*data.Request {
@@ -61,7 +72,7 @@ type mySpider struct {
 	Name                string
 	RequestArgs         scheduler.RequestArgs
 	DataArgs            scheduler.DataArgs
-	respParsers         []module.ParseResponse
+	parserRoutes        []*module.ParserRoute
 	itemProcessors      []module.ProcessItem
 	ParsersModels       []*parsermodel.Model
 	ProcessorsModels    []*processormodel.Model
@@ -73,6 +84,15 @@ type mySpider struct {
 	compilingStatusLock sync.Mutex
 	CreatedAt           time.Time
 	MaxThread           int
+	DownloaderArgs      DownloaderArgs
+	ModuleArgs          ModuleArgs
+	warcWriter          *warc.Writer
+	RemoteModules       []string
+	remoteModules       []remote.Module
+	stopPinging         chan struct{}
+	ItemSchema          *data.ItemSchema
+	itemValidator       *data.ItemValidator
+	Provenance          bool
 }
 
 /*
@@ -106,16 +126,28 @@ func New(name string,
 	if initialUrls != nil {
 		initialUrls = parseurl.ParseReqUrl(initialUrls, nil)
 		for _, urlStr := range initialUrls {
-			if strings.Index(urlStr, "http") != 0 {
-				urlStr = "http://" + urlStr
+			req, yierr := genInitialReq(urlStr)
+			if yierr != nil {
+				return nil, yierr
 			}
-			httpReq, err := http.NewRequest("GET", urlStr, nil)
-			if err != nil {
-				return nil, constant.NewYiErrore(constant.ERR_SPIDER_NEW, err)
+			spider.InitialReqs = append(spider.InitialReqs, req)
+		}
+	}
+	// seeds of the parser models are handled by the models
+	for _, parserModel := range parsersModels {
+		for _, urlStr := range parseurl.ParseReqUrl(parserModel.Seeds, nil) {
+			req, yierr := genInitialReq(urlStr)
+			if yierr != nil {
+				return nil, yierr
 			}
-			req := data.NewRequest(httpReq)
+			req.SetParser(parserModel.Name)
 			spider.InitialReqs = append(spider.InitialReqs, req)
 		}
+		formReqs, yierr := form.SeedRequests(parserModel)
+		if yierr != nil {
+			return nil, yierr
+		}
+		spider.InitialReqs = append(spider.InitialReqs, formReqs...)
 	}
 	if initialReqs != nil {
 		for _, req := range initialReqs {
@@ -125,6 +157,38 @@ func New(name string,
 	return spider, nil
 }
 
+/*
+ * create an initial request of the url, http is the default scheme
+ * a url without scheme could be parsed with its host as the scheme, such as localhost:8080/a,
+ * so the scheme followed by a port is not a scheme.
+ */
+func genInitialReq(urlStr string) (*data.Request, *constant.YiError) {
+	u, err := url.Parse(urlStr)
+	if err != nil || u.Scheme == "" || isPort(strings.SplitN(u.Opaque, "/", 2)[0]) {
+		urlStr = "http://" + urlStr
+	}
+	httpReq, err := http.NewRequest("GET", urlStr, nil)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_SPIDER_NEW, err)
+	}
+	return data.NewRequest(httpReq), nil
+}
+
+/*
+ * check whether s is a port number
+ */
+func isPort(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 func (spider *mySpider) SpiderName() string {
 	return spider.Name
 }
@@ -160,7 +224,10 @@ func (spider *mySpider) Complile() (yierr *constant.YiError) {
 	//	return
 	//}
 	//spider.itemProccessors = f.(func() []module.ProcessItem)()
-	spider.respParsers, yierr = parsers.GenParsersByModels(spider.ParsersModels)
+	if _, yierr = spider.RequestArgs.URLFilter(); yierr != nil {
+		return yierr
+	}
+	spider.parserRoutes, yierr = parsers.GenRoutesByModels(spider.ParsersModels)
 	if yierr != nil {
 		return yierr
 	}
@@ -168,6 +235,14 @@ func (spider *mySpider) Complile() (yierr *constant.YiError) {
 	if yierr != nil {
 		return yierr
 	}
+	spider.itemValidator = nil
+	if spider.ItemSchema != nil {
+		validator, err := spider.ItemSchema.Compile()
+		if err != nil {
+			return constant.NewYiErrore(constant.ERR_ITEM_SCHEMA, err)
+		}
+		spider.itemValidator = validator
+	}
 	return
 }
 
@@ -184,21 +259,56 @@ func (spider *mySpider) InitSchduler() (yierr *constant.YiError) {
 
 	sched := scheduler.New(spider.Name)
 	spider.Scheduler = sched
-	downloader, yierr := downloader.New("D1", genHTTPClient(), module.CalculateScoreSimple, spider.MaxThread)
-	if yierr != nil {
+	if yierr := spider.ModuleArgs.Check(); yierr != nil {
 		return yierr
 	}
-	parsers := spider.respParsers
-	analyzer, yierr := analyzer.New("A1", parsers, module.CalculateScoreSimple)
+	// the local downloaders are not built if the downloads are forwarded to remote modules
+	downloaders, remotePipelines, yierr := spider.genRemoteModules()
 	if yierr != nil {
 		return yierr
 	}
-	processors := spider.itemProcessors
-	pipeline, yierr := pipeline.New("P1", processors, module.CalculateScoreSimple)
+	if len(downloaders) == 0 {
+		if downloaders, yierr = spider.genDownloaders(); yierr != nil {
+			return yierr
+		}
+	}
+	analyzerNumber, pipelineNumber := spider.ModuleArgs.numbers()
+	analyzers := []module.Analyzer{}
+	for i := 0; i < analyzerNumber; i++ {
+		mid, yierr := module.GenMID(module.TYPE_ANALYZER, uint64(i+1), nil)
+		if yierr != nil {
+			return yierr
+		}
+		analyzer, yierr := analyzer.NewRouted(mid, spider.parserRoutes, module.CalculateScoreSimple)
+		if yierr != nil {
+			return yierr
+		}
+		analyzer.SetProvenance(spider.provenanceSource())
+		analyzers = append(analyzers, analyzer)
+	}
+	pipelines := remotePipelines
+	if len(pipelines) == 0 {
+		for i := 0; i < pipelineNumber; i++ {
+			mid, yierr := module.GenMID(module.TYPE_PIPELINE, uint64(i+1), nil)
+			if yierr != nil {
+				return yierr
+			}
+			pipeline, yierr := pipeline.New(mid, spider.itemProcessors, module.CalculateScoreSimple)
+			if yierr != nil {
+				return yierr
+			}
+			pipelines = append(pipelines, pipeline)
+		}
+	}
+	if spider.itemValidator != nil {
+		for _, p := range pipelines {
+			p.SetItemValidator(spider.validateItem)
+		}
+	}
 	moduleArgs := scheduler.ModuleArgs{
-		Downloader: downloader,
-		Analyzer:   analyzer,
-		Pipeline:   pipeline,
+		Downloaders: downloaders,
+		Analyzers:   analyzers,
+		Pipelines:   pipelines,
 	}
 	yierr = sched.Init(spider.RequestArgs, spider.DataArgs, moduleArgs)
 	if yierr != nil {
@@ -207,6 +317,123 @@ func (spider *mySpider) InitSchduler() (yierr *constant.YiError) {
 	return nil
 }
 
+/*
+ * generate the local downloaders, the threads of the spider are shared by them
+ */
+func (spider *mySpider) genDownloaders() ([]module.Downloader, *constant.YiError) {
+	clients, recorders, yierr := spider.genDownloaderArgs()
+	if yierr != nil {
+		return nil, yierr
+	}
+	downloaders := []module.Downloader{}
+	for i, client := range clients {
+		mid, yierr := module.GenMID(module.TYPE_DOWNLOADER, uint64(i+1), nil)
+		if yierr != nil {
+			return nil, yierr
+		}
+		maxThread := splitThreads(spider.MaxThread, len(clients), i)
+		downloader, yierr := downloader.New(mid, client, module.CalculateScoreSimple, maxThread, recorders...)
+		if yierr != nil {
+			return nil, yierr
+		}
+		downloaders = append(downloaders, downloader)
+	}
+	return downloaders, nil
+}
+
+/*
+ * generate the http clients and the response recorders according to the downloader args
+ * one downloader is created for every client.
+ */
+func (spider *mySpider) genDownloaderArgs() ([]*http.Client, []downloader.ResponseRecorder, *constant.YiError) {
+	args := spider.DownloaderArgs
+	if yierr := args.Check(); yierr != nil {
+		return nil, nil, yierr
+	}
+	clients := []*http.Client{}
+	for _, clientArgs := range args.Clients {
+		client, yierr := genHTTPClientByArgs(clientArgs)
+		if yierr != nil {
+			return nil, nil, yierr
+		}
+		clients = append(clients, client)
+	}
+	if len(clients) == 0 {
+		clients = append(clients, genHTTPClient())
+	}
+	recorders := []downloader.ResponseRecorder{}
+	if args.Mode == DOWNLOAD_MODE_RECORD || args.Mode == DOWNLOAD_MODE_REPLAY {
+		store, err := replay.NewFileStore(args.StoreDir)
+		if err != nil {
+			return nil, nil, constant.NewYiErrore(constant.ERR_NEW_DOWNLOADER_FAIL, err)
+		}
+		if args.Mode == DOWNLOAD_MODE_RECORD {
+			recorders = append(recorders, replay.NewRecorder(store))
+		} else {
+			// proxies are meaningless when serving from the store
+			clients = []*http.Client{{Transport: replay.NewTransport(store)}}
+		}
+	}
+	if args.Warc != nil {
+		if spider.warcWriter != nil {
+			spider.warcWriter.Close()
+		}
+		writer, err := warc.NewWriter(*args.Warc)
+		if err != nil {
+			return nil, nil, constant.NewYiErrore(constant.ERR_NEW_DOWNLOADER_FAIL, err)
+		}
+		spider.warcWriter = writer
+		recorders = append(recorders, downloader.NewWarcRecorder(writer))
+	}
+	return clients, recorders, nil
+}
+
+/*
+ * set the downloader args, it takes effect when the scheduler is initialized
+ */
+func (spider *mySpider) SetDownloaderArgs(args DownloaderArgs) *constant.YiError {
+	if yierr := args.Check(); yierr != nil {
+		return yierr
+	}
+	spider.DownloaderArgs = args
+	return nil
+}
+
+/*
+ * set the numbers of analyzers and pipelines, it takes effect when the scheduler is initialized
+ */
+func (spider *mySpider) SetModuleArgs(args ModuleArgs) *constant.YiError {
+	if yierr := args.Check(); yierr != nil {
+		return yierr
+	}
+	spider.ModuleArgs = args
+	return nil
+}
+
+/*
+ * set the item schema, it takes effect when the spider is compiled
+ */
+func (spider *mySpider) SetItemSchema(schema *data.ItemSchema) *constant.YiError {
+	if schema != nil {
+		if _, err := schema.Compile(); err != nil {
+			return constant.NewYiErrore(constant.ERR_ITEM_SCHEMA, err)
+		}
+	}
+	spider.ItemSchema = schema
+	return nil
+}
+
+/*
+ * validate the item by the item schema, the invalid item is reported with the field
+ */
+func (spider *mySpider) validateItem(item data.Item) (data.Item, *constant.YiError) {
+	validItem, err := spider.itemValidator.Validate(item)
+	if err != nil {
+		return nil, constant.NewYiErrore(constant.ERR_ITEM_INVALID, err)
+	}
+	return validItem, nil
+}
+
 func (spider *mySpider) InitDistributeQueue(distributerQueue buffer.Pool) {
 	spider.Scheduler.SetDistributeQueue(distributerQueue)
 }
@@ -273,6 +500,10 @@ func (spider *mySpider) Stop() *constant.YiError {
 	yierr := spider.Scheduler.Stop()
 	if yierr == nil {
 		spider.EndTime = time.Now()
+		if spider.warcWriter != nil {
+			spider.warcWriter.Close()
+		}
+		spider.closeRemoteModules()
 	}
 	return yierr
 }
@@ -312,7 +543,23 @@ func (spider *mySpider) SpiderStatus() *SpiderStatus {
 		StartTime:       spider.StartTime,
 		EndTime:         spider.EndTime,
 		CreatedAt:       spider.CreatedAt,
+		Modules:         moduleSummaries(summary),
+	}
+}
+
+/*
+ * get the summaries of the modules of the scheduler by mid
+ * the extra of the summaries is dropped, so the status can be sent by gob.
+ */
+func moduleSummaries(summary scheduler.SummaryStruct) map[string]module.SummaryStruct {
+	modules := map[string]module.SummaryStruct{}
+	for _, list := range [][]module.SummaryStruct{summary.Downloaders, summary.Analyzers, summary.Pipelines} {
+		for _, s := range list {
+			s.Extra = nil
+			modules[string(s.ID)] = s
+		}
 	}
+	return modules
 }
 
 /*
@@ -349,6 +596,11 @@ func (spider *mySpider) Copy() Spider {
 		EndTime:          spider.EndTime,
 		CreatedAt:        spider.CreatedAt,
 		MaxThread:        spider.MaxThread,
+		DownloaderArgs:   spider.DownloaderArgs,
+		ModuleArgs:       spider.ModuleArgs,
+		RemoteModules:    spider.RemoteModules,
+		ItemSchema:       spider.ItemSchema,
+		Provenance:       spider.Provenance,
 	}
 }
 
@@ -380,5 +632,21 @@ func megerSpiderStatus(a *SpiderStatus, b *SpiderStatus) *SpiderStatus {
 	a.Running += b.Running
 	a.Success += b.Success
 	a.Waiting += b.Waiting
+	// the modules of the same mid on different nodes are summed
+	for mid, s := range b.Modules {
+		if a.Modules == nil {
+			a.Modules = map[string]module.SummaryStruct{}
+		}
+		sum, ok := a.Modules[mid]
+		if !ok {
+			a.Modules[mid] = s
+			continue
+		}
+		sum.Called += s.Called
+		sum.Accepted += s.Accepted
+		sum.Completed += s.Completed
+		sum.Handling += s.Handling
+		a.Modules[mid] = sum
+	}
 	return a
 }
@@ -0,0 +1,70 @@
+package spider
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/l-dandelion/yi-ants-go/core/module"
+	"github.com/l-dandelion/yi-ants-go/core/module/local/downloader"
+	"github.com/l-dandelion/yi-ants-go/core/module/local/fetcher"
+	"github.com/l-dandelion/yi-ants-go/core/scheduler"
+)
+
+func TestGenInitialReq(t *testing.T) {
+	tests := map[string]string{
+		"example.com/a":           "http://example.com/a",
+		"localhost:8080/a":        "http://localhost:8080/a",
+		"localhost:8080":          "http://localhost:8080",
+		"https://example.com/a":   "https://example.com/a",
+		"file:page.html":          "file:page.html",
+		"mem:fixture/list?page=1": "mem:fixture/list?page=1",
+	}
+	for urlStr, expected := range tests {
+		req, yierr := genInitialReq(urlStr)
+		if yierr != nil {
+			t.Fatalf("An error occurs when creating the request of %q: %s", urlStr, yierr)
+		}
+		if actual := req.HTTPReq().URL.String(); actual != expected {
+			t.Fatalf("Inconsistent url of %q: expected: %s, actual: %s", urlStr, expected, actual)
+		}
+	}
+}
+
+func TestFileSeed(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "spider")
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "page.html"), []byte("<html>page</html>"), 0644); err != nil {
+		t.Fatalf("An error occurs when writing the page: %s", err)
+	}
+	if err := fetcher.Register("file", fetcher.NewFileFetcher(dir)); err != nil {
+		t.Fatalf("An error occurs when registering the file fetcher: %s", err)
+	}
+	defer fetcher.Default().Unregister("file")
+
+	sp, yierr := New("file", scheduler.RequestArgs{}, scheduler.DataArgs{}, []string{"file:page.html"}, nil, nil, nil, 1)
+	if yierr != nil {
+		t.Fatalf("An error occurs when creating a spider: %s", yierr)
+	}
+	reqs := sp.GetInitReqs()
+	if len(reqs) != 1 || reqs[0].HTTPReq().URL.String() != "file:page.html" {
+		t.Fatalf("Inconsistent initial requests: %v", reqs)
+	}
+	d, yierr := downloader.New(module.MID("D1"), &http.Client{}, nil, 1)
+	if yierr != nil {
+		t.Fatalf("An error occurs when creating a downloader: %s", yierr)
+	}
+	resp, yierr := d.Download(reqs[0])
+	if yierr != nil {
+		t.Fatalf("An error occurs when downloading the seed: %s", yierr)
+	}
+	body, err := ioutil.ReadAll(resp.HTTPResp().Body)
+	if err != nil {
+		t.Fatalf("An error occurs when reading the body: %s", err)
+	}
+	if resp.HTTPResp().StatusCode != http.StatusOK || string(body) != "<html>page</html>" {
+		t.Fatalf("Inconsistent response: %d %q", resp.HTTPResp().StatusCode, body)
+	}
+}
@@ -32,6 +32,8 @@ var gmsgMap = map[int]string{
 	ERR_CRAWL_GET_COMPLATE_URL: "Get Complate Url Fail",
 	//new http request fail
 	ERR_CRAWL_NEW_HTTP_REQUEST: "New HTTP Request Fail",
+	//record response fail
+	ERR_CRAWL_RECORD: "Record Response Fail",
 
 	/*
 	 * module error
@@ -55,6 +57,8 @@ var gmsgMap = map[int]string{
 	ERR_NEW_ANALYZER_FAIL: "New Analyzer Fail(新建解析器失败)",
 	//new pipeline error(新建处理管道失败)
 	ERR_NEW_PIPELINE: "New Pipeline Fail",
+	//remote module error(远程组件错误)
+	ERR_REMOTE_MODULE: "Remote Module Error(远程组件错误)",
 
 	/*
 	 * scheduler error
@@ -107,6 +111,10 @@ var gmsgMap = map[int]string{
 	ERR_CRAWLER_NEW: "New Crawler Fail",
 	// pop request fail
 	ERR_REQUEST_POP: "Pop Request Fail",
+	// write checkpoint fail
+	ERR_CHECKPOINT_WRITE: "Write Checkpoint Fail",
+	// read checkpoint fail
+	ERR_CHECKPOINT_READ: "Read Checkpoint Fail",
 
 	/*
 	 * cluster error
@@ -129,6 +137,28 @@ var gmsgMap = map[int]string{
 	ERR_GET_PROCESSORS_SOURCE: "Get Processors Source Fail",
 	// get processors fail
 	ERR_GET_PROCESSORS: "Get Processors Fail",
+	// parse json fail
+	ERR_PARSE_JSON: "Parse Json Fail",
+	// illegal json path
+	ERR_JSON_PATH: "Illegal Json Path",
+	// illegal selector
+	ERR_SELECTOR: "Illegal Selector",
+	// illegal regex pattern
+	ERR_REGEX_PATTERN: "Illegal Regex Pattern",
+	// field filter fail
+	ERR_FIELD_FILTER: "Field Filter Fail",
+	// illegal rule schema
+	ERR_RULE_SCHEMA: "Illegal Rule Schema",
+	// parse xml fail
+	ERR_PARSE_XML: "Parse Xml Fail",
+	// compile script fail
+	ERR_SCRIPT_COMPILE: "Compile Script Fail",
+	// run script fail
+	ERR_SCRIPT_RUN: "Run Script Fail",
+	// illegal item schema
+	ERR_ITEM_SCHEMA: "Illegal Item Schema",
+	// invalid item
+	ERR_ITEM_INVALID: "Invalid Item",
 }
 
 func GetErrMsg(errno int) string {
@@ -31,6 +31,8 @@ const (
 	ERR_CRAWL_GET_COMPLATE_URL = 20006
 	//new http request fail
 	ERR_CRAWL_NEW_HTTP_REQUEST = 20007
+	//record response fail
+	ERR_CRAWL_RECORD = 20008
 
 	/*
 	 * module error
@@ -54,6 +56,8 @@ const (
 	ERR_NEW_ANALYZER_FAIL = 30008
 	//new pipeline error(新建处理管道失败)
 	ERR_NEW_PIPELINE = 30009
+	//remote module error(远程组件错误)
+	ERR_REMOTE_MODULE = 30010
 
 	/*
 	 * scheduler error
@@ -106,6 +110,10 @@ const (
 	ERR_CRAWLER_NEW = 70001
 	// pop request fail
 	ERR_REQUEST_POP = 70002
+	// write checkpoint fail
+	ERR_CHECKPOINT_WRITE = 70003
+	// read checkpoint fail
+	ERR_CHECKPOINT_READ = 70004
 
 	/*
 	 * cluster error
@@ -128,4 +136,26 @@ const (
 	ERR_GET_PROCESSORS_SOURCE = 90004
 	// get processors fail
 	ERR_GET_PROCESSORS = 90005
+	// parse json fail
+	ERR_PARSE_JSON = 90006
+	// illegal json path
+	ERR_JSON_PATH = 90007
+	// illegal selector
+	ERR_SELECTOR = 90008
+	// illegal regex pattern
+	ERR_REGEX_PATTERN = 90009
+	// field filter fail
+	ERR_FIELD_FILTER = 90010
+	// illegal rule schema
+	ERR_RULE_SCHEMA = 90011
+	// parse xml fail
+	ERR_PARSE_XML = 90012
+	// compile script fail
+	ERR_SCRIPT_COMPILE = 90013
+	// run script fail
+	ERR_SCRIPT_RUN = 90014
+	// illegal item schema
+	ERR_ITEM_SCHEMA = 90015
+	// invalid item
+	ERR_ITEM_INVALID = 90016
 )
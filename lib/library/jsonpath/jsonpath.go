@@ -0,0 +1,381 @@
+package jsonpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+ * compiled JSONPath expression
+ * supported syntax:
+ *   $ or @          the root (or current) node
+ *   .key ['key']    child by key
+ *   [n]             element by index, negative index counts from the end
+ *   [start:end]     slice of array
+ *   [a,b] ['a','b'] union of indexes or keys
+ *   .* [*]          all children
+ *   ..key ..*       recursive descent
+ * the document is the result of encoding/json decoding into interface{}.
+ */
+type Path struct {
+	expr  string
+	steps []step
+}
+
+// kind of a path step
+const (
+	stepKey = iota
+	stepIndex
+	stepSlice
+	stepWildcard
+)
+
+/*
+ * one step of a path
+ * recursive: apply the step to the node and all its descendants
+ */
+type step struct {
+	kind      int
+	keys      []string
+	indexes   []int
+	start     *int
+	end       *int
+	recursive bool
+}
+
+/*
+ * compile a JSONPath expression
+ */
+func Compile(expr string) (*Path, error) {
+	s := strings.TrimSpace(expr)
+	if s == "" {
+		return nil, fmt.Errorf("empty json path")
+	}
+	if s[0] == '$' || s[0] == '@' {
+		s = s[1:]
+	}
+	p := &Path{expr: expr}
+	for len(s) > 0 {
+		var (
+			st  step
+			err error
+		)
+		switch {
+		case strings.HasPrefix(s, ".."):
+			s = s[2:]
+			st.recursive = true
+			if strings.HasPrefix(s, "[") {
+				st, s, err = parseBracket(s)
+				st.recursive = true
+			} else {
+				st, s, err = parseDotKey(s)
+				st.recursive = true
+			}
+		case s[0] == '.':
+			st, s, err = parseDotKey(s[1:])
+		case s[0] == '[':
+			st, s, err = parseBracket(s)
+		default:
+			// a path without root such as "data.items"
+			if len(p.steps) == 0 {
+				st, s, err = parseDotKey(s)
+			} else {
+				err = fmt.Errorf("unexpected %q", s[0])
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("illegal json path %q: %s", expr, err)
+		}
+		p.steps = append(p.steps, st)
+	}
+	return p, nil
+}
+
+/*
+ * compile a JSONPath expression, panic if it is illegal
+ */
+func MustCompile(expr string) *Path {
+	p, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+/*
+ * get the expression of the path
+ */
+func (p *Path) String() string {
+	return p.expr
+}
+
+/*
+ * check whether the path matches one value at most
+ * a path with recursive descent, wildcard, slice or union matches a list of values.
+ */
+func (p *Path) Definite() bool {
+	for _, st := range p.steps {
+		if st.recursive || st.kind == stepWildcard || st.kind == stepSlice || len(st.keys) > 1 || len(st.indexes) > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+ * find all values matched by the path
+ */
+func (p *Path) Find(doc interface{}) []interface{} {
+	nodes := []interface{}{doc}
+	for _, st := range p.steps {
+		next := []interface{}{}
+		for _, node := range nodes {
+			if st.recursive {
+				for _, n := range descendants(node) {
+					next = append(next, st.apply(n)...)
+				}
+			} else {
+				next = append(next, st.apply(node)...)
+			}
+		}
+		nodes = next
+		if len(nodes) == 0 {
+			break
+		}
+	}
+	return nodes
+}
+
+/*
+ * find the first value matched by the path
+ */
+func (p *Path) First(doc interface{}) (interface{}, bool) {
+	values := p.Find(doc)
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+/*
+ * compile the expression and find all matched values
+ */
+func Find(expr string, doc interface{}) ([]interface{}, error) {
+	p, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return p.Find(doc), nil
+}
+
+/*
+ * parse a key after the dot, such as "key" or "*"
+ */
+func parseDotKey(s string) (step, string, error) {
+	i := 0
+	for i < len(s) && s[i] != '.' && s[i] != '[' {
+		i++
+	}
+	key := s[:i]
+	if key == "" {
+		return step{}, s, fmt.Errorf("empty key")
+	}
+	if key == "*" {
+		return step{kind: stepWildcard}, s[i:], nil
+	}
+	return step{kind: stepKey, keys: []string{key}}, s[i:], nil
+}
+
+/*
+ * parse a bracket, such as ['key'], [0], [1:3], [*]
+ */
+func parseBracket(s string) (step, string, error) {
+	end := closingBracket(s)
+	if end < 0 {
+		return step{}, s, fmt.Errorf("unclosed bracket")
+	}
+	content := strings.TrimSpace(s[1:end])
+	rest := s[end+1:]
+	if content == "*" {
+		return step{kind: stepWildcard}, rest, nil
+	}
+	if content == "" {
+		return step{}, rest, fmt.Errorf("empty bracket")
+	}
+	if content[0] == '\'' || content[0] == '"' {
+		keys := []string{}
+		for _, part := range splitUnion(content) {
+			part = strings.TrimSpace(part)
+			if len(part) < 2 || part[0] != part[len(part)-1] || (part[0] != '\'' && part[0] != '"') {
+				return step{}, rest, fmt.Errorf("illegal quoted key %s", part)
+			}
+			keys = append(keys, part[1:len(part)-1])
+		}
+		return step{kind: stepKey, keys: keys}, rest, nil
+	}
+	if strings.Contains(content, ":") {
+		parts := strings.Split(content, ":")
+		if len(parts) != 2 {
+			return step{}, rest, fmt.Errorf("illegal slice %s", content)
+		}
+		st := step{kind: stepSlice}
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return step{}, rest, fmt.Errorf("illegal slice %s", content)
+			}
+			if i == 0 {
+				st.start = &n
+			} else {
+				st.end = &n
+			}
+		}
+		return st, rest, nil
+	}
+	st := step{kind: stepIndex}
+	for _, part := range strings.Split(content, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return step{}, rest, fmt.Errorf("illegal index %s", part)
+		}
+		st.indexes = append(st.indexes, n)
+	}
+	return st, rest, nil
+}
+
+/*
+ * get the index of the bracket closing the one at s[0], quotes are skipped
+ */
+func closingBracket(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+/*
+ * split a union of quoted keys by commas outside quotes
+ */
+func splitUnion(s string) []string {
+	parts := []string{}
+	var quote byte
+	begin := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ',':
+			parts = append(parts, s[begin:i])
+			begin = i + 1
+		}
+	}
+	return append(parts, s[begin:])
+}
+
+/*
+ * apply the step to a node
+ */
+func (st step) apply(node interface{}) []interface{} {
+	result := []interface{}{}
+	switch st.kind {
+	case stepKey:
+		if m, ok := node.(map[string]interface{}); ok {
+			for _, key := range st.keys {
+				if v, ok := m[key]; ok {
+					result = append(result, v)
+				}
+			}
+		}
+	case stepIndex:
+		if arr, ok := node.([]interface{}); ok {
+			for _, i := range st.indexes {
+				if i < 0 {
+					i += len(arr)
+				}
+				if i >= 0 && i < len(arr) {
+					result = append(result, arr[i])
+				}
+			}
+		}
+	case stepSlice:
+		if arr, ok := node.([]interface{}); ok {
+			start, end := 0, len(arr)
+			if st.start != nil {
+				start = normalize(*st.start, len(arr))
+			}
+			if st.end != nil {
+				end = normalize(*st.end, len(arr))
+			}
+			for i := start; i < end; i++ {
+				result = append(result, arr[i])
+			}
+		}
+	case stepWildcard:
+		result = append(result, children(node)...)
+	}
+	return result
+}
+
+/*
+ * normalize a slice bound into [0, length]
+ */
+func normalize(i, length int) int {
+	if i < 0 {
+		i += length
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > length {
+		return length
+	}
+	return i
+}
+
+/*
+ * get the children of a node, the keys of an object are in sorted order
+ */
+func children(node interface{}) []interface{} {
+	result := []interface{}{}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(n) {
+			result = append(result, n[key])
+		}
+	case []interface{}:
+		result = append(result, n...)
+	}
+	return result
+}
+
+/*
+ * get the node and all its descendants in document order
+ */
+func descendants(node interface{}) []interface{} {
+	result := []interface{}{node}
+	for _, child := range children(node) {
+		result = append(result, descendants(child)...)
+	}
+	return result
+}
@@ -0,0 +1,104 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const testDoc = `{
+	"data": {
+		"items": [
+			{"id": 1, "name": "a", "tags": ["x", "y"]},
+			{"id": 2, "name": "b", "tags": []},
+			{"id": 3, "name": "c", "owner": {"name": "d"}}
+		],
+		"next": "token-2",
+		"a.b": true
+	}
+}`
+
+func TestFind(t *testing.T) {
+	doc, err := Decode([]byte(testDoc))
+	if err != nil {
+		t.Fatalf("An error occurs when decoding: %s", err)
+	}
+	cases := []struct {
+		expr     string
+		expected []interface{}
+	}{
+		{"$.data.next", []interface{}{"token-2"}},
+		{"data.next", []interface{}{"token-2"}},
+		{"$['data']['a.b']", []interface{}{true}},
+		{"$.data.items[0].id", []interface{}{json.Number("1")}},
+		{"$.data.items[-1].name", []interface{}{"c"}},
+		{"$.data.items[*].name", []interface{}{"a", "b", "c"}},
+		{"$.data.items.*.id", []interface{}{json.Number("1"), json.Number("2"), json.Number("3")}},
+		{"$.data.items[0,2].id", []interface{}{json.Number("1"), json.Number("3")}},
+		{"$.data.items[1:].id", []interface{}{json.Number("2"), json.Number("3")}},
+		{"$.data.items[:-2].id", []interface{}{json.Number("1")}},
+		{"$..name", []interface{}{"a", "b", "c", "d"}},
+		{"$.data.items[0]['id','name']", []interface{}{json.Number("1"), "a"}},
+		{"$.data.items[0].tags[*]", []interface{}{"x", "y"}},
+		{"$.data.missing", []interface{}{}},
+		{"$.data.items[9]", []interface{}{}},
+	}
+	for _, c := range cases {
+		actual, err := Find(c.expr, doc)
+		if err != nil {
+			t.Fatalf("An error occurs when finding %s: %s", c.expr, err)
+		}
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Fatalf("Inconsistent result of %s: expected: %v, actual: %v", c.expr, c.expected, actual)
+		}
+	}
+
+	item, _ := MustCompile("$.data.items[2]").First(doc)
+	if v, _ := MustCompile("@.owner.name").First(item); v != "d" {
+		t.Fatalf("Inconsistent relative result: expected: %v, actual: %v", "d", v)
+	}
+}
+
+func TestDefinite(t *testing.T) {
+	cases := map[string]bool{
+		"$":                    true,
+		"$.data.next":          true,
+		"$['data']['a.b']":     true,
+		"@.items[-1].name":     true,
+		"$.items[*].name":      false,
+		"$.items.*":            false,
+		"$.items[0,2]":         false,
+		"$.items[1:]":          false,
+		"$..name":              false,
+		"$.items[0]['id','a']": false,
+	}
+	for expr, expected := range cases {
+		if actual := MustCompile(expr).Definite(); actual != expected {
+			t.Fatalf("Inconsistent definite of %s: expected: %v, actual: %v", expr, expected, actual)
+		}
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	for _, expr := range []string{"", "$.", "$[", "$[]", "$['a]", "$[a]", "$[1:2:3]"} {
+		if _, err := Compile(expr); err == nil {
+			t.Fatalf("No error when compiling illegal path %q!", expr)
+		}
+	}
+}
+
+func TestToString(t *testing.T) {
+	cases := map[string]interface{}{
+		"":        nil,
+		"a":       "a",
+		"12":      json.Number("12"),
+		"true":    true,
+		`["a"]`:   []interface{}{"a"},
+		`{"a":1}`: map[string]interface{}{"a": json.Number("1")},
+	}
+	for expected, v := range cases {
+		if actual := ToString(v); actual != expected {
+			t.Fatalf("Inconsistent string: expected: %s, actual: %s", expected, actual)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+/*
+ * decode a json document, numbers are kept as json.Number
+ */
+func Decode(b []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+/*
+ * convert a scalar value into string
+ * objects and arrays are encoded as json, null is an empty string.
+ */
+func ToString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		if value {
+			return "true"
+		}
+		return "false"
+	default:
+		b, _ := json.Marshal(value)
+		return string(b)
+	}
+}
+
+/*
+ * get the keys of an object in sorted order
+ */
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,86 @@
+package pattern
+
+import (
+	"regexp"
+)
+
+/*
+ * compiled text pattern
+ * the named groups of a match are the fields of the result.
+ */
+type Pattern struct {
+	re    *regexp.Regexp
+	names []string
+}
+
+/*
+ * compile a regular expression with the syntax of package regexp
+ */
+func Compile(expr string) (*Pattern, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &Pattern{re: re}
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			p.names = append(p.names, name)
+		}
+	}
+	return p, nil
+}
+
+/*
+ * get the expression of the pattern
+ */
+func (p *Pattern) String() string {
+	return p.re.String()
+}
+
+/*
+ * get the names of the named groups
+ */
+func (p *Pattern) Names() []string {
+	return p.names
+}
+
+/*
+ * check whether the pattern has named groups
+ */
+func (p *Pattern) HasNames() bool {
+	return len(p.names) > 0
+}
+
+/*
+ * find all matches in the text, at most n matches if n >= 0
+ * every match is a map from the group name to the matched text,
+ * a group not taking part in the match is an empty string.
+ */
+func (p *Pattern) FindAll(text string, n int) []map[string]string {
+	result := []map[string]string{}
+	for _, submatch := range p.re.FindAllStringSubmatch(text, n) {
+		m := make(map[string]string, len(p.names))
+		for i, name := range p.re.SubexpNames() {
+			if name != "" {
+				m[name] = submatch[i]
+			}
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+/*
+ * find the value of the first match
+ * it is the first group if there is any group, otherwise the whole match.
+ */
+func (p *Pattern) FindValue(text string) (string, bool) {
+	submatch := p.re.FindStringSubmatch(text)
+	if submatch == nil {
+		return "", false
+	}
+	if len(submatch) > 1 {
+		return submatch[1], true
+	}
+	return submatch[0], true
+}
@@ -0,0 +1,60 @@
+package pattern
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindAll(t *testing.T) {
+	p, err := Compile(`id=(?P<id>\d+)(?:,name=(?P<name>\w+))?`)
+	if err != nil {
+		t.Fatalf("Compile fail: %s", err)
+	}
+	if !reflect.DeepEqual(p.Names(), []string{"id", "name"}) {
+		t.Fatalf("Wrong names: %v", p.Names())
+	}
+	matches := p.FindAll("id=1,name=a; id=2; id=3,name=c", -1)
+	expected := []map[string]string{
+		{"id": "1", "name": "a"},
+		{"id": "2", "name": ""},
+		{"id": "3", "name": "c"},
+	}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Fatalf("Wrong matches: %v", matches)
+	}
+	if matches = p.FindAll("id=1 id=2", 1); len(matches) != 1 {
+		t.Fatalf("Wrong number of matches: %d", len(matches))
+	}
+	if matches = p.FindAll("nothing", -1); len(matches) != 0 {
+		t.Fatalf("Unexpected matches: %v", matches)
+	}
+}
+
+func TestFindValue(t *testing.T) {
+	cases := []struct {
+		expr  string
+		text  string
+		value string
+		found bool
+	}{
+		{`price: (\d+)`, "price: 12 yuan", "12", true},
+		{`\d+`, "price: 12 yuan", "12", true},
+		{`\d+`, "free", "", false},
+	}
+	for _, c := range cases {
+		p, err := Compile(c.expr)
+		if err != nil {
+			t.Fatalf("Compile %s fail: %s", c.expr, err)
+		}
+		value, found := p.FindValue(c.text)
+		if value != c.value || found != c.found {
+			t.Fatalf("Wrong value of %s: %q %v", c.expr, value, found)
+		}
+	}
+}
+
+func TestCompileIllegal(t *testing.T) {
+	if _, err := Compile(`(?P<id>\d+`); err == nil {
+		t.Fatalf("Expected an error")
+	}
+}
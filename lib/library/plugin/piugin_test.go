@@ -1,6 +1,14 @@
 package plugin
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
 
 var funcStr = `package main
 import "fmt"
@@ -15,3 +23,181 @@ func TestPlugin(t *testing.T) {
 	}
 	f.(func())()
 }
+
+func TestPluginCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugin-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := CacheDir()
+	SetCacheDir(dir)
+	defer SetCacheDir(old)
+
+	src := "package main\nfunc Answer() int {\n\treturn 42\n}\n"
+	if _, err = GenFuncFromStr(src, "Answer"); err != nil {
+		t.Fatal(err)
+	}
+	soPath := filepath.Join(CacheDir(), CacheKey(src)+".so")
+	if _, err = os.Stat(soPath); err != nil {
+		t.Fatalf("Plugin is not cached: %s", err)
+	}
+	f, err := GenFuncFromStr(src, "Answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer := f.(func() int)(); answer != 42 {
+		t.Fatalf("Wrong answer: %d", answer)
+	}
+	files, _ := ioutil.ReadDir(CacheDir())
+	if len(files) != 1 {
+		t.Fatalf("Unexpected files in the cache: %d", len(files))
+	}
+
+	// stale plugins are removed
+	past := time.Now().Add(-2 * DEFAULT_MAX_AGE)
+	os.Chtimes(soPath, past, past)
+	if err = Clean(DEFAULT_MAX_AGE); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(soPath); !os.IsNotExist(err) {
+		t.Fatalf("Stale plugin is not removed")
+	}
+}
+
+func TestBrokenCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugin-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := CacheDir()
+	SetCacheDir(dir)
+	defer SetCacheDir(old)
+
+	// a broken plugin in the cache is removed and compiled again
+	src := "package main\nfunc Broken() int {\n\treturn 7\n}\n"
+	soPath := filepath.Join(CacheDir(), CacheKey(src)+".so")
+	if err = os.MkdirAll(CacheDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(soPath, []byte("not a plugin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := GenFuncFromStr(src, "Broken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := f.(func() int)(); n != 7 {
+		t.Fatalf("Wrong result: %d", n)
+	}
+	files, _ := ioutil.ReadDir(CacheDir())
+	if len(files) != 1 || files[0].Name() != filepath.Base(soPath) {
+		t.Fatalf("Unexpected files in the cache: %v", files)
+	}
+}
+
+func TestCleanForeignFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugin-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := CacheDir()
+	SetCacheDir(dir)
+	defer SetCacheDir(old)
+	if CacheDir() != filepath.Join(dir, CACHE_SUBDIR) {
+		t.Fatalf("Wrong cache directory: %s", CacheDir())
+	}
+
+	key := CacheKey("package main\n")
+	stale := []string{key + ".so", key + ".1540000000000000000.so", key + ".build-123456"}
+	foreign := []string{"notes.txt", "data", "abc.so", key + ".go", "x" + key[1:] + ".so"}
+	for _, name := range append(stale, foreign...) {
+		if name == "data" || strings.Contains(name, ".build-") {
+			err = os.MkdirAll(filepath.Join(CacheDir(), name, "sub"), 0755)
+		} else if err = os.MkdirAll(CacheDir(), 0755); err == nil {
+			err = ioutil.WriteFile(filepath.Join(CacheDir(), name), []byte(name), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		past := time.Now().Add(-2 * DEFAULT_MAX_AGE)
+		os.Chtimes(filepath.Join(CacheDir(), name), past, past)
+	}
+	// the files beside the cache are never touched
+	if err = ioutil.WriteFile(filepath.Join(dir, key+".so"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Clean(DEFAULT_MAX_AGE); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range stale {
+		if _, err = os.Stat(filepath.Join(CacheDir(), name)); !os.IsNotExist(err) {
+			t.Fatalf("Stale %s is not removed", name)
+		}
+	}
+	for _, name := range foreign {
+		if _, err = os.Stat(filepath.Join(CacheDir(), name)); err != nil {
+			t.Fatalf("Foreign %s is removed: %s", name, err)
+		}
+	}
+	if _, err = os.Stat(filepath.Join(dir, key+".so")); err != nil {
+		t.Fatalf("File beside the cache is removed: %s", err)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	if hostIdentity() == "" {
+		t.Fatal("Empty host identity")
+	}
+	if CacheKey("a") == CacheKey("b") || CacheKey("a") != CacheKey("a") {
+		t.Fatal("Wrong cache keys")
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugin-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := CacheDir()
+	SetCacheDir(dir)
+	defer SetCacheDir(old)
+
+	src := "package main\n\nfunc Hello() {\n\tundefinedFunc()\n}\n"
+	_, err = GenFuncFromStr(src, "Hello")
+	compileErr, ok := err.(*CompileError)
+	if !ok {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(compileErr.Diagnostics) != 1 || compileErr.Diagnostics[0].Line != 4 ||
+		!strings.Contains(compileErr.Diagnostics[0].Message, "undefinedFunc") {
+		t.Fatalf("Wrong diagnostics: %v", compileErr.Diagnostics)
+	}
+	if !strings.Contains(err.Error(), "source.go:4:") {
+		t.Fatalf("Wrong error: %s", err)
+	}
+	files, _ := ioutil.ReadDir(CacheDir())
+	if len(files) != 0 {
+		t.Fatalf("Unexpected files in the cache: %d", len(files))
+	}
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	output := "# command-line-arguments\n" +
+		"/tmp/x/abc.go:4:2: undefined: foo\n" +
+		"./abc.go:7: missing return\n"
+	expected := []Diagnostic{
+		{File: "abc.go", Line: 4, Column: 2, Message: "undefined: foo"},
+		{File: "abc.go", Line: 7, Message: "missing return"},
+	}
+	if diagnostics := ParseDiagnostics(output, ""); !reflect.DeepEqual(diagnostics, expected) {
+		t.Fatalf("Wrong diagnostics: %v", diagnostics)
+	}
+	if diagnostics := ParseDiagnostics(output, "parsers.go"); diagnostics[0].File != "parsers.go" {
+		t.Fatalf("Wrong file: %s", diagnostics[0].File)
+	}
+}
@@ -1,48 +1,326 @@
 package plugin
 
 import (
-	"errors"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"github.com/l-dandelion/yi-ants-go/lib/utils"
-	"math/rand"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"plugin"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
-var path = "./"
+// the directory holding the cache by default
+var DEFAULT_CACHE_DIR = os.TempDir()
 
-func GenFuncFromStr(sourceStr, funcName string) (interface{}, error) {
-	fileName := fmt.Sprintf("%x", rand.Uint64()) + ".go"
+// the cache is the subdirectory of the directory set by SetCacheDir,
+// so the directory could be shared with other files
+const CACHE_SUBDIR = "yi-ants-plugins"
+
+// the cached plugins unused for the duration are removed by Clean
+const DEFAULT_MAX_AGE = 7 * 24 * time.Hour
+
+// the names of the files in the cache: the plugins, the links opening them and the build directories
+var cacheFileRegexp = regexp.MustCompile(`^[0-9a-f]{32}(\.\d+)?\.so$|^[0-9a-f]{32}\.build-\d+$`)
+
+var (
+	cacheDir  = filepath.Join(DEFAULT_CACHE_DIR, CACHE_SUBDIR)
+	cacheLock sync.RWMutex
+	// lock of every key, so the same source is compiled once at a time
+	keyLocks sync.Map
+	// the cache directory is cleaned once after it is set
+	cleanOnce = &sync.Once{}
+)
+
+/*
+ * set the directory holding the plugin cache, the cache is its subdirectory CACHE_SUBDIR
+ */
+func SetCacheDir(dir string) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	cacheDir = filepath.Join(dir, CACHE_SUBDIR)
+	cleanOnce = &sync.Once{}
+}
+
+/*
+ * get the directory of the plugin cache
+ */
+func CacheDir() string {
+	cacheLock.RLock()
+	defer cacheLock.RUnlock()
+	return cacheDir
+}
+
+/*
+ * diagnostic of the compiler at a line of the source
+ */
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return d.Message
+	}
+	if d.Column == 0 {
+		return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+/*
+ * error of compiling a plugin
+ * Output is the whole output of the compiler.
+ */
+type CompileError struct {
+	Diagnostics []Diagnostic
+	Output      string
+	Err         error
+}
+
+func (e *CompileError) Error() string {
+	if len(e.Diagnostics) == 0 {
+		return fmt.Sprintf("compile plugin fail: %s", e.Err)
+	}
+	msgs := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		msgs[i] = d.String()
+	}
+	return "compile plugin fail: " + strings.Join(msgs, "; ")
+}
+
+// a diagnostic line of the compiler, such as ./a.go:3:2: undefined: x
+var diagnosticRegexp = regexp.MustCompile(`^(.*?\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+/*
+ * parse the diagnostics in the output of the compiler
+ * the file of the source is reported as fileName.
+ */
+func ParseDiagnostics(output string, fileName string) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, line := range strings.Split(output, "\n") {
+		match := diagnosticRegexp.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		d := Diagnostic{File: filepath.Base(match[1]), Message: match[4]}
+		d.Line, _ = strconv.Atoi(match[2])
+		d.Column, _ = strconv.Atoi(match[3])
+		if fileName != "" {
+			d.File = fileName
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
+
+/*
+ * get the key of the source in the cache
+ * the plugin depends on the go version, the platform and the host binary as well,
+ * since it can only be opened by the binary built with the same packages.
+ */
+func CacheKey(sourceStr string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s/%s %s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH, hostIdentity())
+	h.Write([]byte(sourceStr))
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+var (
+	hostOnce sync.Once
+	hostID   string
+)
+
+/*
+ * get the identity of the host binary
+ * it is the hash of the executable, or of the build info if the executable can't be read.
+ */
+func hostIdentity() string {
+	hostOnce.Do(func() {
+		h := sha256.New()
+		if path, err := os.Executable(); err == nil {
+			if f, err := os.Open(path); err == nil {
+				_, err = io.Copy(h, f)
+				f.Close()
+				if err == nil {
+					hostID = hex.EncodeToString(h.Sum(nil))
+					return
+				}
+			}
+		}
+		if info, ok := debug.ReadBuildInfo(); ok {
+			h.Reset()
+			fmt.Fprintf(h, "%s %s %s\n", info.Main.Path, info.Main.Version, info.Main.Sum)
+			for _, dep := range info.Deps {
+				fmt.Fprintf(h, "%s %s %s\n", dep.Path, dep.Version, dep.Sum)
+			}
+			hostID = hex.EncodeToString(h.Sum(nil))
+		}
+	})
+	return hostID
+}
 
-	dirPath := path
-	err := utils.SaveFile(dirPath, fileName, []byte(sourceStr))
+/*
+ * compile the source into a plugin and look up the function
+ * the plugin is cached by the hash of the source, so the same source is compiled only once.
+ */
+func GenFuncFromStr(sourceStr, funcName string) (interface{}, error) {
+	p, err := open(sourceStr, "source.go")
 	if err != nil {
 		return nil, err
 	}
-	defer os.Remove(fileName)
-	return GenFuncFromSource(dirPath+"/"+fileName, funcName)
+	return p.Lookup(funcName)
 }
 
+/*
+ * compile the source file into a plugin and look up the function
+ */
 func GenFuncFromSource(filePath, funcName string) (interface{}, error) {
-	fileName := fmt.Sprintf("%x", rand.Uint64()) + ".so"
-	dirPath := path
-	cmd := exec.Command("go", "build", "-o", dirPath+"/"+fileName, "-buildmode=plugin", filePath)
-	out, err := cmd.Output()
+	b, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
-	if string(out) != "" {
-		return nil, errors.New(string(out))
-	}
-	defer os.Remove(fileName)
-	p, err := plugin.Open(dirPath + "/" + fileName)
+	p, err := open(string(b), filepath.Base(filePath))
 	if err != nil {
 		return nil, err
 	}
-	f, err := p.Lookup(funcName)
+	return p.Lookup(funcName)
+}
+
+/*
+ * compile the source and open the plugin
+ * a cached plugin failing to open, such as a broken file, is removed and compiled once again.
+ */
+func open(sourceStr, fileName string) (*plugin.Plugin, error) {
+	soPath, cached, err := compile(sourceStr, fileName, false)
 	if err != nil {
 		return nil, err
 	}
-	return f, err
+	p, err := plugin.Open(soPath)
+	if err == nil || !cached {
+		return p, err
+	}
+	if soPath, _, err = compile(sourceStr, fileName, true); err != nil {
+		return nil, err
+	}
+	// the runtime remembers the failure of the path, so the new plugin is opened by another path
+	linkPath := fmt.Sprintf("%s.%d.so", strings.TrimSuffix(soPath, ".so"), time.Now().UnixNano())
+	if err = os.Link(soPath, linkPath); err != nil {
+		return nil, err
+	}
+	defer os.Remove(linkPath)
+	return plugin.Open(linkPath)
+}
+
+/*
+ * get the path of the compiled plugin of the source, compile it if it is not cached
+ * cached is true if the plugin is from the cache, and the cached plugin is removed first if evict is set.
+ * the diagnostics refer to the source as fileName.
+ */
+func compile(sourceStr, fileName string, evict bool) (soPath string, cached bool, err error) {
+	dir := CacheDir()
+	getCleanOnce().Do(func() {
+		Clean(DEFAULT_MAX_AGE)
+	})
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return "", false, err
+	}
+	key := CacheKey(sourceStr)
+	if soPath, err = filepath.Abs(filepath.Join(dir, key+".so")); err != nil {
+		return "", false, err
+	}
+
+	lock, _ := keyLocks.LoadOrStore(key, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if evict {
+		os.Remove(soPath)
+	}
+	if _, err := os.Stat(soPath); err == nil {
+		// keep the used plugin from being cleaned
+		now := time.Now()
+		os.Chtimes(soPath, now, now)
+		return soPath, true, nil
+	}
+
+	// build in a temporary directory and move the plugin into the cache,
+	// so a plugin in the cache is always complete
+	buildDir, err := ioutil.TempDir(dir, key+".build-")
+	if err != nil {
+		return "", false, err
+	}
+	defer os.RemoveAll(buildDir)
+	srcPath := filepath.Join(buildDir, key+".go")
+	if err = ioutil.WriteFile(srcPath, []byte(sourceStr), 0644); err != nil {
+		return "", false, err
+	}
+	outPath := filepath.Join(buildDir, key+".so")
+	// the source is built in the working directory, so it could import the packages of the module
+	cmd := exec.Command("go", "build", "-buildmode=plugin", "-o", outPath, srcPath)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", false, &CompileError{
+			Diagnostics: ParseDiagnostics(string(out), fileName),
+			Output:      string(out),
+			Err:         err,
+		}
+	}
+	if err = os.Rename(outPath, soPath); err != nil {
+		return "", false, err
+	}
+	return soPath, false, nil
+}
+
+/*
+ * get the once of cleaning the cache directory
+ */
+func getCleanOnce() *sync.Once {
+	cacheLock.RLock()
+	defer cacheLock.RUnlock()
+	return cleanOnce
+}
+
+/*
+ * remove the plugins unused for maxAge and the files left by broken builds from the cache
+ * a loaded plugin can't be unloaded, but removing its file is safe.
+ * only the files named by the cache are removed, other files in the directory are kept.
+ */
+func Clean(maxAge time.Duration) error {
+	dir := CacheDir()
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(-maxAge)
+	for _, info := range infos {
+		name := info.Name()
+		if !cacheFileRegexp.MatchString(name) {
+			continue
+		}
+		stale := info.ModTime().Before(deadline)
+		// a build directory is removed when its build ends, one left for an hour is broken
+		if strings.Contains(name, ".build-") {
+			stale = info.ModTime().Before(time.Now().Add(-time.Hour))
+		}
+		if stale {
+			os.RemoveAll(filepath.Join(dir, name))
+		}
+	}
+	return nil
 }
@@ -1,5 +0,0 @@
-package main
-import "fmt"
-func Hello() {
-	fmt.Println("Hello")
-}
\ No newline at end of file
@@ -0,0 +1,780 @@
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+ * function of the language, which runs under the limits of the interpreter
+ */
+type builtin func(in *interp, args []interface{}) (interface{}, error)
+
+/*
+ * builtin functions
+ *   len(x) str(x) num(x) int(x) type(x)
+ *   trim(s[, cutset]) lower(s) upper(s) split(s, sep) join(xs, sep) replace(s, old, new)
+ *   starts_with(s, prefix) ends_with(s, suffix) index_of(s, sub) slice(s|xs, start[, end])
+ *   keys(m) values(m) push(xs, v...) sort(xs) range([start, ]end)
+ *   re_match(p, s) re_find(p, s) re_find_all(p, s) re_replace(p, s, repl)
+ *   json_decode(s) json_encode(v) url_join(base, ref) format(f, args...)
+ *   abs(n) floor(n) ceil(n) round(n) min(n...) max(n...) fail(msg)
+ * re_find gets the first group of the first match (or the whole match if there is no group), nil if not found.
+ * format replaces every "{}" of f with the next argument.
+ */
+var builtins map[string]interface{}
+
+func init() {
+	builtins = map[string]interface{}{
+		"len":         builtin(builtinLen),
+		"str":         builtin(builtinStr),
+		"num":         builtin(builtinNum),
+		"int":         builtin(builtinInt),
+		"type":        builtin(builtinType),
+		"trim":        builtin(builtinTrim),
+		"lower":       stringFunc(strings.ToLower),
+		"upper":       stringFunc(strings.ToUpper),
+		"split":       builtin(builtinSplit),
+		"join":        builtin(builtinJoin),
+		"replace":     builtin(builtinReplace),
+		"starts_with": builtin(builtinStartsWith),
+		"ends_with":   builtin(builtinEndsWith),
+		"index_of":    builtin(builtinIndexOf),
+		"slice":       builtin(builtinSlice),
+		"keys":        builtin(builtinKeys),
+		"values":      builtin(builtinValues),
+		"push":        builtin(builtinPush),
+		"sort":        builtin(builtinSort),
+		"range":       builtin(builtinRange),
+		"re_match":    builtin(builtinReMatch),
+		"re_find":     builtin(builtinReFind),
+		"re_find_all": builtin(builtinReFindAll),
+		"re_replace":  builtin(builtinReReplace),
+		"json_decode": builtin(builtinJSONDecode),
+		"json_encode": builtin(builtinJSONEncode),
+		"url_join":    builtin(builtinURLJoin),
+		"format":      builtin(builtinFormat),
+		"abs":         numberFunc(math.Abs),
+		"floor":       numberFunc(math.Floor),
+		"ceil":        numberFunc(math.Ceil),
+		"round":       numberFunc(math.Round),
+		"min":         builtin(builtinMin),
+		"max":         builtin(builtinMax),
+		"fail":        builtin(builtinFail),
+	}
+}
+
+/*
+ * check the number of the arguments
+ */
+func checkArgs(args []interface{}, min, max int) error {
+	if len(args) < min {
+		return fmt.Errorf("not enough arguments")
+	}
+	if len(args) > max {
+		return fmt.Errorf("too many arguments")
+	}
+	return nil
+}
+
+func stringArg(args []interface{}, i int) (string, error) {
+	s, ok := args[i].(string)
+	if !ok {
+		return "", fmt.Errorf("argument %d must be string, not %s", i+1, typeOf(args[i]))
+	}
+	return s, nil
+}
+
+func numberArg(args []interface{}, i int) (float64, error) {
+	n, ok := args[i].(float64)
+	if !ok {
+		return 0, fmt.Errorf("argument %d must be number, not %s", i+1, typeOf(args[i]))
+	}
+	return n, nil
+}
+
+func listArg(args []interface{}, i int) ([]interface{}, error) {
+	switch list := args[i].(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		return list, nil
+	}
+	return nil, fmt.Errorf("argument %d must be list, not %s", i+1, typeOf(args[i]))
+}
+
+func mapArg(args []interface{}, i int) (map[string]interface{}, error) {
+	switch m := args[i].(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return m, nil
+	}
+	return nil, fmt.Errorf("argument %d must be map, not %s", i+1, typeOf(args[i]))
+}
+
+func stringFunc(f func(string) string) builtin {
+	return func(in *interp, args []interface{}) (interface{}, error) {
+		if err := checkArgs(args, 1, 1); err != nil {
+			return nil, err
+		}
+		s, err := stringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return f(s), nil
+	}
+}
+
+func numberFunc(f func(float64) float64) builtin {
+	return func(in *interp, args []interface{}) (interface{}, error) {
+		if err := checkArgs(args, 1, 1); err != nil {
+			return nil, err
+		}
+		n, err := numberArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return f(n), nil
+	}
+}
+
+func builtinLen(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch val := args[0].(type) {
+	case nil:
+		return float64(0), nil
+	case string:
+		return float64(runeLen(val)), nil
+	case []interface{}:
+		return float64(len(val)), nil
+	case map[string]interface{}:
+		return float64(len(val)), nil
+	}
+	return nil, fmt.Errorf("cannot get length of %s", typeOf(args[0]))
+}
+
+func builtinStr(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	return in.toString(args[0])
+}
+
+/*
+ * convert a string or a bool to number, nil if it is not a number
+ */
+func builtinNum(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch val := args[0].(type) {
+	case float64:
+		return val, nil
+	case bool:
+		if val {
+			return float64(1), nil
+		}
+		return float64(0), nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			return nil, nil
+		}
+		return n, nil
+	}
+	return nil, nil
+}
+
+func builtinInt(in *interp, args []interface{}) (interface{}, error) {
+	n, err := builtinNum(in, args)
+	if n == nil || err != nil {
+		return n, err
+	}
+	return math.Trunc(n.(float64)), nil
+}
+
+func builtinType(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	return typeOf(args[0]), nil
+}
+
+func builtinTrim(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, 2); err != nil {
+		return nil, err
+	}
+	if args[0] == nil {
+		return "", nil
+	}
+	s, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 1 {
+		return strings.TrimSpace(s), nil
+	}
+	cutset, err := stringArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Trim(s, cutset), nil
+}
+
+func builtinSplit(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 2, 2); err != nil {
+		return nil, err
+	}
+	s, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	sep, err := stringArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(s, sep)
+	list := make([]interface{}, len(parts))
+	for i, part := range parts {
+		list[i] = part
+	}
+	return list, nil
+}
+
+func builtinJoin(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 2, 2); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	sep, err := stringArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for i, elem := range list {
+		if i > 0 {
+			buf.WriteString(sep)
+		}
+		s, err := in.toString(elem)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(s)
+		if err = in.checkLen(buf.Len()); err != nil {
+			return nil, err
+		}
+	}
+	return buf.String(), nil
+}
+
+func builtinReplace(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 3, 3); err != nil {
+		return nil, err
+	}
+	strs := make([]string, 3)
+	for i := range strs {
+		s, err := stringArg(args, i)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+	s, old, new := strs[0], strs[1], strs[2]
+	if n := strings.Count(s, old); len(new) > len(old) {
+		if err := in.checkLen(len(s) + n*(len(new)-len(old))); err != nil {
+			return nil, err
+		}
+	}
+	return strings.Replace(s, old, new, -1), nil
+}
+
+func builtinStartsWith(in *interp, args []interface{}) (interface{}, error) {
+	s, sub, err := twoStrings(args)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasPrefix(s, sub), nil
+}
+
+func builtinEndsWith(in *interp, args []interface{}) (interface{}, error) {
+	s, sub, err := twoStrings(args)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasSuffix(s, sub), nil
+}
+
+/*
+ * get the index of the substring in characters, -1 if not found
+ */
+func builtinIndexOf(in *interp, args []interface{}) (interface{}, error) {
+	s, sub, err := twoStrings(args)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.Index(s, sub)
+	if i < 0 {
+		return float64(-1), nil
+	}
+	return float64(runeLen(s[:i])), nil
+}
+
+func twoStrings(args []interface{}) (string, string, error) {
+	if err := checkArgs(args, 2, 2); err != nil {
+		return "", "", err
+	}
+	s, err := stringArg(args, 0)
+	if err != nil {
+		return "", "", err
+	}
+	sub, err := stringArg(args, 1)
+	return s, sub, err
+}
+
+/*
+ * get the part of a string (in characters) or a list from start to end
+ * negative positions count from the end, and the positions are clamped.
+ */
+func builtinSlice(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 2, 3); err != nil {
+		return nil, err
+	}
+	var length int
+	var runes []rune
+	switch val := args[0].(type) {
+	case string:
+		runes = []rune(val)
+		length = len(runes)
+	case []interface{}:
+		length = len(val)
+	default:
+		return nil, fmt.Errorf("cannot slice %s", typeOf(args[0]))
+	}
+	bounds := []int{0, length}
+	for i := 1; i < len(args); i++ {
+		n, err := numberArg(args, i)
+		if err != nil {
+			return nil, err
+		}
+		pos := int(n)
+		if pos < 0 {
+			pos += length
+		}
+		if pos < 0 {
+			pos = 0
+		}
+		if pos > length {
+			pos = length
+		}
+		bounds[i-1] = pos
+	}
+	start, end := bounds[0], bounds[1]
+	if end < start {
+		end = start
+	}
+	if list, ok := args[0].([]interface{}); ok {
+		return append([]interface{}{}, list[start:end]...), nil
+	}
+	return string(runes[start:end]), nil
+}
+
+func builtinKeys(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	m, err := mapArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	list := []interface{}{}
+	for _, key := range sortedKeys(m) {
+		list = append(list, key)
+	}
+	return list, nil
+}
+
+/*
+ * get the values of the map in the order of the keys
+ */
+func builtinValues(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	m, err := mapArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	list := []interface{}{}
+	for _, key := range sortedKeys(m) {
+		list = append(list, m[key])
+	}
+	return list, nil
+}
+
+/*
+ * append the values to the list and return the list, like append of go
+ */
+func builtinPush(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, math.MaxInt32); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err = in.checkLen(len(list) + len(args) - 1); err != nil {
+		return nil, err
+	}
+	return append(list, args[1:]...), nil
+}
+
+/*
+ * sort a list of numbers or strings into a new list
+ */
+func builtinSort(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	sorted := append([]interface{}{}, list...)
+	var sortErr error
+	sort.SliceStable(sorted, func(i, j int) bool {
+		switch x := sorted[i].(type) {
+		case float64:
+			if y, ok := sorted[j].(float64); ok {
+				return x < y
+			}
+		case string:
+			if y, ok := sorted[j].(string); ok {
+				return x < y
+			}
+		}
+		sortErr = fmt.Errorf("cannot compare %s and %s", typeOf(sorted[i]), typeOf(sorted[j]))
+		return false
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+	return sorted, nil
+}
+
+/*
+ * get the list of the integers from start (0 by default) to end (excluded)
+ */
+func builtinRange(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, 2); err != nil {
+		return nil, err
+	}
+	bounds := make([]float64, len(args))
+	for i := range args {
+		n, err := numberArg(args, i)
+		if err != nil {
+			return nil, err
+		}
+		bounds[i] = math.Trunc(n)
+	}
+	start, end := float64(0), bounds[0]
+	if len(bounds) == 2 {
+		start, end = bounds[0], bounds[1]
+	}
+	if end < start {
+		end = start
+	}
+	if err := in.checkLen(int(math.Min(end-start, math.MaxInt32))); err != nil {
+		return nil, err
+	}
+	list := make([]interface{}, 0, int(end-start))
+	for n := start; n < end; n++ {
+		list = append(list, n)
+	}
+	return list, nil
+}
+
+/*
+ * get the compiled regular expression of the run
+ */
+func (in *interp) regexp(args []interface{}) (*regexp.Regexp, string, error) {
+	p, s, err := twoStrings(args)
+	if err != nil {
+		return nil, "", err
+	}
+	if in.regexps == nil {
+		in.regexps = map[string]*regexp.Regexp{}
+	}
+	re, ok := in.regexps[p]
+	if !ok {
+		if re, err = regexp.Compile(p); err != nil {
+			return nil, "", err
+		}
+		in.regexps[p] = re
+	}
+	return re, s, nil
+}
+
+func builtinReMatch(in *interp, args []interface{}) (interface{}, error) {
+	re, s, err := in.regexp(args)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString(s), nil
+}
+
+func builtinReFind(in *interp, args []interface{}) (interface{}, error) {
+	re, s, err := in.regexp(args)
+	if err != nil {
+		return nil, err
+	}
+	match := re.FindStringSubmatch(s)
+	if match == nil {
+		return nil, nil
+	}
+	return matchValue(match), nil
+}
+
+func builtinReFindAll(in *interp, args []interface{}) (interface{}, error) {
+	re, s, err := in.regexp(args)
+	if err != nil {
+		return nil, err
+	}
+	list := []interface{}{}
+	for _, match := range re.FindAllStringSubmatch(s, -1) {
+		list = append(list, matchValue(match))
+	}
+	return list, nil
+}
+
+/*
+ * the first group of the match, or the whole match if there is no group
+ */
+func matchValue(match []string) string {
+	if len(match) > 1 {
+		return match[1]
+	}
+	return match[0]
+}
+
+/*
+ * replace the matches with the template, where $1 or ${name} is a group
+ */
+func builtinReReplace(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 3, 3); err != nil {
+		return nil, err
+	}
+	re, s, err := in.regexp(args[:2])
+	if err != nil {
+		return nil, err
+	}
+	repl, err := stringArg(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	result := []byte{}
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+		result = append(result, s[last:match[0]]...)
+		result = re.ExpandString(result, repl, s, match)
+		last = match[1]
+		if err = in.checkLen(len(result)); err != nil {
+			return nil, err
+		}
+	}
+	result = append(result, s[last:]...)
+	if err = in.checkLen(len(result)); err != nil {
+		return nil, err
+	}
+	return string(result), nil
+}
+
+func builtinJSONDecode(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	s, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if err = json.Unmarshal([]byte(s), &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func builtinJSONEncode(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	return in.encodeJSON(args[0])
+}
+
+/*
+ * resolve the reference against the base url
+ */
+func builtinURLJoin(in *interp, args []interface{}) (interface{}, error) {
+	base, ref, err := twoStrings(args)
+	if err != nil {
+		return nil, err
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	refURL, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return nil, err
+	}
+	return baseURL.ResolveReference(refURL).String(), nil
+}
+
+func builtinFormat(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, math.MaxInt32); err != nil {
+		return nil, err
+	}
+	f, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(f, "{}")
+	var buf bytes.Buffer
+	for i, part := range parts {
+		buf.WriteString(part)
+		if i == len(parts)-1 {
+			break
+		}
+		if i+1 < len(args) {
+			s, err := in.toString(args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			buf.WriteString(s)
+		}
+		if err = in.checkLen(buf.Len()); err != nil {
+			return nil, err
+		}
+	}
+	return buf.String(), nil
+}
+
+func builtinMin(in *interp, args []interface{}) (interface{}, error) {
+	return extremum(args, func(x, y float64) bool { return x < y })
+}
+
+func builtinMax(in *interp, args []interface{}) (interface{}, error) {
+	return extremum(args, func(x, y float64) bool { return x > y })
+}
+
+/*
+ * get the extremum of the numbers, or of the numbers in a list
+ */
+func extremum(args []interface{}, better func(x, y float64) bool) (interface{}, error) {
+	if len(args) == 1 {
+		if list, ok := args[0].([]interface{}); ok {
+			args = list
+		}
+	}
+	var result interface{}
+	for i := range args {
+		n, err := numberArg(args, i)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil || better(n, result.(float64)) {
+			result = n
+		}
+	}
+	return result, nil
+}
+
+/*
+ * stop the script with the message
+ */
+func builtinFail(in *interp, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	msg, err := in.toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return nil, errors.New(msg)
+}
+
+/*
+ * encode the value in json, the length of the result is limited
+ * the keys of maps are sorted.
+ */
+func (in *interp) encodeJSON(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := in.writeJSON(&buf, v, 0); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (in *interp) writeJSON(buf *bytes.Buffer, v interface{}, depth int) error {
+	if depth > maxEqualDepth {
+		return fmt.Errorf("value too deep to encode")
+	}
+	if err := in.checkLen(buf.Len()); err != nil {
+		return err
+	}
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(val))
+	case float64:
+		if math.IsInf(val, 0) || math.IsNaN(val) {
+			return fmt.Errorf("cannot encode %v", val)
+		}
+		buf.WriteString(formatNumber(val))
+	case string:
+		b, _ := json.Marshal(val)
+		buf.Write(b)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range val {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := in.writeJSON(buf, elem, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		buf.WriteByte('{')
+		for i, key := range sortedKeys(val) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			b, _ := json.Marshal(key)
+			buf.Write(b)
+			buf.WriteByte(':')
+			if err := in.writeJSON(buf, val[key], depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("cannot encode %s", typeOf(v))
+	}
+	return in.checkLen(buf.Len())
+}
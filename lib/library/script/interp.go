@@ -0,0 +1,794 @@
+package script
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// control flow after a statement
+const (
+	ctrlNone = iota
+	ctrlBreak
+	ctrlContinue
+	ctrlReturn
+)
+
+/*
+ * scope of variables
+ */
+type env struct {
+	vars   map[string]interface{}
+	parent *env
+}
+
+func newEnv(parent *env) *env {
+	return &env{vars: map[string]interface{}{}, parent: parent}
+}
+
+func (e *env) lookup(name string) (*env, bool) {
+	for ; e != nil; e = e.parent {
+		if _, ok := e.vars[name]; ok {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+/*
+ * function defined by the script
+ */
+type closure struct {
+	fn  *funcExpr
+	env *env
+}
+
+/*
+ * state of a run
+ */
+type interp struct {
+	limits   Limits
+	deadline time.Time
+	steps    int
+	depth    int
+	regexps  map[string]*regexp.Regexp
+}
+
+/*
+ * error at a line of the script
+ * the errors of the limits are not wrapped.
+ */
+func lineError(line int, err error) error {
+	switch err {
+	case ErrStepLimit, ErrTimeout, ErrLenLimit, ErrDepthLimit:
+		return err
+	}
+	if _, ok := err.(*scriptError); ok {
+		return err
+	}
+	return &scriptError{line: line, err: err}
+}
+
+type scriptError struct {
+	line int
+	err  error
+}
+
+func (e *scriptError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.err)
+}
+
+/*
+ * count a step and check the limits
+ */
+func (in *interp) step() error {
+	in.steps++
+	if in.steps > in.limits.MaxSteps {
+		return ErrStepLimit
+	}
+	if in.steps&1023 == 0 && time.Now().After(in.deadline) {
+		return ErrTimeout
+	}
+	return nil
+}
+
+/*
+ * check the length of a string or a list
+ */
+func (in *interp) checkLen(n int) error {
+	if n > in.limits.MaxLen {
+		return ErrLenLimit
+	}
+	return nil
+}
+
+func (in *interp) execBlock(stmts []interface{}, e *env) (int, interface{}, error) {
+	for _, stmt := range stmts {
+		ctrl, val, err := in.exec(stmt, e)
+		if err != nil || ctrl != ctrlNone {
+			return ctrl, val, err
+		}
+	}
+	return ctrlNone, nil, nil
+}
+
+func (in *interp) exec(stmt interface{}, e *env) (int, interface{}, error) {
+	if err := in.step(); err != nil {
+		return ctrlNone, nil, err
+	}
+	switch s := stmt.(type) {
+	case *letStmt:
+		val, err := in.eval(s.x, e)
+		if err != nil {
+			return ctrlNone, nil, err
+		}
+		e.vars[s.name] = val
+	case *assignStmt:
+		if err := in.assign(s, e); err != nil {
+			return ctrlNone, nil, lineError(s.line, err)
+		}
+	case *exprStmt:
+		if _, err := in.eval(s.x, e); err != nil {
+			return ctrlNone, nil, err
+		}
+	case *ifStmt:
+		cond, err := in.eval(s.cond, e)
+		if err != nil {
+			return ctrlNone, nil, err
+		}
+		if truth(cond) {
+			return in.execBlock(s.then, newEnv(e))
+		}
+		return in.execBlock(s.els, newEnv(e))
+	case *whileStmt:
+		for {
+			cond, err := in.eval(s.cond, e)
+			if err != nil {
+				return ctrlNone, nil, err
+			}
+			if !truth(cond) {
+				break
+			}
+			ctrl, val, err := in.execBlock(s.body, newEnv(e))
+			if err != nil || ctrl == ctrlReturn {
+				return ctrl, val, err
+			}
+			if ctrl == ctrlBreak {
+				break
+			}
+		}
+	case *forStmt:
+		return in.execFor(s, e)
+	case *returnStmt:
+		if s.x == nil {
+			return ctrlReturn, nil, nil
+		}
+		val, err := in.eval(s.x, e)
+		if err != nil {
+			return ctrlNone, nil, err
+		}
+		return ctrlReturn, val, nil
+	case *breakStmt:
+		return ctrlBreak, nil, nil
+	case *continueStmt:
+		return ctrlContinue, nil, nil
+	}
+	return ctrlNone, nil, nil
+}
+
+/*
+ * iterate a list (index and element), a map (sorted key and value) or a string (index and character)
+ */
+func (in *interp) execFor(s *forStmt, e *env) (int, interface{}, error) {
+	x, err := in.eval(s.x, e)
+	if err != nil {
+		return ctrlNone, nil, err
+	}
+	var keys, vals []interface{}
+	switch val := x.(type) {
+	case nil:
+	case []interface{}:
+		for i, elem := range val {
+			keys = append(keys, float64(i))
+			vals = append(vals, elem)
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(val) {
+			keys = append(keys, key)
+			vals = append(vals, val[key])
+		}
+	case string:
+		i := 0
+		for _, r := range val {
+			keys = append(keys, float64(i))
+			vals = append(vals, string(r))
+			i++
+		}
+	default:
+		return ctrlNone, nil, lineError(s.line, fmt.Errorf("cannot iterate %s", typeOf(x)))
+	}
+	for i := range keys {
+		body := newEnv(e)
+		if s.val == "" {
+			// "for v in xs" gets the elements of lists and the keys of maps
+			if _, ok := x.(map[string]interface{}); ok {
+				body.vars[s.key] = keys[i]
+			} else {
+				body.vars[s.key] = vals[i]
+			}
+		} else {
+			body.vars[s.key] = keys[i]
+			body.vars[s.val] = vals[i]
+		}
+		ctrl, val, err := in.execBlock(s.body, body)
+		if err != nil || ctrl == ctrlReturn {
+			return ctrl, val, err
+		}
+		if ctrl == ctrlBreak {
+			break
+		}
+	}
+	return ctrlNone, nil, nil
+}
+
+func (in *interp) assign(s *assignStmt, e *env) error {
+	val, err := in.eval(s.x, e)
+	if err != nil {
+		return err
+	}
+	switch target := s.target.(type) {
+	case *identExpr:
+		scope, ok := e.lookup(target.name)
+		if !ok {
+			return fmt.Errorf("undefined: %s", target.name)
+		}
+		scope.vars[target.name] = val
+	case *memberExpr:
+		x, err := in.eval(target.x, e)
+		if err != nil {
+			return err
+		}
+		m, ok := x.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot set field %s of %s", target.name, typeOf(x))
+		}
+		m[target.name] = val
+	case *indexExpr:
+		x, err := in.eval(target.x, e)
+		if err != nil {
+			return err
+		}
+		index, err := in.eval(target.index, e)
+		if err != nil {
+			return err
+		}
+		switch container := x.(type) {
+		case map[string]interface{}:
+			key, ok := index.(string)
+			if !ok {
+				return fmt.Errorf("map key must be string, not %s", typeOf(index))
+			}
+			container[key] = val
+		case []interface{}:
+			i, ok := listIndex(index, len(container))
+			if !ok {
+				return fmt.Errorf("index %s out of range", toString(index))
+			}
+			container[i] = val
+		default:
+			return fmt.Errorf("cannot set index of %s", typeOf(x))
+		}
+	}
+	return nil
+}
+
+func (in *interp) eval(x interface{}, e *env) (interface{}, error) {
+	if err := in.step(); err != nil {
+		return nil, err
+	}
+	switch expr := x.(type) {
+	case *literalExpr:
+		return expr.value, nil
+	case *identExpr:
+		scope, ok := e.lookup(expr.name)
+		if !ok {
+			return nil, lineError(expr.line, fmt.Errorf("undefined: %s", expr.name))
+		}
+		return scope.vars[expr.name], nil
+	case *listExpr:
+		list := make([]interface{}, 0, len(expr.elems))
+		for _, elem := range expr.elems {
+			val, err := in.eval(elem, e)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, val)
+		}
+		return list, nil
+	case *mapExpr:
+		m := make(map[string]interface{}, len(expr.keys))
+		for i, key := range expr.keys {
+			val, err := in.eval(expr.vals[i], e)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = val
+		}
+		return m, nil
+	case *funcExpr:
+		return &closure{fn: expr, env: e}, nil
+	case *unaryExpr:
+		val, err := in.eval(expr.x, e)
+		if err != nil {
+			return nil, err
+		}
+		if expr.op == "!" {
+			return !truth(val), nil
+		}
+		num, ok := val.(float64)
+		if !ok {
+			return nil, lineError(expr.line, fmt.Errorf("cannot negate %s", typeOf(val)))
+		}
+		return -num, nil
+	case *binaryExpr:
+		return in.evalBinary(expr, e)
+	case *memberExpr:
+		val, err := in.eval(expr.x, e)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, lineError(expr.line, fmt.Errorf("cannot get field %s of %s", expr.name, typeOf(val)))
+		}
+		return m[expr.name], nil
+	case *indexExpr:
+		val, err := in.eval(expr.x, e)
+		if err != nil {
+			return nil, err
+		}
+		index, err := in.eval(expr.index, e)
+		if err != nil {
+			return nil, err
+		}
+		result, err := getIndex(val, index)
+		if err != nil {
+			return nil, lineError(expr.line, err)
+		}
+		return result, nil
+	case *callExpr:
+		fn, err := in.eval(expr.fn, e)
+		if err != nil {
+			return nil, err
+		}
+		args := make([]interface{}, 0, len(expr.args))
+		for _, arg := range expr.args {
+			val, err := in.eval(arg, e)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, val)
+		}
+		result, err := in.call(fn, args)
+		if err != nil {
+			return nil, lineError(expr.line, err)
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("unknown expression %T", x)
+}
+
+/*
+ * call a function of the script or the host
+ */
+func (in *interp) call(fn interface{}, args []interface{}) (interface{}, error) {
+	switch f := fn.(type) {
+	case builtin:
+		return f(in, args)
+	case Func:
+		result, err := f(args)
+		if err != nil {
+			return nil, err
+		}
+		result = Value(result)
+		if err = in.checkValue(result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	case *closure:
+		if len(args) > len(f.fn.params) {
+			return nil, fmt.Errorf("too many arguments to %s", f.name())
+		}
+		in.depth++
+		defer func() { in.depth-- }()
+		if in.depth > in.limits.MaxDepth {
+			return nil, ErrDepthLimit
+		}
+		body := newEnv(f.env)
+		// missing arguments are nil
+		for i, param := range f.fn.params {
+			if i < len(args) {
+				body.vars[param] = args[i]
+			} else {
+				body.vars[param] = nil
+			}
+		}
+		ctrl, result, err := in.execBlock(f.fn.body, body)
+		if err != nil {
+			return nil, err
+		}
+		if ctrl == ctrlBreak || ctrl == ctrlContinue {
+			return nil, fmt.Errorf("break or continue outside a loop")
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("cannot call %s", typeOf(fn))
+}
+
+func (c *closure) name() string {
+	if c.fn.name == "" {
+		return "func"
+	}
+	return c.fn.name
+}
+
+/*
+ * check the length of the string or list returned by the host
+ */
+func (in *interp) checkValue(v interface{}) error {
+	switch val := v.(type) {
+	case string:
+		return in.checkLen(len(val))
+	case []interface{}:
+		return in.checkLen(len(val))
+	}
+	return nil
+}
+
+func (in *interp) evalBinary(expr *binaryExpr, e *env) (interface{}, error) {
+	x, err := in.eval(expr.x, e)
+	if err != nil {
+		return nil, err
+	}
+	// short circuit, the result is the last evaluated operand
+	switch expr.op {
+	case "||":
+		if truth(x) {
+			return x, nil
+		}
+		return in.eval(expr.y, e)
+	case "&&":
+		if !truth(x) {
+			return x, nil
+		}
+		return in.eval(expr.y, e)
+	}
+	y, err := in.eval(expr.y, e)
+	if err != nil {
+		return nil, err
+	}
+	result, err := in.binary(expr.op, x, y)
+	if err != nil {
+		return nil, lineError(expr.line, err)
+	}
+	return result, nil
+}
+
+func (in *interp) binary(op string, x, y interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return in.equal(x, y, 0)
+	case "!=":
+		eq, err := in.equal(x, y, 0)
+		return !eq, err
+	case "in":
+		return in.contains(y, x)
+	}
+	xn, xIsNum := x.(float64)
+	yn, yIsNum := y.(float64)
+	if xIsNum && yIsNum {
+		switch op {
+		case "+":
+			return xn + yn, nil
+		case "-":
+			return xn - yn, nil
+		case "*":
+			return xn * yn, nil
+		case "/":
+			if yn == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return xn / yn, nil
+		case "%":
+			if yn == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return math.Mod(xn, yn), nil
+		case "<":
+			return xn < yn, nil
+		case "<=":
+			return xn <= yn, nil
+		case ">":
+			return xn > yn, nil
+		case ">=":
+			return xn >= yn, nil
+		}
+	}
+	xs, xIsStr := x.(string)
+	ys, yIsStr := y.(string)
+	if xIsStr && yIsStr {
+		switch op {
+		case "<":
+			return xs < ys, nil
+		case "<=":
+			return xs <= ys, nil
+		case ">":
+			return xs > ys, nil
+		case ">=":
+			return xs >= ys, nil
+		}
+	}
+	if op == "+" {
+		// a string joins the other operand as a string
+		if xIsStr || yIsStr {
+			xs, err := in.toString(x)
+			if err != nil {
+				return nil, err
+			}
+			ys, err := in.toString(y)
+			if err != nil {
+				return nil, err
+			}
+			if err = in.checkLen(len(xs) + len(ys)); err != nil {
+				return nil, err
+			}
+			return xs + ys, nil
+		}
+		xl, xIsList := x.([]interface{})
+		yl, yIsList := y.([]interface{})
+		if xIsList && yIsList {
+			if err := in.checkLen(len(xl) + len(yl)); err != nil {
+				return nil, err
+			}
+			list := make([]interface{}, 0, len(xl)+len(yl))
+			return append(append(list, xl...), yl...), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid operation: %s %s %s", typeOf(x), op, typeOf(y))
+}
+
+/*
+ * get the element of a list, the value of a map or the character of a string
+ * a negative index counts from the end, and nil is got out of range.
+ */
+func getIndex(x, index interface{}) (interface{}, error) {
+	switch val := x.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		if i, ok := listIndex(index, len(val)); ok {
+			return val[i], nil
+		}
+		if _, ok := index.(float64); !ok {
+			return nil, fmt.Errorf("list index must be number, not %s", typeOf(index))
+		}
+		return nil, nil
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("map key must be string, not %s", typeOf(index))
+		}
+		return val[key], nil
+	case string:
+		runes := []rune(val)
+		if i, ok := listIndex(index, len(runes)); ok {
+			return string(runes[i]), nil
+		}
+		if _, ok := index.(float64); !ok {
+			return nil, fmt.Errorf("string index must be number, not %s", typeOf(index))
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("cannot index %s", typeOf(x))
+}
+
+/*
+ * get the index of a list of the length
+ */
+func listIndex(index interface{}, length int) (int, bool) {
+	f, ok := index.(float64)
+	if !ok {
+		return 0, false
+	}
+	i := int(f)
+	if i < 0 {
+		i += length
+	}
+	return i, i >= 0 && i < length && float64(int(f)) == f
+}
+
+/*
+ * check whether the container has the element
+ * a list has the equal element, a map has the key and a string has the substring.
+ */
+func (in *interp) contains(container, elem interface{}) (bool, error) {
+	switch val := container.(type) {
+	case nil:
+		return false, nil
+	case []interface{}:
+		for _, e := range val {
+			eq, err := in.equal(e, elem, 0)
+			if err != nil || eq {
+				return eq, err
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		key, ok := elem.(string)
+		if !ok {
+			return false, nil
+		}
+		_, ok = val[key]
+		return ok, nil
+	case string:
+		sub, ok := elem.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot find %s in string", typeOf(elem))
+		}
+		return strings.Contains(val, sub), nil
+	}
+	return false, fmt.Errorf("cannot find in %s", typeOf(container))
+}
+
+// max depth of the compared lists and maps, which may contain themselves
+const maxEqualDepth = 100
+
+/*
+ * compare the values deeply, every compared element is a step
+ */
+func (in *interp) equal(x, y interface{}, depth int) (bool, error) {
+	if depth > maxEqualDepth {
+		return false, nil
+	}
+	switch xv := x.(type) {
+	case []interface{}:
+		yv, ok := y.([]interface{})
+		if !ok || len(xv) != len(yv) {
+			return false, nil
+		}
+		for i := range xv {
+			if err := in.step(); err != nil {
+				return false, err
+			}
+			if eq, err := in.equal(xv[i], yv[i], depth+1); err != nil || !eq {
+				return false, err
+			}
+		}
+		return true, nil
+	case map[string]interface{}:
+		yv, ok := y.(map[string]interface{})
+		if !ok || len(xv) != len(yv) {
+			return false, nil
+		}
+		for key, val := range xv {
+			if err := in.step(); err != nil {
+				return false, err
+			}
+			other, ok := yv[key]
+			if !ok {
+				return false, nil
+			}
+			if eq, err := in.equal(val, other, depth+1); err != nil || !eq {
+				return false, err
+			}
+		}
+		return true, nil
+	case Func, builtin:
+		return false, nil
+	}
+	switch y.(type) {
+	case []interface{}, map[string]interface{}, Func, builtin:
+		return false, nil
+	}
+	return x == y, nil
+}
+
+/*
+ * truth of a value, nil, false, 0, "" and empty lists and maps are false
+ */
+func truth(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case float64:
+		return val != 0
+	case string:
+		return val != ""
+	case []interface{}:
+		return len(val) > 0
+	case map[string]interface{}:
+		return len(val) > 0
+	}
+	return true
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	case Func, builtin, *closure:
+		return "func"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+/*
+ * convert a value to string, integral numbers have no decimal point
+ * lists and maps are encoded in json.
+ */
+func (in *interp) toString(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case []interface{}, map[string]interface{}:
+		return in.encodeJSON(val)
+	}
+	return toString(v), nil
+}
+
+/*
+ * convert a value other than lists and maps to string
+ */
+func toString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return formatNumber(val)
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	case Func, builtin, *closure:
+		return "func"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func formatNumber(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1e15 {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// length of a string in characters
+func runeLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
@@ -0,0 +1,144 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// kind of a token
+const (
+	tokEOF = iota
+	tokIdent
+	tokKeyword
+	tokNumber
+	tokString
+	tokOp
+)
+
+// reserved words
+var keywords = map[string]bool{
+	"let": true, "if": true, "else": true, "for": true, "in": true, "while": true,
+	"func": true, "return": true, "break": true, "continue": true,
+	"true": true, "false": true, "nil": true,
+}
+
+// operators of two characters, checked before the ones of one character
+var operators2 = []string{"==", "!=", "<=", ">=", "&&", "||"}
+
+// operators and punctuations of one character
+const operators1 = "+-*/%<>=!()[]{},.:;"
+
+/*
+ * token of the source
+ */
+type token struct {
+	kind int
+	text string
+	num  float64
+	line int
+}
+
+/*
+ * split the source into tokens
+ * comments start with "#" or "//" and end at the end of the line.
+ * strings are quoted by ' or " with the escapes of go, or by ` as raw strings.
+ */
+func tokenize(src string) ([]token, error) {
+	tokens := []token{}
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			num, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: illegal number %q", line, src[start:i])
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], num: num, line: line})
+		case c == '"' || c == '\'' || c == '`':
+			text, n, err := scanString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+			line += strings.Count(src[i:i+n], "\n")
+			i += n
+			tokens = append(tokens, token{kind: tokString, text: text, line: line})
+		case c == '_' || c < utf8.RuneSelf && unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] < utf8.RuneSelf && (unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i])))) {
+				i++
+			}
+			kind := tokIdent
+			if keywords[src[start:i]] {
+				kind = tokKeyword
+			}
+			tokens = append(tokens, token{kind: kind, text: src[start:i], line: line})
+		default:
+			op := ""
+			for _, op2 := range operators2 {
+				if strings.HasPrefix(src[i:], op2) {
+					op = op2
+					break
+				}
+			}
+			if op == "" && strings.IndexByte(operators1, c) >= 0 {
+				op = string(c)
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(src[i:])
+				return nil, fmt.Errorf("line %d: unexpected character %q", line, r)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, line: line})
+			i += len(op)
+		}
+	}
+	tokens = append(tokens, token{kind: tokEOF, line: line})
+	return tokens, nil
+}
+
+/*
+ * scan the string literal at the beginning of s
+ * return the value and the length of the literal.
+ */
+func scanString(s string) (string, int, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote != '`':
+			i++
+		case s[i] == '\n' && quote != '`':
+			return "", 0, fmt.Errorf("unterminated string")
+		case s[i] == quote:
+			if quote == '`' {
+				return s[1:i], i + 1, nil
+			}
+			literal := s[:i+1]
+			if quote == '\'' {
+				// unquote a single quoted string as a double quoted one
+				literal = `"` + strings.Replace(strings.Replace(s[1:i], `\'`, `'`, -1), `"`, `\"`, -1) + `"`
+			}
+			value, err := strconv.Unquote(literal)
+			if err != nil {
+				return "", 0, fmt.Errorf("illegal string %s", s[:i+1])
+			}
+			return value, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
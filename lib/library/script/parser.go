@@ -0,0 +1,552 @@
+package script
+
+import (
+	"fmt"
+)
+
+/*
+ * nodes of the syntax tree
+ * every node keeps the line of its source for errors.
+ */
+type (
+	literalExpr struct {
+		line  int
+		value interface{}
+	}
+	identExpr struct {
+		line int
+		name string
+	}
+	listExpr struct {
+		line  int
+		elems []interface{}
+	}
+	mapExpr struct {
+		line int
+		keys []string
+		vals []interface{}
+	}
+	unaryExpr struct {
+		line int
+		op   string
+		x    interface{}
+	}
+	binaryExpr struct {
+		line int
+		op   string
+		x, y interface{}
+	}
+	indexExpr struct {
+		line     int
+		x, index interface{}
+	}
+	memberExpr struct {
+		line int
+		x    interface{}
+		name string
+	}
+	callExpr struct {
+		line int
+		fn   interface{}
+		args []interface{}
+	}
+	funcExpr struct {
+		line   int
+		name   string
+		params []string
+		body   []interface{}
+	}
+
+	letStmt struct {
+		line int
+		name string
+		x    interface{}
+	}
+	assignStmt struct {
+		line   int
+		target interface{}
+		x      interface{}
+	}
+	exprStmt struct {
+		line int
+		x    interface{}
+	}
+	ifStmt struct {
+		line int
+		cond interface{}
+		then []interface{}
+		els  []interface{}
+	}
+	forStmt struct {
+		line     int
+		key, val string
+		x        interface{}
+		body     []interface{}
+	}
+	whileStmt struct {
+		line int
+		cond interface{}
+		body []interface{}
+	}
+	returnStmt struct {
+		line int
+		x    interface{}
+	}
+	breakStmt struct {
+		line int
+	}
+	continueStmt struct {
+		line int
+	}
+)
+
+// binary operators by precedence, from low to high
+var precedences = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "in"},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+/*
+ * recursive descent parser of the tokens
+ */
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+/*
+ * parse the source into statements
+ */
+func parse(src string) ([]interface{}, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	stmts := []interface{}{}
+	for p.peek().kind != tokEOF {
+		stmt, err := p.parseStmt()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+/*
+ * check whether the next token is the operator or keyword
+ */
+func (p *parser) is(text string) bool {
+	t := p.peek()
+	return (t.kind == tokOp || t.kind == tokKeyword) && t.text == text
+}
+
+/*
+ * consume the next token if it is the operator or keyword
+ */
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.errorf("expect %q", text)
+	}
+	return nil
+}
+
+func (p *parser) expectIdent() (string, error) {
+	t := p.peek()
+	if t.kind != tokIdent {
+		return "", p.errorf("expect a name")
+	}
+	p.next()
+	return t.text, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	found := t.text
+	if t.kind == tokEOF {
+		found = "end of script"
+	} else if t.kind == tokString {
+		found = fmt.Sprintf("%q", t.text)
+	}
+	return fmt.Errorf("line %d: %s, found %s", t.line, fmt.Sprintf(format, args...), found)
+}
+
+/*
+ * parse a statement, an optional ";" may follow it
+ */
+func (p *parser) parseStmt() (stmt interface{}, err error) {
+	t := p.peek()
+	switch {
+	case p.accept("let"):
+		var name string
+		if name, err = p.expectIdent(); err != nil {
+			return
+		}
+		if err = p.expect("="); err != nil {
+			return
+		}
+		var x interface{}
+		if x, err = p.parseExpr(); err != nil {
+			return
+		}
+		stmt = &letStmt{line: t.line, name: name, x: x}
+	case p.is("if"):
+		stmt, err = p.parseIf()
+	case p.accept("for"):
+		stmt, err = p.parseFor(t.line)
+	case p.accept("while"):
+		s := &whileStmt{line: t.line}
+		if s.cond, err = p.parseExpr(); err != nil {
+			return
+		}
+		if s.body, err = p.parseBlock(); err != nil {
+			return
+		}
+		stmt = s
+	case p.is("func") && p.tokens[p.pos+1].kind == tokIdent:
+		p.next()
+		var fn *funcExpr
+		if fn, err = p.parseFunc(t.line, p.next().text); err != nil {
+			return
+		}
+		stmt = &letStmt{line: t.line, name: fn.name, x: fn}
+	case p.accept("return"):
+		s := &returnStmt{line: t.line}
+		if !p.is(";") && !p.is("}") && p.peek().kind != tokEOF {
+			if s.x, err = p.parseExpr(); err != nil {
+				return
+			}
+		}
+		stmt = s
+	case p.accept("break"):
+		stmt = &breakStmt{line: t.line}
+	case p.accept("continue"):
+		stmt = &continueStmt{line: t.line}
+	default:
+		var x interface{}
+		if x, err = p.parseExpr(); err != nil {
+			return
+		}
+		if p.accept("=") {
+			switch x.(type) {
+			case *identExpr, *indexExpr, *memberExpr:
+			default:
+				return nil, fmt.Errorf("line %d: cannot assign to the expression", t.line)
+			}
+			s := &assignStmt{line: t.line, target: x}
+			if s.x, err = p.parseExpr(); err != nil {
+				return
+			}
+			stmt = s
+		} else {
+			stmt = &exprStmt{line: t.line, x: x}
+		}
+	}
+	if err == nil {
+		p.accept(";")
+	}
+	return
+}
+
+func (p *parser) parseIf() (interface{}, error) {
+	line := p.next().line
+	s := &ifStmt{line: line}
+	var err error
+	if s.cond, err = p.parseExpr(); err != nil {
+		return nil, err
+	}
+	if s.then, err = p.parseBlock(); err != nil {
+		return nil, err
+	}
+	if p.accept("else") {
+		if p.is("if") {
+			var elseIf interface{}
+			if elseIf, err = p.parseIf(); err != nil {
+				return nil, err
+			}
+			s.els = []interface{}{elseIf}
+		} else if s.els, err = p.parseBlock(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+/*
+ * parse "for v in x {}" or "for k, v in x {}"
+ */
+func (p *parser) parseFor(line int) (interface{}, error) {
+	s := &forStmt{line: line}
+	var err error
+	if s.key, err = p.expectIdent(); err != nil {
+		return nil, err
+	}
+	if p.accept(",") {
+		if s.val, err = p.expectIdent(); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.expect("in"); err != nil {
+		return nil, err
+	}
+	if s.x, err = p.parseExpr(); err != nil {
+		return nil, err
+	}
+	if s.body, err = p.parseBlock(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (p *parser) parseBlock() ([]interface{}, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	stmts := []interface{}{}
+	for !p.accept("}") {
+		if p.peek().kind == tokEOF {
+			return nil, p.errorf("expect %q", "}")
+		}
+		stmt, err := p.parseStmt()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+/*
+ * parse the parameters and the body of a function
+ */
+func (p *parser) parseFunc(line int, name string) (*funcExpr, error) {
+	fn := &funcExpr{line: line, name: name}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	for !p.accept(")") {
+		if len(fn.params) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		param, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		fn.params = append(fn.params, param)
+	}
+	var err error
+	if fn.body, err = p.parseBlock(); err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
+
+func (p *parser) parseExpr() (interface{}, error) {
+	return p.parseBinary(0)
+}
+
+func (p *parser) parseBinary(level int) (interface{}, error) {
+	if level == len(precedences) {
+		return p.parseUnary()
+	}
+	x, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		op := ""
+		for _, candidate := range precedences[level] {
+			if p.is(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return x, nil
+		}
+		p.next()
+		y, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryExpr{line: t.line, op: op, x: x, y: y}
+	}
+}
+
+func (p *parser) parseUnary() (interface{}, error) {
+	t := p.peek()
+	if p.accept("!") || p.accept("-") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{line: t.line, op: t.text, x: x}, nil
+	}
+	return p.parsePostfix()
+}
+
+/*
+ * parse the calls, indexes and members following a primary expression
+ */
+func (p *parser) parsePostfix() (interface{}, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case p.accept("("):
+			call := &callExpr{line: t.line, fn: x}
+			if call.args, err = p.parseList(")"); err != nil {
+				return nil, err
+			}
+			x = call
+		case p.accept("["):
+			index, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &indexExpr{line: t.line, x: x, index: index}
+		case p.accept("."):
+			name := p.peek()
+			if name.kind != tokIdent && name.kind != tokKeyword {
+				return nil, p.errorf("expect a name")
+			}
+			p.next()
+			x = &memberExpr{line: t.line, x: x, name: name.text}
+		default:
+			return x, nil
+		}
+	}
+}
+
+/*
+ * parse the expressions separated by "," until the end
+ */
+func (p *parser) parseList(end string) ([]interface{}, error) {
+	list := []interface{}{}
+	for !p.accept(end) {
+		if len(list) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			// trailing comma
+			if p.accept(end) {
+				break
+			}
+		}
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, x)
+	}
+	return list, nil
+}
+
+func (p *parser) parsePrimary() (interface{}, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokNumber:
+		p.next()
+		return &literalExpr{line: t.line, value: t.num}, nil
+	case tokString:
+		p.next()
+		return &literalExpr{line: t.line, value: t.text}, nil
+	case tokIdent:
+		p.next()
+		return &identExpr{line: t.line, name: t.text}, nil
+	}
+	switch {
+	case p.accept("true"):
+		return &literalExpr{line: t.line, value: true}, nil
+	case p.accept("false"):
+		return &literalExpr{line: t.line, value: false}, nil
+	case p.accept("nil"):
+		return &literalExpr{line: t.line, value: nil}, nil
+	case p.accept("func"):
+		return p.parseFunc(t.line, "")
+	case p.accept("("):
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		return x, nil
+	case p.accept("["):
+		elems, err := p.parseList("]")
+		if err != nil {
+			return nil, err
+		}
+		return &listExpr{line: t.line, elems: elems}, nil
+	case p.accept("{"):
+		return p.parseMap(t.line)
+	}
+	return nil, p.errorf("unexpected token")
+}
+
+/*
+ * parse a map literal, a key is a string or a name
+ */
+func (p *parser) parseMap(line int) (interface{}, error) {
+	m := &mapExpr{line: line}
+	for !p.accept("}") {
+		if len(m.keys) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			if p.accept("}") {
+				break
+			}
+		}
+		t := p.peek()
+		if t.kind != tokString && t.kind != tokIdent {
+			return nil, p.errorf("expect a key")
+		}
+		p.next()
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		val, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		m.keys = append(m.keys, t.text)
+		m.vals = append(m.vals, val)
+	}
+	return m, nil
+}
@@ -0,0 +1,274 @@
+package script
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+/*
+ * a small sandboxed script language for custom parse and process logic
+ * statements:
+ *   let x = expr              define a variable in the current block
+ *   x = expr, x[i] = expr, x.k = expr
+ *   if cond {} else if cond {} else {}
+ *   for v in xs {}, for i, v in xs {}, for k, v in m {}
+ *   while cond {}, break, continue
+ *   func f(a, b) { return a + b }
+ * expressions:
+ *   numbers, strings ('', "" or raw ``), true, false, nil, [list], {"key": value}
+ *   || && == != in < <= > >= + - * / % ! and unary -, f(x), xs[i], m.k, func(x) {}
+ * a script can only call the builtins and the functions given by the host,
+ * and it is stopped when it runs out of steps or time.
+ */
+type Program struct {
+	src   string
+	stmts []interface{}
+}
+
+/*
+ * function given by the host
+ * the arguments and the result are values of the script:
+ * nil, bool, float64, string, []interface{}, map[string]interface{} and Func.
+ */
+type Func func(args []interface{}) (interface{}, error)
+
+/*
+ * limits of a run, the defaults are used for the zero fields
+ */
+type Limits struct {
+	MaxSteps int           // max number of the evaluated statements and expressions
+	Timeout  time.Duration // max time of the run
+	MaxLen   int           // max length of a string or a list
+	MaxDepth int           // max depth of the calls
+}
+
+// default limits
+const (
+	DEFAULT_MAX_STEPS = 1000000
+	DEFAULT_TIMEOUT   = time.Second
+	DEFAULT_MAX_LEN   = 1 << 20
+	DEFAULT_MAX_DEPTH = 200
+)
+
+// errors of the limits
+var (
+	ErrStepLimit  = errors.New("script: too many steps")
+	ErrTimeout    = errors.New("script: timeout")
+	ErrLenLimit   = errors.New("script: string or list too long")
+	ErrDepthLimit = errors.New("script: calls too deep")
+)
+
+/*
+ * compile the source of a script
+ */
+func Compile(src string) (*Program, error) {
+	stmts, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{src: src, stmts: stmts}, nil
+}
+
+/*
+ * get the source of the program
+ */
+func (p *Program) String() string {
+	return p.src
+}
+
+/*
+ * run the program with the globals
+ * the globals are converted by Value, and the builtins are shadowed by them.
+ * result is the value of the top level return statement, nil if there is none.
+ */
+func (p *Program) Run(globals map[string]interface{}, limits Limits) (result interface{}, err error) {
+	if limits.MaxSteps <= 0 {
+		limits.MaxSteps = DEFAULT_MAX_STEPS
+	}
+	if limits.Timeout <= 0 {
+		limits.Timeout = DEFAULT_TIMEOUT
+	}
+	if limits.MaxLen <= 0 {
+		limits.MaxLen = DEFAULT_MAX_LEN
+	}
+	if limits.MaxDepth <= 0 {
+		limits.MaxDepth = DEFAULT_MAX_DEPTH
+	}
+	in := &interp{limits: limits, deadline: time.Now().Add(limits.Timeout)}
+	root := newEnv(nil)
+	for name, fn := range builtins {
+		root.vars[name] = fn
+	}
+	for name, val := range globals {
+		root.vars[name] = Value(val)
+	}
+	env := newEnv(root)
+	ctrl, result, err := in.execBlock(p.stmts, env)
+	if err != nil {
+		return nil, err
+	}
+	if ctrl == ctrlBreak || ctrl == ctrlContinue {
+		return nil, errors.New("break or continue outside a loop")
+	}
+	return result, nil
+}
+
+/*
+ * convert a go value to a value of the script
+ * integers and json numbers become float64, slices become []interface{},
+ * maps with string keys become map[string]interface{}, and structs go through encoding/json.
+ * slices and maps are copied only if their types differ.
+ */
+func Value(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil, bool, float64, string, Func, builtin, *closure:
+		return v
+	case func(args []interface{}) (interface{}, error):
+		return Func(val)
+	case int:
+		return float64(val)
+	case int64:
+		return float64(val)
+	case int32:
+		return float64(val)
+	case uint:
+		return float64(val)
+	case uint32:
+		return float64(val)
+	case uint64:
+		return float64(val)
+	case float32:
+		return float64(val)
+	case json.Number:
+		f, err := val.Float64()
+		if err != nil {
+			return val.String()
+		}
+		return f
+	case []byte:
+		return string(val)
+	case []interface{}:
+		for i, elem := range val {
+			val[i] = Value(elem)
+		}
+		return val
+	case map[string]interface{}:
+		for key, elem := range val {
+			val[key] = Value(elem)
+		}
+		return val
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = Value(rv.Index(i).Interface())
+		}
+		return list
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			m := make(map[string]interface{}, rv.Len())
+			for _, key := range rv.MapKeys() {
+				m[key.String()] = Value(rv.MapIndex(key).Interface())
+			}
+			return m
+		}
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var result interface{}
+	if err = json.Unmarshal(b, &result); err != nil {
+		return nil
+	}
+	return result
+}
+
+// keys of the limits in the rule of a model
+const (
+	LIMIT_MAX_STEPS = "max_steps"
+	LIMIT_TIMEOUT   = "timeout"
+	LIMIT_MAX_LEN   = "max_len"
+)
+
+/*
+ * get the limits from the rule of a model, such as {"max_steps": "100000", "timeout": "500ms"}
+ */
+func ParseLimits(rule map[string]string) (Limits, error) {
+	limits := Limits{}
+	var err error
+	if v, ok := rule[LIMIT_MAX_STEPS]; ok {
+		if limits.MaxSteps, err = strconv.Atoi(v); err != nil {
+			return limits, fmt.Errorf("%s: %s", LIMIT_MAX_STEPS, err)
+		}
+	}
+	if v, ok := rule[LIMIT_TIMEOUT]; ok {
+		if limits.Timeout, err = time.ParseDuration(v); err != nil {
+			return limits, fmt.Errorf("%s: %s", LIMIT_TIMEOUT, err)
+		}
+	}
+	if v, ok := rule[LIMIT_MAX_LEN]; ok {
+		if limits.MaxLen, err = strconv.Atoi(v); err != nil {
+			return limits, fmt.Errorf("%s: %s", LIMIT_MAX_LEN, err)
+		}
+	}
+	return limits, nil
+}
+
+// max depth of the copied lists and maps
+const maxCopyDepth = 100
+
+/*
+ * copy the lists and maps of the value deeply
+ * a script may keep changing a value after it is given to the host,
+ * and a value sharing its lists or maps may be too large to copy.
+ */
+func Copy(v interface{}) (interface{}, error) {
+	count := 0
+	return copyValue(v, 0, &count)
+}
+
+func copyValue(v interface{}, depth int, count *int) (interface{}, error) {
+	*count++
+	if *count > DEFAULT_MAX_LEN {
+		return nil, ErrLenLimit
+	}
+	if depth > maxCopyDepth {
+		return nil, errors.New("value too deep to copy")
+	}
+	switch val := v.(type) {
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, elem := range val {
+			c, err := copyValue(elem, depth+1, count)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = c
+		}
+		return list, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for key, elem := range val {
+			c, err := copyValue(elem, depth+1, count)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = c
+		}
+		return m, nil
+	case Func, builtin, *closure:
+		return nil, errors.New("cannot copy func")
+	}
+	return v, nil
+}
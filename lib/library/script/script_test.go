@@ -0,0 +1,150 @@
+package script
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func run(t *testing.T, src string, globals map[string]interface{}) interface{} {
+	p, err := Compile(src)
+	if err != nil {
+		t.Fatalf("Compile fail: %s", err)
+	}
+	result, err := p.Run(globals, Limits{})
+	if err != nil {
+		t.Fatalf("Run fail: %s", err)
+	}
+	return result
+}
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		src    string
+		result interface{}
+	}{
+		{`return 1 + 2 * 3 - 4 / 2`, float64(5)},
+		{`return 7 % 3 == 1 && !(2 > 3)`, true},
+		{`return "a" + 1 + [1, "b"]`, `a1[1,"b"]`},
+		{`let x = nil; return x || "default"`, "default"},
+		{`return "b" in {"a": 1, b: 2} && 2 in [1, 2] && "ell" in "hello"`, true},
+		{`let s = 0; for i in range(1, 5) { if i == 3 { continue }; s = s + i }; return s`, float64(7)},
+		{`let n = 0; while true { n = n + 1; if n >= 3 { break } }; return n`, float64(3)},
+		{`let r = []; for k, v in {"b": 2, "a": 1} { r = push(r, k + v) }; return r`, []interface{}{"a1", "b2"}},
+		{`func fib(n) { if n < 2 { return n }; return fib(n - 1) + fib(n - 2) }; return fib(10)`, float64(55)},
+		{`let add = func(a) { return func(b) { return a + b } }; return add(1)(2)`, float64(3)},
+		{`let m = {"a": [1, 2]}; m.a[-1] = 3; m["b"] = m.a[5]; return m`, map[string]interface{}{"a": []interface{}{float64(1), float64(3)}, "b": nil}},
+		{`if false { return 1 } else if true { return 2 } else { return 3 }`, float64(2)},
+		{`return [len("héllo"), "héllo"[1], slice("héllo", 1, -1), slice([1, 2, 3], 1)]`, []interface{}{float64(5), "é", "éll", []interface{}{float64(2), float64(3)}}},
+		{`return join(split(trim("  a,b  "), ","), "-") + upper("c") + replace("xx", "x", "y")`, "a-bCyy"},
+		{`return [num(" 1.5 "), num("x"), int("2.7"), str(3), str(0.5), type({})]`, []interface{}{1.5, nil, float64(2), "3", "0.5", "map"}},
+		{"return [re_find(`price: (\\d+)`, 'price: 12'), re_find_all(\"\\\\d+\", \"1 a 22\"), re_match(\"^a\", 'it\\'s')]", []interface{}{"12", []interface{}{"1", "22"}, false}},
+		{"return re_replace(`(\\w+)@(\\w+)`, \"a@b c@d\", \"$2@$1\")", "b@a d@c"},
+		{`return json_decode(json_encode({"b": [1, true, nil], "a": "x"}))`, map[string]interface{}{"a": "x", "b": []interface{}{float64(1), true, nil}}},
+		{`return json_encode({"b": 1, "a": "x"})`, `{"a":"x","b":1}`},
+		{`return url_join("http://a.com/x/y", "../z?p=1")`, "http://a.com/z?p=1"},
+		{`return format("{} of {}", 1, [2])`, "1 of [2]"},
+		{`return [min(3, 1, 2), max([3, 1, 2]), sort(["b", "a"]), keys({"b": 1, "a": 2}), values({"b": 1, "a": 2})]`,
+			[]interface{}{float64(1), float64(3), []interface{}{"a", "b"}, []interface{}{"a", "b"}, []interface{}{float64(2), float64(1)}}},
+		{`# comment
+		// another comment
+		let x = 1;`, nil},
+	}
+	for _, c := range cases {
+		result := run(t, c.src, nil)
+		if !reflect.DeepEqual(result, c.result) {
+			t.Fatalf("Wrong result of %s: %#v", c.src, result)
+		}
+	}
+}
+
+func TestHost(t *testing.T) {
+	emitted := []interface{}{}
+	globals := map[string]interface{}{
+		"item": map[string]interface{}{"count": 2, "tags": []string{"a", "b"}},
+		"emit": Func(func(args []interface{}) (interface{}, error) {
+			emitted = append(emitted, args...)
+			return nil, nil
+		}),
+	}
+	result := run(t, `
+		for tag in item.tags { emit(tag + item.count) }
+		item.count = item.count + 1
+		return item`, globals)
+	if !reflect.DeepEqual(emitted, []interface{}{"a2", "b2"}) {
+		t.Fatalf("Wrong emitted: %v", emitted)
+	}
+	if result.(map[string]interface{})["count"] != float64(3) {
+		t.Fatalf("Wrong result: %v", result)
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	cases := []struct {
+		src string
+		err string
+	}{
+		{"let x = 1\nlet = 2", "line 2"},
+		{"if x { ", "end of script"},
+		{`let s = "abc`, "unterminated string"},
+		{"1 + 2 = 3", "cannot assign"},
+		{"let x = @", "unexpected character"},
+	}
+	for _, c := range cases {
+		_, err := Compile(c.src)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Wrong error of %q: %v", c.src, err)
+		}
+	}
+}
+
+func TestRunError(t *testing.T) {
+	cases := []struct {
+		src string
+		err string
+	}{
+		{"let x = 1\nreturn y", "line 2: undefined: y"},
+		{`return 1 / 0`, "division by zero"},
+		{`return 1 - "a"`, "invalid operation"},
+		{`return nil.x`, "cannot get field"},
+		{`fail("bad " + 1)`, "bad 1"},
+		{`return len(1)`, "cannot get length"},
+		{`x = 1`, "undefined: x"},
+	}
+	for _, c := range cases {
+		p, err := Compile(c.src)
+		if err != nil {
+			t.Fatalf("Compile %q fail: %s", c.src, err)
+		}
+		_, err = p.Run(nil, Limits{})
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("Wrong error of %q: %v", c.src, err)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	cases := []struct {
+		src    string
+		limits Limits
+		err    error
+	}{
+		{`while true {}`, Limits{MaxSteps: 10000}, ErrStepLimit},
+		{`while true {}`, Limits{MaxSteps: 1 << 40, Timeout: 10 * time.Millisecond}, ErrTimeout},
+		{`let s = "x"; while true { s = s + s }`, Limits{MaxLen: 1024}, ErrLenLimit},
+		{`let l = [1]; while true { l = [l, l] }; `, Limits{MaxSteps: 100000}, ErrStepLimit},
+		{`let l = [1]; for i in range(30) { l = [l, l] }; return str(l)`, Limits{MaxLen: 1024}, ErrLenLimit},
+		{`func f() { return f() }; f()`, Limits{MaxDepth: 50}, ErrDepthLimit},
+		{`return range(100000)`, Limits{MaxLen: 1000}, ErrLenLimit},
+	}
+	for _, c := range cases {
+		p, err := Compile(c.src)
+		if err != nil {
+			t.Fatalf("Compile %q fail: %s", c.src, err)
+		}
+		if _, err = p.Run(nil, c.limits); err != c.err {
+			t.Fatalf("Wrong error of %q: %v", c.src, err)
+		}
+	}
+}
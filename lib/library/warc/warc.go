@@ -0,0 +1,379 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/l-dandelion/yi-ants-go/lib/utils"
+)
+
+const (
+	VERSION = "WARC/1.1"
+
+	// default max size of a warc file before rotation (1GB)
+	DEFAULT_MAX_FILE_SIZE = 1 << 30
+
+	// max number of the names tried when the names of the files exist
+	MAX_NAME_RETRIES = 100
+)
+
+// the error returned when writing to a closed writer
+var ErrClosedWriter = errors.New("closed warc writer")
+
+/*
+ * args for creating a warc writer
+ * Dir: the directory the warc files are written into
+ * Prefix: the prefix of warc file names
+ * MaxFileSize: rotate to a new file when the current one reaches this size (in bytes)
+ * Gzip: compress every record as a separate gzip member
+ */
+type Args struct {
+	Dir         string `json:"dir"`
+	Prefix      string `json:"prefix"`
+	MaxFileSize int64  `json:"max_file_size"`
+	Gzip        bool   `json:"gzip"`
+}
+
+/*
+ * check whether the args are valid
+ */
+func (args *Args) Check() error {
+	if args.Dir == "" {
+		return errors.New("empty warc directory")
+	}
+	if args.MaxFileSize < 0 {
+		return fmt.Errorf("negative max warc file size: %d", args.MaxFileSize)
+	}
+	return nil
+}
+
+/*
+ * writer of warc 1.1 files
+ * the implementation is concurrent and secure.
+ */
+type Writer struct {
+	args     Args
+	file     *os.File
+	fileName string
+	size     int64
+	serial   int
+	closed   bool
+	lock     sync.Mutex
+}
+
+/*
+ * create an instance of Writer
+ * the first warc file is created lazily on the first record.
+ */
+func NewWriter(args Args) (*Writer, error) {
+	if err := args.Check(); err != nil {
+		return nil, err
+	}
+	if args.Prefix == "" {
+		args.Prefix = "ants"
+	}
+	if args.MaxFileSize == 0 {
+		args.MaxFileSize = DEFAULT_MAX_FILE_SIZE
+	}
+	dir, err := utils.CheckDirPath(args.Dir)
+	if err != nil {
+		return nil, err
+	}
+	args.Dir = dir
+	return &Writer{args: args}, nil
+}
+
+/*
+ * get the name of the warc file being written
+ */
+func (w *Writer) FileName() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.fileName
+}
+
+/*
+ * write a request record and a response record for one fetch
+ * the request record refers to the response record by WARC-Concurrent-To.
+ */
+func (w *Writer) WriteExchange(httpReq *http.Request, httpResp *http.Response, body []byte) error {
+	date := time.Now().UTC()
+	targetURI := httpReq.URL.String()
+
+	respBlock := dumpResponse(httpResp, body)
+	respID := newRecordID()
+	respHeader := Header{
+		{"WARC-Type", "response"},
+		{"WARC-Record-ID", respID},
+		{"WARC-Date", date.Format(time.RFC3339)},
+		{"WARC-Target-URI", targetURI},
+		{"WARC-Payload-Digest", digest(body)},
+		{"Content-Type", "application/http;msgtype=response"},
+	}
+
+	reqBlock, err := dumpRequest(httpReq)
+	if err != nil {
+		return err
+	}
+	reqHeader := Header{
+		{"WARC-Type", "request"},
+		{"WARC-Record-ID", newRecordID()},
+		{"WARC-Date", date.Format(time.RFC3339)},
+		{"WARC-Target-URI", targetURI},
+		{"WARC-Concurrent-To", respID},
+		{"Content-Type", "application/http;msgtype=request"},
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	// rotate before the pair, so that a request and its response stay in one file
+	if err := w.prepareFile(); err != nil {
+		return err
+	}
+	if err := w.writeRecord(reqHeader, reqBlock); err != nil {
+		return err
+	}
+	return w.writeRecord(respHeader, respBlock)
+}
+
+/*
+ * write a record with the given header fields and content block
+ */
+func (w *Writer) WriteRecord(header Header, block []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.prepareFile(); err != nil {
+		return err
+	}
+	return w.writeRecord(header, block)
+}
+
+/*
+ * close the writer and the current warc file
+ */
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+/*
+ * make sure there is an open warc file with free space, the lock must be held
+ */
+func (w *Writer) prepareFile() error {
+	if w.closed {
+		return ErrClosedWriter
+	}
+	if w.file == nil || w.size >= w.args.MaxFileSize {
+		return w.rotate()
+	}
+	return nil
+}
+
+/*
+ * write a record into the current file, the lock must be held
+ */
+func (w *Writer) writeRecord(header Header, block []byte) error {
+	record := encodeRecord(header, block)
+	if w.args.Gzip {
+		var err error
+		record, err = gzipBytes(record)
+		if err != nil {
+			return err
+		}
+	}
+	n, err := w.file.Write(record)
+	if err != nil {
+		// drop the partial record and rotate on the next record
+		w.file.Truncate(w.size)
+		w.file.Close()
+		w.file = nil
+		return err
+	}
+	w.size += int64(n)
+	return nil
+}
+
+/*
+ * close the current file and open the next one, starting it with a warcinfo record
+ */
+func (w *Writer) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	ext := ".warc"
+	if w.args.Gzip {
+		ext += ".gz"
+	}
+	var (
+		file     *os.File
+		fileName string
+		err      error
+	)
+	// the pid tells the writers of the processes apart, the serial the files of a writer,
+	// and a name taken by another writer of the process is skipped.
+	for i := 0; i < MAX_NAME_RETRIES; i++ {
+		w.serial++
+		fileName = fmt.Sprintf("%s-%s-%d-%05d%s", w.args.Prefix,
+			time.Now().UTC().Format("20060102150405"), os.Getpid(), w.serial, ext)
+		file, err = os.OpenFile(filepath.Join(w.args.Dir, fileName),
+			os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if !os.IsExist(err) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.fileName = fileName
+	w.size = 0
+
+	info := []byte("software: yi-ants-go\r\nformat: WARC File Format 1.1\r\n")
+	return w.writeRecord(Header{
+		{"WARC-Type", "warcinfo"},
+		{"WARC-Record-ID", newRecordID()},
+		{"WARC-Date", time.Now().UTC().Format(time.RFC3339)},
+		{"WARC-Filename", fileName},
+		{"Content-Type", "application/warc-fields"},
+	}, info)
+}
+
+/*
+ * a named field of a warc record header
+ */
+type Field struct {
+	Name  string
+	Value string
+}
+
+/*
+ * ordered fields of a warc record header
+ */
+type Header []Field
+
+/*
+ * get the value of the first field named name
+ */
+func (h Header) Get(name string) string {
+	for _, field := range h {
+		if field.Name == name {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+/*
+ * encode a record: version line, header fields, content length, block and two CRLFs
+ */
+func encodeRecord(header Header, block []byte) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString(VERSION + "\r\n")
+	for _, field := range header {
+		buf.WriteString(field.Name + ": " + field.Value + "\r\n")
+	}
+	buf.WriteString("WARC-Block-Digest: " + digest(block) + "\r\n")
+	buf.WriteString("Content-Length: " + strconv.Itoa(len(block)) + "\r\n")
+	buf.WriteString("\r\n")
+	buf.Write(block)
+	buf.WriteString("\r\n\r\n")
+	return buf.Bytes()
+}
+
+/*
+ * dump the status line, headers and body of a http response
+ */
+func dumpResponse(httpResp *http.Response, body []byte) []byte {
+	buf := &bytes.Buffer{}
+	proto := httpResp.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	status := httpResp.Status
+	if status == "" {
+		status = strconv.Itoa(httpResp.StatusCode) + " " + http.StatusText(httpResp.StatusCode)
+	}
+	buf.WriteString(proto + " " + status + "\r\n")
+	httpResp.Header.Write(buf)
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+/*
+ * dump the request with its body
+ * the body of a sent request is consumed, so a new one is got by GetBody and the request is not changed.
+ * the body is left out if it could not be got again.
+ */
+func dumpRequest(httpReq *http.Request) ([]byte, error) {
+	if httpReq.Body == nil || httpReq.Body == http.NoBody || httpReq.GetBody == nil {
+		return httputil.DumpRequest(httpReq, false)
+	}
+	body, err := httpReq.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	dumpReq := *httpReq
+	dumpReq.Body = body
+	return httputil.DumpRequest(&dumpReq, true)
+}
+
+/*
+ * compress b as a single gzip member
+ */
+func gzipBytes(b []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+/*
+ * sha1 digest in the base32 form used by warc
+ */
+func digest(b []byte) string {
+	sum := sha1.Sum(b)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+/*
+ * generate a random uuid record id
+ */
+func newRecordID() string {
+	var u [16]byte
+	io.ReadFull(rand.Reader, u[:])
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
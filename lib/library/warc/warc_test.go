@@ -0,0 +1,203 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newExchange(t *testing.T) (*http.Request, *http.Response, []byte) {
+	httpReq, err := http.NewRequest("GET", "http://example.com/a?b=c", nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s", err)
+	}
+	httpResp := &http.Response{
+		Status:     "200 OK",
+		StatusCode: 200,
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{"Content-Type": []string{"text/html"}},
+	}
+	return httpReq, httpResp, []byte("<html><body>hello</body></html>")
+}
+
+func readAll(t *testing.T, dir string, gz bool) (files []string, content string) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("An error occurs when reading dir: %s", err)
+	}
+	buf := &bytes.Buffer{}
+	for _, info := range infos {
+		files = append(files, info.Name())
+		b, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			t.Fatalf("An error occurs when reading file: %s", err)
+		}
+		if gz {
+			zr, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("An error occurs when creating gzip reader: %s", err)
+			}
+			b, err = ioutil.ReadAll(zr)
+			if err != nil {
+				t.Fatalf("An error occurs when reading gzip members: %s", err)
+			}
+		}
+		buf.Write(b)
+	}
+	return files, buf.String()
+}
+
+func TestWriteExchange(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "warc")
+	defer os.RemoveAll(dir)
+	w, err := NewWriter(Args{Dir: dir, Prefix: "test"})
+	if err != nil {
+		t.Fatalf("An error occurs when creating a warc writer: %s", err)
+	}
+	httpReq, httpResp, body := newExchange(t)
+	if err := w.WriteExchange(httpReq, httpResp, body); err != nil {
+		t.Fatalf("An error occurs when writing an exchange: %s", err)
+	}
+	w.Close()
+	if err := w.WriteExchange(httpReq, httpResp, body); err != ErrClosedWriter {
+		t.Fatalf("Inconsistent error after close: expected: %s, actual: %v", ErrClosedWriter, err)
+	}
+
+	files, content := readAll(t, dir, false)
+	if len(files) != 1 || !strings.HasSuffix(files[0], ".warc") {
+		t.Fatalf("Unexpected warc files: %v", files)
+	}
+	if n := strings.Count(content, VERSION+"\r\n"); n != 3 {
+		t.Fatalf("Inconsistent record number: expected: %d, actual: %d", 3, n)
+	}
+	for _, expected := range []string{
+		"WARC-Type: warcinfo",
+		"WARC-Type: request",
+		"WARC-Type: response",
+		"WARC-Target-URI: http://example.com/a?b=c",
+		"GET /a?b=c HTTP/1.1",
+		"HTTP/1.1 200 OK",
+		string(body),
+	} {
+		if !strings.Contains(content, expected) {
+			t.Fatalf("Missing %q in warc content:\n%s", expected, content)
+		}
+	}
+}
+
+func TestRotateAndGzip(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "warc")
+	defer os.RemoveAll(dir)
+	w, err := NewWriter(Args{Dir: dir, MaxFileSize: 1, Gzip: true})
+	if err != nil {
+		t.Fatalf("An error occurs when creating a warc writer: %s", err)
+	}
+	httpReq, httpResp, body := newExchange(t)
+	for i := 0; i < 3; i++ {
+		if err := w.WriteExchange(httpReq, httpResp, body); err != nil {
+			t.Fatalf("An error occurs when writing an exchange: %s", err)
+		}
+	}
+	w.Close()
+
+	files, content := readAll(t, dir, true)
+	// every exchange exceeds the max size, so each file holds the warcinfo record and one exchange
+	if len(files) != 3 {
+		t.Fatalf("Inconsistent warc file number: expected: %d, actual: %d (%v)", 3, len(files), files)
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".warc.gz") {
+			t.Fatalf("Unexpected warc file name: %s", file)
+		}
+	}
+	if n := strings.Count(content, "WARC-Type: response"); n != 3 {
+		t.Fatalf("Inconsistent response record number: expected: %d, actual: %d", 3, n)
+	}
+}
+
+func TestArgsCheck(t *testing.T) {
+	if _, err := NewWriter(Args{}); err == nil {
+		t.Fatal("No error when creating a warc writer with empty dir!")
+	}
+	if _, err := NewWriter(Args{Dir: os.TempDir(), MaxFileSize: -1}); err == nil {
+		t.Fatal("No error when creating a warc writer with negative max file size!")
+	}
+}
+
+func TestSamePrefix(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "warc")
+	defer os.RemoveAll(dir)
+	httpReq, httpResp, body := newExchange(t)
+	for i := 0; i < 2; i++ {
+		w, err := NewWriter(Args{Dir: dir, Prefix: "test"})
+		if err != nil {
+			t.Fatalf("An error occurs when creating a warc writer: %s", err)
+		}
+		if err := w.WriteExchange(httpReq, httpResp, body); err != nil {
+			t.Fatalf("An error occurs when writing an exchange of writer %d: %s", i, err)
+		}
+		w.Close()
+	}
+	if files, _ := readAll(t, dir, false); len(files) != 2 {
+		t.Fatalf("Inconsistent warc file number: expected: %d, actual: %d (%v)", 2, len(files), files)
+	}
+}
+
+func TestRotateAfterWriteError(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "warc")
+	defer os.RemoveAll(dir)
+	w, err := NewWriter(Args{Dir: dir})
+	if err != nil {
+		t.Fatalf("An error occurs when creating a warc writer: %s", err)
+	}
+	httpReq, httpResp, body := newExchange(t)
+	if err := w.WriteExchange(httpReq, httpResp, body); err != nil {
+		t.Fatalf("An error occurs when writing an exchange: %s", err)
+	}
+	first := w.FileName()
+	// break the current file
+	w.file.Close()
+	if err := w.WriteExchange(httpReq, httpResp, body); err == nil {
+		t.Fatal("No error when writing to a broken file!")
+	}
+	if err := w.WriteExchange(httpReq, httpResp, body); err != nil {
+		t.Fatalf("An error occurs when writing after a write error: %s", err)
+	}
+	if w.FileName() == first {
+		t.Fatalf("The writer didn't rotate after a write error: %s", first)
+	}
+	w.Close()
+}
+
+func TestWriteRequestBody(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "warc")
+	defer os.RemoveAll(dir)
+	w, err := NewWriter(Args{Dir: dir, Prefix: "test"})
+	if err != nil {
+		t.Fatalf("An error occurs when creating a warc writer: %s", err)
+	}
+	httpReq, err := http.NewRequest("POST", "http://example.com/search", strings.NewReader("q=lamp"))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s", err)
+	}
+	// the body of a sent request is consumed
+	ioutil.ReadAll(httpReq.Body)
+	_, httpResp, body := newExchange(t)
+	if err := w.WriteExchange(httpReq, httpResp, body); err != nil {
+		t.Fatalf("An error occurs when writing an exchange: %s", err)
+	}
+	w.Close()
+
+	_, content := readAll(t, dir, false)
+	if !strings.Contains(content, "POST /search HTTP/1.1\r\n") || !strings.Contains(content, "\r\n\r\nq=lamp") {
+		t.Fatalf("Missing the request body in warc content:\n%s", content)
+	}
+	if b, _ := ioutil.ReadAll(httpReq.Body); len(b) != 0 {
+		t.Fatalf("The body of the request is changed: %q", b)
+	}
+}
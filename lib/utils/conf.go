@@ -8,14 +8,21 @@ import (
 
 // settings
 type Settings struct {
-	HttpPort         int
-	MulticastEnable  bool
-	Name             string
-	NodeList         []string
-	TcpPort          int
-	LogPath          string
-	ConfigFile       string
-	DownloadInterval int
+	HttpPort          int
+	MulticastEnable   bool
+	Name              string
+	NodeList          []string
+	TcpPort           int
+	LogPath           string
+	ConfigFile        string
+	DownloadInterval  int
+	HostedDownloaders int      // number of downloaders hosted for the remote downloaders of other nodes
+	HostedTimeout     int      // dial timeout in seconds of the hosted downloaders, the default of spiders if zero
+	HostedProxy       string   // proxy url of the hosted downloaders
+	HostedPipelines   int      // number of pipelines hosted for the remote pipelines of other nodes
+	HostedProcessors  []string // types of the processors of the hosted pipelines, console by default
+	PluginDir         string   // directory holding the cache of the compiled plugins of source models, the temp directory by default
+	CheckpointFile    string   // file of the checkpoint of the distribute queue, restored when the node starts
 }
 
 func NewSettings() *Settings {
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/l-dandelion/yi-ants-go/core/parsers/fixture"
+)
+
+/*
+ * check the parser models of a fixture suite against the golden files
+ * usage: fixture -dir path/to/suite [-run regexp] [-update]
+ * the layout of the suite directory is described in package core/parsers/fixture.
+ */
+func main() {
+	dir := flag.String("dir", ".", "directory of the fixture suite")
+	run := flag.String("run", "", "only check the fixtures whose names match the regexp")
+	update := flag.Bool("update", false, "rewrite the golden files of the different results")
+	flag.Parse()
+
+	suite, err := fixture.Load(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load suite fail: %s\n", err)
+		os.Exit(2)
+	}
+	if *run != "" {
+		re, err := regexp.Compile(*run)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "illegal -run: %s\n", err)
+			os.Exit(2)
+		}
+		fixtures := []*fixture.Fixture{}
+		for _, f := range suite.Fixtures {
+			if re.MatchString(f.Name) {
+				fixtures = append(fixtures, f)
+			}
+		}
+		suite.Fixtures = fixtures
+	}
+
+	failed := 0
+	for _, outcome := range suite.Check(*update) {
+		name := outcome.Fixture.Name
+		switch {
+		case outcome.Err != nil:
+			failed++
+			fmt.Printf("FAIL %s: %s\n", name, outcome.Err)
+		case outcome.Updated:
+			fmt.Printf("UPDATED %s\n%s", name, outcome.Diff)
+		case outcome.Diff != "":
+			failed++
+			fmt.Printf("FAIL %s\n--- %s\n+++ got\n%s", name, suite.GoldenPath(outcome.Fixture), outcome.Diff)
+		default:
+			fmt.Printf("ok %s\n", name)
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d fixtures failed\n", failed, len(suite.Fixtures))
+		os.Exit(1)
+	}
+}